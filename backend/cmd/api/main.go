@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,7 +9,10 @@ import (
 
 	"akwaba-bebe/backend/internal/database"
 	"akwaba-bebe/backend/internal/handlers"
+	"akwaba-bebe/backend/internal/jobs"
 	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/notifications"
+	"akwaba-bebe/backend/internal/payments"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -25,14 +29,51 @@ func main() {
 	db := database.InitDB()
 	defer db.Close()
 
+	// Prestataires de paiement configurés par variables d'environnement
+	paymentRegistry := payments.NewRegistryFromEnv()
+	log.Printf("Moyens de paiement en ligne : %v", paymentRegistry.Names())
+
+	// Notifications (email SMTP, SMS) configurées par variables d'environnement
+	notifier := notifications.NewNotifierFromEnv(db)
+	log.Printf("Canaux de notification : %v", notifier.Channels())
+
+	// Jobs en arrière-plan
+	abandonedCartJob, abandonedCartInterval := jobs.AbandonedCartJobFromEnv(db, notifier)
+	jobs.Every(context.Background(), "paniers abandonnés", abandonedCartInterval, abandonedCartJob.Run)
+	coPurchaseJob, coPurchaseInterval := jobs.CoPurchaseJobFromEnv(db)
+	jobs.Every(context.Background(), "achats conjoints", coPurchaseInterval, coPurchaseJob.Run)
+	backInStockJob, backInStockInterval := jobs.BackInStockJobFromEnv(db, notifier)
+	jobs.Every(context.Background(), "retours en stock", backInStockInterval, backInStockJob.Run)
+	pendingPaymentJob, pendingPaymentInterval := jobs.PendingPaymentJobFromEnv(db, handlers.ExpireOrderPayment)
+	jobs.Every(context.Background(), "paiements expirés", pendingPaymentInterval, pendingPaymentJob.Run)
+
 	// Initialisation des Handlers
 	productHandler := &handlers.ProductHandler{DB: db}
 	authHandler := &handlers.AuthHandler{DB: db}
 	categoryHandler := &handlers.CategoryHandler{DB: db}
 	subCategoryHandler := &handlers.SubCategoryHandler{DB: db}
 	articleHandler := &handlers.ArticleHandler{DB: db}
-	orderHandler := &handlers.OrderHandler{DB: db}
+	orderHandler := &handlers.OrderHandler{DB: db, Payments: paymentRegistry, Notifier: notifier}
 	contactHandler := &handlers.ContactHandler{DB: db}
+	promotionHandler := &handlers.PromotionHandler{DB: db}
+	couponHandler := &handlers.CouponHandler{DB: db}
+	shippingHandler := &handlers.ShippingHandler{DB: db}
+	deliverySlotHandler := &handlers.DeliverySlotHandler{DB: db}
+	paymentHandler := &handlers.PaymentHandler{DB: db, Payments: paymentRegistry}
+	webhookHandler := &handlers.WebhookHandler{DB: db, Payments: paymentRegistry}
+	codHandler := &handlers.CODHandler{DB: db}
+	cartHandler := &handlers.CartHandler{DB: db}
+	registryHandler := &handlers.RegistryHandler{DB: db}
+	reviewHandler := &handlers.ReviewHandler{DB: db}
+	questionHandler := &handlers.ProductQuestionHandler{DB: db, Notifier: notifier}
+	relatedHandler := &handlers.RelatedProductHandler{DB: db}
+	inventoryHandler := &handlers.InventoryHandler{DB: db}
+	stockHandler := &handlers.StockHandler{DB: db}
+	stockSubscriptionHandler := &handlers.StockSubscriptionHandler{DB: db}
+	preorderHandler := &handlers.PreorderHandler{DB: db}
+	bundleHandler := &handlers.BundleHandler{DB: db}
+	auditHandler := &handlers.AuditHandler{DB: db}
+	userHandler := &handlers.UserHandler{DB: db}
 
 	// --- ROUTES AUTHENTIFICATION ---
 	http.HandleFunc("/signup", enableCORS(authHandler.Signup))
@@ -53,89 +94,512 @@ func main() {
 	// --- ROUTE S3 UPLOAD ---
 	http.HandleFunc("/upload", enableCORS(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			productHandler.UploadImage(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(productHandler.UploadImage)(w, r)
 		} else {
 			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
 		}
 	}))
 
 	// --- ROUTES PRODUITS ---
-	http.HandleFunc("/products", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/products", enableCORS(middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/products" {
 			productHandlerDispatcher(w, r, productHandler)
 			return
 		}
 		switch r.Method {
 		case http.MethodGet:
-			productHandler.GetAllProducts(w, r)
+			// Token optionnel : l'admin peut lister les produits archivés
+			middleware.OptionalAuth(productHandler.GetAllProducts)(w, r)
 		case http.MethodPost:
-			middleware.IsAdmin(productHandler.CreateProduct)(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(productHandler.CreateProduct)(w, r)
 		default:
 			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/products/promotion/apply", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/products/promotion/apply", enableCORS(middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPatch {
-			middleware.IsAdmin(productHandler.ApplyPromotion)(w, r)
+			middleware.RequirePermission(db, middleware.PermPricingManage)(productHandler.ApplyPromotion)(w, r)
 		} else {
 			http.Error(w, "PATCH requis", http.StatusMethodNotAllowed)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/products/promotion/remove", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/products/promotion/remove", enableCORS(middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPatch {
-			middleware.IsAdmin(productHandler.RemovePromotion)(w, r)
+			middleware.RequirePermission(db, middleware.PermPricingManage)(productHandler.RemovePromotion)(w, r)
 		} else {
 			http.Error(w, "PATCH requis", http.StatusMethodNotAllowed)
 		}
+	})))
+
+	// Actions groupées : catégorie, archivage, prix, stock (dry-run possible)
+	http.HandleFunc("/products/bulk", enableCORS(middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(productHandler.BulkUpdateProducts)(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Import CSV (dry-run possible) et export CSV/XLSX du catalogue
+	http.HandleFunc("/products/import", enableCORS(middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(productHandler.ImportProducts)(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/products/export", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(productHandler.ExportProducts)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
 	}))
 
+	// Audit posé route par route : les écritures publiques (avis, questions, alertes)
+	// faites avec un token staff ne sont pas des modifications du catalogue
 	http.HandleFunc("/products/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		productHandlerDispatcher(w, r, productHandler)
+		// Avis clients : GET public, POST réservé aux clients connectés
+		if strings.HasSuffix(r.URL.Path, "/reviews") {
+			switch r.Method {
+			case http.MethodGet:
+				reviewHandler.GetProductReviews(w, r)
+			case http.MethodPost:
+				middleware.RequireAuth(reviewHandler.CreateReview)(w, r)
+			default:
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		// Questions produit : client connecté ou invité (nom + email)
+		if strings.HasSuffix(r.URL.Path, "/questions") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+				return
+			}
+			middleware.OptionalAuth(questionHandler.CreateQuestion)(w, r)
+			return
+		}
+		// Alerte de retour en stock : client connecté ou invité (email ou téléphone)
+		if strings.HasSuffix(r.URL.Path, "/stock-alerts") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+				return
+			}
+			middleware.OptionalAuth(stockSubscriptionHandler.Subscribe)(w, r)
+			return
+		}
+		// Réglages de précommande [ADMIN]
+		if strings.HasSuffix(r.URL.Path, "/preorder") {
+			if r.Method != http.MethodPut {
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+				return
+			}
+			middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermInventoryManage)(preorderHandler.SetPreorder))(w, r)
+			return
+		}
+		// Composition d'un coffret [ADMIN]
+		if strings.HasSuffix(r.URL.Path, "/bundle") {
+			if r.Method != http.MethodPut {
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+				return
+			}
+			middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermCatalogManage)(bundleHandler.SetBundle))(w, r)
+			return
+		}
+		// Produits associés : GET public, PUT (liens manuels) réservé à l'admin
+		if strings.HasSuffix(r.URL.Path, "/related") {
+			switch r.Method {
+			case http.MethodGet:
+				relatedHandler.GetRelatedProducts(w, r)
+			case http.MethodPut:
+				middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermCatalogManage)(relatedHandler.SetProductLinks))(w, r)
+			default:
+				http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		middleware.Audit(db, "product", func(w http.ResponseWriter, r *http.Request) {
+			productHandlerDispatcher(w, r, productHandler)
+		})(w, r)
+	}))
+
+	http.HandleFunc("/product-questions", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermReviewsModerate)(questionHandler.GetQuestions)(w, r)
+	}))
+
+	http.HandleFunc("/product-questions/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/answer"):
+			middleware.RequirePermission(db, middleware.PermReviewsModerate)(questionHandler.AnswerQuestion)(w, r)
+		case r.Method == http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermReviewsModerate)(questionHandler.DeleteQuestion)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES STOCKS (ADMIN) ---
+	http.HandleFunc("/inventory", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermInventoryManage)(inventoryHandler.GetInventory)(w, r)
+	}))
+
+	http.HandleFunc("/inventory/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasSuffix(r.URL.Path, "/threshold") {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermInventoryManage)(inventoryHandler.UpdateThreshold))(w, r)
+	}))
+
+	// --- ROUTES JOURNAL DES STOCKS (ADMIN) ---
+	http.HandleFunc("/stock/movements", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermInventoryManage)(stockHandler.GetMovements)(w, r)
+	}))
+
+	http.HandleFunc("/stock/receptions", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermInventoryManage)(stockHandler.CreateReception))(w, r)
+	}))
+
+	http.HandleFunc("/stock/counts", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermInventoryManage)(stockHandler.CreateCount))(w, r)
+	}))
+
+	http.HandleFunc("/stock/adjustments", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.Audit(db, "product", middleware.RequirePermission(db, middleware.PermInventoryManage)(stockHandler.CreateAdjustment))(w, r)
+	}))
+
+	http.HandleFunc("/stock/reconciliation", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermInventoryManage)(stockHandler.GetReconciliation)(w, r)
+	}))
+
+	http.HandleFunc("/preorders", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermInventoryManage)(preorderHandler.GetPreorders)(w, r)
+	}))
+
+	http.HandleFunc("/stock-alerts", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermInventoryManage)(stockSubscriptionHandler.GetDemand)(w, r)
+	}))
+
+	// --- ROUTES AVIS ---
+	http.HandleFunc("/reviews", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermReviewsModerate)(reviewHandler.GetReviews)(w, r)
+	}))
+
+	http.HandleFunc("/reviews/photos", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequireAuth(reviewHandler.UploadReviewPhoto)(w, r)
+	}))
+
+	http.HandleFunc("/reviews/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/moderate"):
+			middleware.RequirePermission(db, middleware.PermReviewsModerate)(reviewHandler.ModerateReview)(w, r)
+		case r.Method == http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermReviewsModerate)(reviewHandler.DeleteReview)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES PROMOTIONS PROGRAMMÉES ---
+	http.HandleFunc("/promotions", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(promotionHandler.GetPromotions)(w, r)
+		case http.MethodPost:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(promotionHandler.CreatePromotion)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/promotions/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(promotionHandler.UpdatePromotion)(w, r)
+		case http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(promotionHandler.DeletePromotion)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES CODES PROMO ---
+	http.HandleFunc("/cart/validate-coupon", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			// Public : le client vérifie son code avant de commander
+			couponHandler.ValidateCoupon(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/coupons", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(couponHandler.GetCoupons)(w, r)
+		case http.MethodPost:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(couponHandler.CreateCoupon)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/coupons/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(couponHandler.UpdateCoupon)(w, r)
+		case http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermPricingManage)(couponHandler.DeleteCoupon)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES LIVRAISON ---
+	http.HandleFunc("/shipping/quote", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			shippingHandler.Quote(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/shipping-zones", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// Zones actives en public (checkout) ; ?all=true inclut les zones désactivées (admin)
+			if r.URL.Query().Get("all") == "true" {
+				middleware.RequirePermission(db, middleware.PermShippingManage)(shippingHandler.GetZones)(w, r)
+			} else {
+				shippingHandler.GetZones(w, r)
+			}
+		case http.MethodPost:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(shippingHandler.CreateZone)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/shipping-zones/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(shippingHandler.UpdateZone)(w, r)
+		case http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(shippingHandler.DeleteZone)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES CRÉNEAUX DE LIVRAISON / RETRAIT ---
+	http.HandleFunc("/delivery-slots", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// Créneaux disponibles en public ; ?all=true inclut complets et désactivés (admin)
+			if r.URL.Query().Get("all") == "true" {
+				middleware.RequirePermission(db, middleware.PermShippingManage)(deliverySlotHandler.GetSlots)(w, r)
+			} else {
+				deliverySlotHandler.GetSlots(w, r)
+			}
+		case http.MethodPost:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(deliverySlotHandler.CreateSlot)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/delivery-slots/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(deliverySlotHandler.UpdateSlot)(w, r)
+		case http.MethodDelete:
+			middleware.RequirePermission(db, middleware.PermShippingManage)(deliverySlotHandler.DeleteSlot)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
 	}))
 
 	// --- ROUTES CATÉGORIES ---
-	http.HandleFunc("/categories", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/categories", enableCORS(middleware.Audit(db, "category", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
 			categoryHandler.GetCategories(w, r)
 		case "POST":
-			middleware.IsAdmin(categoryHandler.CreateCategory)(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(categoryHandler.CreateCategory)(w, r)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/categories/update/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/categories/update/", enableCORS(middleware.Audit(db, "category", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "PUT" {
-			categoryHandler.UpdateCategory(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(categoryHandler.UpdateCategory)(w, r)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/categories/delete/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/categories/delete/", enableCORS(middleware.Audit(db, "category", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "DELETE" {
-			categoryHandler.DeleteCategory(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(categoryHandler.DeleteCategory)(w, r)
 		}
-	}))
+	})))
 
 	// --- ROUTES SOUS-CATÉGORIES ---
-	http.HandleFunc("/subcategories", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/subcategories", enableCORS(middleware.Audit(db, "subcategory", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
 			subCategoryHandler.GetSubCategories(w, r)
 		case "POST":
-			middleware.IsAdmin(subCategoryHandler.CreateSubCategory)(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(subCategoryHandler.CreateSubCategory)(w, r)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/subcategories/update/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/subcategories/update/", enableCORS(middleware.Audit(db, "subcategory", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "PUT" {
-			middleware.IsAdmin(subCategoryHandler.UpdateSubCategory)(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(subCategoryHandler.UpdateSubCategory)(w, r)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/subcategories/delete/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/subcategories/delete/", enableCORS(middleware.Audit(db, "subcategory", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "DELETE" {
-			middleware.IsAdmin(subCategoryHandler.DeleteSubCategory)(w, r)
+			middleware.RequirePermission(db, middleware.PermCatalogManage)(subCategoryHandler.DeleteSubCategory)(w, r)
+		}
+	})))
+
+	// --- ROUTES PANIER ---
+	// Panier invité (header X-Cart-ID) ou rattaché au compte si un token est fourni
+	http.HandleFunc("/cart", enableCORS(middleware.OptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			cartHandler.GetCart(w, r)
+		case http.MethodDelete:
+			cartHandler.ClearCart(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	http.HandleFunc("/cart/items", enableCORS(middleware.OptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		cartHandler.AddItem(w, r)
+	})))
+
+	http.HandleFunc("/cart/contact", enableCORS(middleware.OptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		cartHandler.UpdateContact(w, r)
+	})))
+
+	// Lien de la relance "panier abandonné" (public, le token fait office de secret)
+	http.HandleFunc("/cart/restore/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		cartHandler.RestoreCart(w, r)
+	}))
+
+	http.HandleFunc("/abandoned-carts/stats", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+			return
+		}
+		middleware.RequirePermission(db, middleware.PermOrdersRead)(cartHandler.GetAbandonedStats)(w, r)
+	}))
+
+	http.HandleFunc("/cart/items/", enableCORS(middleware.OptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			cartHandler.UpdateItem(w, r)
+		case http.MethodDelete:
+			cartHandler.RemoveItem(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// --- ROUTES LISTES DE NAISSANCE ---
+	http.HandleFunc("/registries", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			middleware.RequireAuth(registryHandler.GetMyRegistries)(w, r)
+		case http.MethodPost:
+			middleware.RequireAuth(registryHandler.CreateRegistry)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/registries/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		// Lien partagé : public, le token fait office de secret
+		case strings.HasPrefix(path, "/registries/public/") && r.Method == http.MethodGet:
+			registryHandler.GetPublicRegistry(w, r)
+		case strings.HasSuffix(path, "/items") && r.Method == http.MethodPost:
+			middleware.RequireAuth(registryHandler.AddRegistryItem)(w, r)
+		case strings.Contains(path, "/items/") && r.Method == http.MethodPut:
+			middleware.RequireAuth(registryHandler.UpdateRegistryItem)(w, r)
+		case strings.Contains(path, "/items/") && r.Method == http.MethodDelete:
+			middleware.RequireAuth(registryHandler.DeleteRegistryItem)(w, r)
+		case r.Method == http.MethodGet:
+			middleware.RequireAuth(registryHandler.GetRegistry)(w, r)
+		case r.Method == http.MethodPut:
+			middleware.RequireAuth(registryHandler.UpdateRegistry)(w, r)
+		case r.Method == http.MethodDelete:
+			middleware.RequireAuth(registryHandler.DeleteRegistry)(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		}
 	}))
 
@@ -143,25 +607,36 @@ func main() {
 	http.HandleFunc("/orders", enableCORS(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "POST":
-			// Pas d'auth requise : un client non connecté peut passer commande
-			orderHandler.CreateOrder(w, r)
+			// Pas d'auth requise : un client non connecté peut passer commande.
+			// Idempotency-Key évite les commandes en double (double clic, réseau instable).
+			middleware.Idempotency(db, "orders", orderHandler.CreateOrder)(w, r)
 		case "GET":
 			// Protection admin obligatoire : liste toutes les commandes clients (données sensibles)
-			middleware.IsAdmin(orderHandler.GetAllOrders)(w, r)
+			middleware.RequirePermission(db, middleware.PermOrdersRead)(orderHandler.GetAllOrders)(w, r)
 		}
 	}))
 
-	http.HandleFunc("/orders/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" {
-			orderHandler.GetOrderDetails(w, r)
+	http.HandleFunc("/orders/", enableCORS(middleware.Audit(db, "order", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case r.Method == "POST" && strings.Contains(path, "/items/") && strings.HasSuffix(path, "/cancel"):
+			middleware.RequirePermission(db, middleware.PermOrdersManage)(orderHandler.CancelOrderItem)(w, r)
+		case r.Method == "POST" && strings.HasSuffix(path, "/refunds"):
+			middleware.RequirePermission(db, middleware.PermOrdersManage)(orderHandler.RefundOrder)(w, r)
+		case r.Method == "GET" && strings.HasSuffix(path, "/refunds"):
+			middleware.RequirePermission(db, middleware.PermOrdersRead)(orderHandler.GetOrderRefunds)(w, r)
+		case r.Method == "GET" && strings.HasSuffix(path, "/history"):
+			middleware.RequirePermission(db, middleware.PermOrdersRead)(orderHandler.GetOrderHistory)(w, r)
+		case r.Method == "GET":
+			middleware.RequirePermission(db, middleware.PermOrdersRead)(orderHandler.GetOrderDetails)(w, r)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/orders/update/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/orders/update/", enableCORS(middleware.Audit(db, "order", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
-			orderHandler.UpdateOrderStatus(w, r)
+			middleware.RequirePermission(db, middleware.PermOrdersManage)(orderHandler.UpdateOrderStatus)(w, r)
 		}
-	}))
+	})))
 
 	http.HandleFunc("/my-orders", enableCORS(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
@@ -169,29 +644,158 @@ func main() {
 		}
 	}))
 
+	// --- ROUTES PAIEMENTS ---
+	http.HandleFunc("/payments", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermOrdersRead)(paymentHandler.GetPayments)(w, r)
+		} else {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/payments/providers", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			paymentHandler.GetProviders(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/payments/initiate", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			paymentHandler.InitiatePayment(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/payments/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/verify"):
+			// Public : l'email de la commande est exigé dans le body
+			paymentHandler.VerifyPayment(w, r)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/refund"):
+			// Journalisé sur la commande du paiement
+			middleware.AuditVia(db, "order", "SELECT order_id FROM payments WHERE id = $1",
+				middleware.RequirePermission(db, middleware.PermOrdersManage)(paymentHandler.RefundPayment))(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES WEBHOOKS PAIEMENT ---
+	// Appelées par les prestataires (pas de CORS) : l'authenticité est vérifiée par signature HMAC
+	http.HandleFunc("/webhooks/payments/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			webhookHandler.ReceivePaymentWebhook(w, r)
+		} else {
+			http.Error(w, "POST requis", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/payment-webhook-events", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermPaymentsManage)(webhookHandler.GetEvents)(w, r)
+		} else {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/payment-webhook-events/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/replay") {
+			middleware.RequirePermission(db, middleware.PermPaymentsManage)(webhookHandler.ReplayEvent)(w, r)
+		} else {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// --- ROUTES PAIEMENT À LA LIVRAISON (LIVREURS) ---
+	http.HandleFunc("/cod/orders/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/rider"):
+			middleware.Audit(db, "order", middleware.RequirePermission(db, middleware.PermOrdersManage)(codHandler.AssignRider))(w, r)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/collect"):
+			middleware.Audit(db, "order", middleware.RequirePermission(db, middleware.PermCODCollect)(codHandler.RecordCollection))(w, r)
+		default:
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/cod/my-orders", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermCODDeliveries)(codHandler.GetMyOrders)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/cod/reconciliation", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermCODCollect)(codHandler.GetReconciliation)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
 	// --- ROUTES CONTACT ---
 	http.HandleFunc("/contact", enableCORS(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "POST":
-			contactHandler.CreateMessage(w, r)
+			middleware.Idempotency(db, "contact", contactHandler.CreateMessage)(w, r)
 		case "GET":
-			middleware.IsAdmin(contactHandler.GetMessages)(w, r)
+			middleware.RequirePermission(db, middleware.PermContactManage)(contactHandler.GetMessages)(w, r)
 		}
 	}))
 
-	http.HandleFunc("/contact/", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/contact/", enableCORS(middleware.Audit(db, "contact_message", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "PATCH" && strings.HasSuffix(r.URL.Path, "/read") {
-			middleware.IsAdmin(contactHandler.MarkAsRead)(w, r)
+			middleware.RequirePermission(db, middleware.PermContactManage)(contactHandler.MarkAsRead)(w, r)
 		}
-	}))
+	})))
 
 	// --- ROUTES BLOG ---
-	http.HandleFunc("/articles", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/articles", enableCORS(middleware.Audit(db, "article", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "GET":
 			articleHandler.GetAllArticles(w, r)
 		case "POST":
-			middleware.IsAdmin(articleHandler.CreateArticle)(w, r)
+			middleware.RequirePermission(db, middleware.PermContentManage)(articleHandler.CreateArticle)(w, r)
+		}
+	})))
+
+	// --- ROUTES RÔLES ET UTILISATEURS ---
+	// Matrice des permissions : middleware/permissions.go
+	http.HandleFunc("/roles", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermUsersManage)(userHandler.GetRoles)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/users", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermUsersManage)(userHandler.GetUsers)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
+		}
+	}))
+
+	http.HandleFunc("/users/", enableCORS(middleware.Audit(db, "user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/role") {
+			middleware.RequirePermission(db, middleware.PermUsersManage)(userHandler.UpdateUserRole)(w, r)
+		} else {
+			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// --- ROUTE JOURNAL D'AUDIT ---
+	// Alimenté par middleware.Audit sur les routes d'écriture admin ci-dessus
+	http.HandleFunc("/audit-log", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			middleware.RequirePermission(db, middleware.PermAuditRead)(auditHandler.GetAuditLog)(w, r)
+		} else {
+			http.Error(w, "GET requis", http.StatusMethodNotAllowed)
 		}
 	}))
 
@@ -213,9 +817,9 @@ func productHandlerDispatcher(w http.ResponseWriter, r *http.Request, h *handler
 	case http.MethodGet:
 		h.GetProduct(w, r)
 	case http.MethodPut:
-		middleware.IsAdmin(h.UpdateProduct)(w, r)
+		middleware.RequirePermission(h.DB, middleware.PermCatalogManage)(h.UpdateProduct)(w, r)
 	case http.MethodDelete:
-		middleware.IsAdmin(h.DeleteProduct)(w, r)
+		middleware.RequirePermission(h.DB, middleware.PermCatalogManage)(h.DeleteProduct)(w, r)
 	default:
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 	}
@@ -234,7 +838,8 @@ func enableCORS(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Authorization, Idempotency-Key, X-Cart-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, X-Cart-ID, X-Skipped-Without-SKU")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Réponse immédiate pour les requêtes Preflight OPTIONS (indispensable pour Vercel)
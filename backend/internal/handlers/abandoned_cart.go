@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+// PUT /cart/contact — email / téléphone du panier, pour la relance en cas d'abandon
+func (h *CartHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.CartContactInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	input.Email = strings.TrimSpace(input.Email)
+	input.Phone = strings.TrimSpace(input.Phone)
+	if input.Email != "" && !strings.Contains(input.Email, "@") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Email invalide"})
+		return
+	}
+
+	cartID, err := findCart(h.DB, r.Header.Get(CartHeader), middleware.UserID(r))
+	if err != nil {
+		writeCartError(w, "UpdateCartContact", err)
+		return
+	}
+	if cartID == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Panier introuvable"})
+		return
+	}
+
+	// Ne touche pas updated_at : renseigner son email n'est pas une activité sur le panier
+	_, err = h.DB.Exec("UPDATE carts SET email = NULLIF($1, ''), phone = NULLIF($2, '') WHERE id = $3",
+		input.Email, input.Phone, cartID)
+	if err != nil {
+		writeCartError(w, "UpdateCartContact", err)
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// GET /cart/restore/{token} — lien "retrouver mon panier" de la relance.
+// Renvoie le panier et son ID (header X-Cart-ID) ; un panier rattaché à un compte
+// (user_id renseigné) ne sera ensuite accessible qu'après connexion.
+func (h *CartHandler) RestoreCart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token := strings.TrimPrefix(r.URL.Path, "/cart/restore/")
+	var cartID, status string
+	err := h.DB.QueryRow(`
+        UPDATE abandoned_cart_reminders r SET restored_at = COALESCE(r.restored_at, NOW())
+        FROM carts c
+        WHERE r.token = $1 AND c.id = r.cart_id
+        RETURNING c.id, c.status`, token).Scan(&cartID, &status)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Lien de panier invalide"})
+		return
+	} else if err != nil {
+		writeCartError(w, "RestoreCart", err)
+		return
+	}
+	if status != "active" {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce panier a déjà été commandé"})
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// GET /abandoned-carts/stats?from=2026-10-01&to=2026-10-19 — Taux de récupération [ADMIN]
+// Période par défaut : les 30 derniers jours.
+func (h *CartHandler) GetAbandonedStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	stats := models.AbandonedCartStats{
+		From: time.Now().AddDate(0, 0, -30).Format("2006-01-02"),
+		To:   time.Now().Format("2006-01-02"),
+	}
+	for param, dst := range map[string]*string{"from": &stats.From, "to": &stats.To} {
+		if v := q.Get(param); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Paramètre %s invalide (AAAA-MM-JJ)", param)})
+				return
+			}
+			*dst = v
+		}
+	}
+
+	err := h.DB.QueryRow(`
+        SELECT COUNT(*) FILTER (WHERE r.status = 'sent'),
+               COUNT(*) FILTER (WHERE r.status = 'failed'),
+               COUNT(*) FILTER (WHERE r.restored_at IS NOT NULL),
+               COUNT(*) FILTER (WHERE r.recovered_order_id IS NOT NULL),
+               COALESCE(SUM(o.total_amount), 0)
+        FROM abandoned_cart_reminders r
+        LEFT JOIN orders o ON o.id = r.recovered_order_id
+        WHERE r.sent_at >= $1::date AND r.sent_at < $2::date + 1`,
+		stats.From, stats.To,
+	).Scan(&stats.RemindersSent, &stats.RemindersFailed, &stats.Restored, &stats.Recovered, &stats.RecoveredRevenue)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetAbandonedStats : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	if stats.RemindersSent > 0 {
+		stats.RecoveryRate = math.Round(float64(stats.Recovered)/float64(stats.RemindersSent)*1000) / 10
+	}
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+type AuditHandler struct {
+	DB *sql.DB
+}
+
+// GET /audit-log — Journal des écritures admin [ADMIN]
+// Filtres : entity_type, entity_id, user_id, action, from / to (AAAA-MM-JJ), limit, offset
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := `
+        SELECT a.id, a.user_id, u.email, a.action, a.entity_type, a.entity_id, a.method, a.path,
+               a.before_data, a.after_data, a.changes, COALESCE(a.ip_address, ''), a.created_at
+        FROM audit_log a
+        LEFT JOIN users u ON u.id = a.user_id
+        WHERE 1=1`
+	args := []interface{}{}
+	if t := q.Get("entity_type"); t != "" {
+		args = append(args, t)
+		query += fmt.Sprintf(" AND a.entity_type = $%d", len(args))
+	}
+	if id, err := strconv.Atoi(q.Get("entity_id")); err == nil {
+		args = append(args, id)
+		query += fmt.Sprintf(" AND a.entity_id = $%d", len(args))
+	}
+	if id, err := strconv.Atoi(q.Get("user_id")); err == nil {
+		args = append(args, id)
+		query += fmt.Sprintf(" AND a.user_id = $%d", len(args))
+	}
+	if action := q.Get("action"); action != "" {
+		args = append(args, action)
+		query += fmt.Sprintf(" AND a.action = $%d", len(args))
+	}
+	for _, bound := range []struct{ param, clause string }{
+		{"from", " AND a.created_at >= $%d"},
+		{"to", " AND a.created_at < $%d::date + 1"},
+	} {
+		v := q.Get(bound.param)
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Date invalide (format AAAA-MM-JJ) : " + bound.param})
+			return
+		}
+		args = append(args, v)
+		query += fmt.Sprintf(bound.clause, len(args))
+	}
+
+	limit := 100
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY a.created_at DESC, a.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetAuditLog : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after, changes []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.UserEmail, &e.Action, &e.EntityType, &e.EntityID, &e.Method, &e.Path,
+			&before, &after, &changes, &e.IPAddress, &e.CreatedAt); err != nil {
+			fmt.Printf("Erreur scan audit_log : %v\n", err)
+			continue
+		}
+		e.Before, e.After, e.Changes = rawJSON(before), rawJSON(after), rawJSON(changes)
+		entries = append(entries, e)
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// rawJSON : document JSONB tel quel, null s'il est absent
+func rawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(data)
+}
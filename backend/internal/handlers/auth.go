@@ -137,11 +137,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fusion du panier invité : le frontend remplace son X-Cart-ID par cart_id
+	cartID, err := mergeGuestCart(h.DB, input.CartID, user.ID)
+	if err != nil {
+		// La connexion ne doit pas échouer à cause du panier
+		fmt.Printf("Erreur BDD mergeGuestCart : %v\n", err)
+	}
+
 	// Réponse succès : token + rôle + nom complet pour le frontend
 	json.NewEncoder(w).Encode(map[string]string{
 		"token":     tokenString,
 		"role":      user.Role,
 		"full_name": user.FullName,
+		"cart_id":   cartID,
 	})
 }
 
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type BundleHandler struct {
+	DB *sql.DB
+}
+
+// loadBundleComponents lit les composants d'un coffret (vide si le produit n'en est pas un)
+func loadBundleComponents(q queryer, bundleID int) ([]models.BundleComponent, error) {
+	rows, err := q.Query(`
+        SELECT c.id, c.name, COALESCE(c.image_url, ''), c.price, bi.quantity, c.stock_quantity
+        FROM bundle_items bi
+        JOIN products c ON c.id = bi.component_id
+        WHERE bi.bundle_id = $1
+        ORDER BY c.id`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make([]models.BundleComponent, 0)
+	for rows.Next() {
+		var c models.BundleComponent
+		if err := rows.Scan(&c.ProductID, &c.Name, &c.ImageURL, &c.Price, &c.Quantity, &c.StockQuantity); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// decrementBundleStock prend sur le stock de chaque composant la quantité d'un coffret
+// vendu et garde la composition sur la ligne (l.Components) pour la remise en stock.
+// Renvoie les composants passés sous leur seuil d'alerte.
+func decrementBundleStock(tx *sql.Tx, orderID int, l *orderLine) ([]lowStockCrossing, error) {
+	components, err := loadBundleComponents(tx, l.Product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return nil, newOrderError(http.StatusConflict, "Le coffret %s n'est plus disponible", l.Product.Name)
+	}
+	l.Components = components
+
+	crossings := make([]lowStockCrossing, 0)
+	for _, c := range components {
+		units := c.Quantity * l.Quantity
+		stock, threshold, err := adjustStock(tx, c.ProductID, -units, stockMovement{
+			Type: "sale", OrderID: orderID, Reason: "Coffret " + l.Product.Name,
+		})
+		if err == errInsufficientStock {
+			return nil, newOrderError(http.StatusConflict, "Stock insuffisant pour %s (composant %s)", l.Product.Name, c.Name)
+		} else if err != nil {
+			return nil, err
+		}
+		if stock <= threshold && stock+units > threshold {
+			crossings = append(crossings, lowStockCrossing{c.ProductID, c.Name, stock, threshold})
+		}
+	}
+	return crossings, nil
+}
+
+// PUT /products/{id}/bundle — Définir la composition d'un coffret [ADMIN]
+// Le prix du coffret reste celui du produit (PUT /products/{id}). Une liste vide
+// refait du coffret un produit simple (stock 0, à réapprovisionner par /stock/receptions).
+func (h *BundleHandler) SetBundle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/products/"), "/bundle"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.BundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	seen := make(map[int]bool, len(req.Items))
+	for _, it := range req.Items {
+		if it.ProductID == id || seen[it.ProductID] || it.Quantity <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Composant invalide ou en double : %d", it.ProductID)})
+			return
+		}
+		seen[it.ProductID] = true
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		writeStockError(w, "SetBundle", id, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var stock int
+	var usedAsComponent bool
+	err = tx.QueryRow(`
+        SELECT stock_quantity, EXISTS (SELECT 1 FROM bundle_items WHERE component_id = $1)
+        FROM products WHERE id = $1 FOR UPDATE`, id).Scan(&stock, &usedAsComponent)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	} else if err != nil {
+		writeStockError(w, "SetBundle", id, err)
+		return
+	}
+	if len(req.Items) > 0 && usedAsComponent {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce produit compose déjà un coffret : il ne peut pas en être un"})
+		return
+	}
+
+	// Le stock propre d'un coffret est toujours 0 : l'ancien stock est sorti par le journal
+	if len(req.Items) > 0 && stock > 0 {
+		if _, _, err := adjustStock(tx, id, -stock, stockMovement{
+			Type: "correction", ActorID: middleware.UserID(r), Reason: "Conversion en coffret",
+		}); err != nil {
+			writeStockError(w, "SetBundle", id, err)
+			return
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM bundle_items WHERE bundle_id = $1", id); err != nil {
+		writeStockError(w, "SetBundle", id, err)
+		return
+	}
+	for _, it := range req.Items {
+		res, err := tx.Exec(`
+            INSERT INTO bundle_items (bundle_id, component_id, quantity)
+            SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM products WHERE id = $2 AND NOT is_bundle)`,
+			id, it.ProductID, it.Quantity)
+		if err != nil {
+			writeStockError(w, "SetBundle", id, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Composant introuvable ou déjà coffret : %d", it.ProductID)})
+			return
+		}
+	}
+	if _, err := tx.Exec("UPDATE products SET is_bundle = $1 WHERE id = $2", len(req.Items) > 0, id); err != nil {
+		writeStockError(w, "SetBundle", id, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeStockError(w, "SetBundle", id, err)
+		return
+	}
+
+	components, err := loadBundleComponents(h.DB, id)
+	if err != nil {
+		fmt.Printf("Erreur BDD SetBundle id=%d : %v\n", id, err)
+	}
+	available := 0
+	for i, c := range components {
+		if n := c.StockQuantity / c.Quantity; i == 0 || n < available {
+			available = n
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Composition du coffret enregistrée",
+		"product_id":     id,
+		"is_bundle":      len(components) > 0,
+		"bundle_items":   components,
+		"stock_quantity": available,
+	})
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+// CartHeader identifie le panier d'un visiteur non connecté (UUID renvoyé à la création)
+const CartHeader = "X-Cart-ID"
+
+type CartHandler struct {
+	DB *sql.DB
+}
+
+// newCartID génère un UUID v4 : il sert de secret pour accéder à un panier invité
+func newCartID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// isCartID vérifie le format avant d'interroger la colonne UUID (sinon erreur SQL)
+func isCartID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, c := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// findCart renvoie l'ID du panier actif de la requête ("" si aucun) :
+// le panier du header X-Cart-ID, sinon celui du compte connecté.
+// Un panier rattaché à un compte n'est accessible qu'à ce compte.
+func findCart(q queryer, cartID string, userID int) (string, error) {
+	if cartID != "" {
+		if !isCartID(cartID) {
+			return "", nil
+		}
+		var owner sql.NullInt64
+		var status string
+		err := q.QueryRow("SELECT user_id, status FROM carts WHERE id = $1", cartID).Scan(&owner, &status)
+		if err == sql.ErrNoRows {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		if status == "active" && (!owner.Valid || int(owner.Int64) == userID) {
+			return cartID, nil
+		}
+		if userID == 0 {
+			return "", nil
+		}
+	}
+
+	if userID > 0 {
+		var id string
+		err := q.QueryRow("SELECT id FROM carts WHERE user_id = $1 AND status = 'active'", userID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return id, err
+	}
+	return "", nil
+}
+
+// ensureCart renvoie le panier actif de la requête, en le créant si besoin
+func (h *CartHandler) ensureCart(r *http.Request) (string, error) {
+	userID := middleware.UserID(r)
+	cartID, err := findCart(h.DB, r.Header.Get(CartHeader), userID)
+	if err != nil || cartID != "" {
+		return cartID, err
+	}
+
+	if cartID, err = newCartID(); err != nil {
+		return "", err
+	}
+	var owner *int
+	if userID > 0 {
+		owner = &userID
+	}
+	_, err = h.DB.Exec("INSERT INTO carts (id, user_id) VALUES ($1, $2)", cartID, owner)
+	return cartID, err
+}
+
+// loadCart lit le panier avec les prix du moment (promotions actives) et le stock disponible
+func loadCart(q queryer, cartID string) (*models.Cart, error) {
+	cart := &models.Cart{ID: cartID, Items: make([]models.CartLineView, 0)}
+	var owner sql.NullInt64
+	err := q.QueryRow("SELECT user_id, status, updated_at FROM carts WHERE id = $1", cartID).
+		Scan(&owner, &cart.Status, &cart.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Valid {
+		id := int(owner.Int64)
+		cart.UserID = &id
+	}
+
+	promos, err := loadActivePromotions(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+        SELECT p.id, p.name, COALESCE(p.image_url, ''), p.price, `+productStockColumn+`,
+               p.category_id, p.subcategory_id, p.promotion_percent, p.preorder_enabled, p.is_bundle, p.archived_at IS NOT NULL, ci.quantity
+        FROM cart_items ci
+        JOIN products p ON p.id = ci.product_id
+        WHERE ci.cart_id = $1
+        ORDER BY ci.added_at, ci.id`, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Product
+		var quantity int
+		var archived bool
+		if err := rows.Scan(&p.ID, &p.Name, &p.ImageURL, &p.Price, &p.StockQuantity,
+			&p.CategoryID, &p.SubcategoryID, &p.PromotionPercent, &p.PreorderEnabled, &p.IsBundle, &archived, &quantity); err != nil {
+			return nil, err
+		}
+		// Produit archivé depuis l'ajout : ligne signalée comme indisponible
+		if archived {
+			p.StockQuantity = 0
+			p.PreorderEnabled = false
+		}
+		resolvePrice(&p, promos)
+
+		line := orderLine{Product: p, Quantity: quantity}
+		view := models.CartLineView{
+			ProductID:        p.ID,
+			Name:             p.Name,
+			ImageURL:         p.ImageURL,
+			Price:            p.Price,
+			FinalPrice:       p.FinalPrice,
+			AppliedPromotion: p.AppliedPromotion,
+			Quantity:         quantity,
+			LineTotal:        line.total(),
+			StockQuantity:    p.StockQuantity,
+			InStock:          quantity <= p.StockQuantity,
+		}
+		view.Preorder = !view.InStock && p.PreorderEnabled && !p.IsBundle
+		if !view.InStock && !view.Preorder {
+			cart.HasStockIssues = true
+		}
+		cart.Items = append(cart.Items, view)
+		cart.ItemCount += quantity
+		cart.Subtotal += view.LineTotal
+	}
+	cart.Subtotal = roundAmount(cart.Subtotal)
+	return cart, rows.Err()
+}
+
+// writeCart renvoie le panier complet et son ID dans le header X-Cart-ID
+func (h *CartHandler) writeCart(w http.ResponseWriter, cartID string, status int) {
+	cart, err := loadCart(h.DB, cartID)
+	if err != nil {
+		fmt.Printf("Erreur BDD loadCart : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lecture panier"})
+		return
+	}
+	w.Header().Set(CartHeader, cartID)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(cart)
+}
+
+// checkCartStock vérifie que le produit existe et que la quantité voulue est disponible
+// (ou que le produit est précommandable)
+func checkCartStock(q queryer, productID, quantity int) error {
+	var name string
+	var stock int
+	var preorder bool
+	var archived bool
+	err := q.QueryRow("SELECT p.name, "+productStockColumn+", p.preorder_enabled AND NOT p.is_bundle, p.archived_at IS NOT NULL FROM products p WHERE p.id = $1", productID).
+		Scan(&name, &stock, &preorder, &archived)
+	if err == sql.ErrNoRows {
+		return newOrderError(http.StatusNotFound, "Produit introuvable (id=%d)", productID)
+	} else if err != nil {
+		return err
+	}
+	if archived {
+		return newOrderError(http.StatusConflict, "%s n'est plus disponible", name)
+	}
+	// Précommande : le plafond est vérifié à la commande
+	if quantity > stock && !preorder {
+		return newOrderError(http.StatusConflict, "Stock insuffisant pour %s (%d disponible(s))", name, stock)
+	}
+	return nil
+}
+
+// writeCartError : même traitement que writeOrderError avec un message générique propre au panier
+func writeCartError(w http.ResponseWriter, context string, err error) {
+	var oe *orderError
+	if errors.As(err, &oe) {
+		w.WriteHeader(oe.status)
+		json.NewEncoder(w).Encode(map[string]string{"message": oe.message})
+		return
+	}
+	fmt.Printf("Erreur BDD %s : %v\n", context, err)
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Erreur mise à jour panier"})
+}
+
+func touchCart(db execer, cartID string) error {
+	_, err := db.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cartID)
+	return err
+}
+
+// GET /cart — panier du header X-Cart-ID ou du compte connecté
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cartID, err := findCart(h.DB, r.Header.Get(CartHeader), middleware.UserID(r))
+	if err != nil {
+		fmt.Printf("Erreur BDD GetCart : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lecture panier"})
+		return
+	}
+	if cartID == "" {
+		// Pas encore de panier : il sera créé au premier ajout
+		json.NewEncoder(w).Encode(models.Cart{Status: "active", Items: make([]models.CartLineView, 0), UpdatedAt: time.Now()})
+		return
+	}
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// POST /cart/items — ajoute un article (la quantité s'ajoute à celle déjà présente)
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.CartItemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.ProductID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+	if input.Quantity < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Quantité invalide"})
+		return
+	}
+
+	cartID, err := h.ensureCart(r)
+	if err != nil {
+		writeCartError(w, "AddCartItem", err)
+		return
+	}
+
+	var current int
+	err = h.DB.QueryRow("SELECT quantity FROM cart_items WHERE cart_id = $1 AND product_id = $2", cartID, input.ProductID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		writeCartError(w, "AddCartItem", err)
+		return
+	}
+	if err := checkCartStock(h.DB, input.ProductID, current+input.Quantity); err != nil {
+		writeCartError(w, "AddCartItem", err)
+		return
+	}
+
+	_, err = h.DB.Exec(`
+        INSERT INTO cart_items (cart_id, product_id, quantity) VALUES ($1, $2, $3)
+        ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`,
+		cartID, input.ProductID, input.Quantity)
+	if err == nil {
+		err = touchCart(h.DB, cartID)
+	}
+	if err != nil {
+		writeCartError(w, "AddCartItem", err)
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// cartItemRequest résout le panier existant et le produit de /cart/items/{product_id}
+func (h *CartHandler) cartItemRequest(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	productID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/cart/items/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID produit invalide"})
+		return "", 0, false
+	}
+	cartID, err := findCart(h.DB, r.Header.Get(CartHeader), middleware.UserID(r))
+	if err != nil {
+		writeCartError(w, "CartItem", err)
+		return "", 0, false
+	}
+	if cartID == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Panier introuvable"})
+		return "", 0, false
+	}
+	return cartID, productID, true
+}
+
+// PUT /cart/items/{product_id} — fixe la quantité (0 retire l'article)
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.CartItemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Quantity < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	cartID, productID, ok := h.cartItemRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var res sql.Result
+	var err error
+	if input.Quantity == 0 {
+		res, err = h.DB.Exec("DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2", cartID, productID)
+	} else {
+		if err := checkCartStock(h.DB, productID, input.Quantity); err != nil {
+			writeCartError(w, "UpdateCartItem", err)
+			return
+		}
+		res, err = h.DB.Exec("UPDATE cart_items SET quantity = $1 WHERE cart_id = $2 AND product_id = $3", input.Quantity, cartID, productID)
+	}
+	if err != nil {
+		writeCartError(w, "UpdateCartItem", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Article absent du panier"})
+		return
+	}
+	if err := touchCart(h.DB, cartID); err != nil {
+		writeCartError(w, "UpdateCartItem", err)
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// DELETE /cart/items/{product_id} — retire un article
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cartID, productID, ok := h.cartItemRequest(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := h.DB.Exec("DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2", cartID, productID)
+	if err == nil {
+		err = touchCart(h.DB, cartID)
+	}
+	if err != nil {
+		writeCartError(w, "RemoveCartItem", err)
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// DELETE /cart — vide le panier
+func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cartID, err := findCart(h.DB, r.Header.Get(CartHeader), middleware.UserID(r))
+	if err != nil {
+		writeCartError(w, "ClearCart", err)
+		return
+	}
+	if cartID == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Panier introuvable"})
+		return
+	}
+
+	_, err = h.DB.Exec("DELETE FROM cart_items WHERE cart_id = $1", cartID)
+	if err == nil {
+		err = touchCart(h.DB, cartID)
+	}
+	if err != nil {
+		writeCartError(w, "ClearCart", err)
+		return
+	}
+
+	h.writeCart(w, cartID, http.StatusOK)
+}
+
+// mergeGuestCart rattache le panier invité au compte qui se connecte.
+// Si le compte a déjà un panier actif, les quantités s'additionnent et le panier
+// invité passe en 'merged'. Renvoie l'ID du panier du compte ("" si rien à fusionner).
+func mergeGuestCart(db *sql.DB, guestID string, userID int) (string, error) {
+	if !isCartID(guestID) {
+		return "", nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var owner sql.NullInt64
+	var status string
+	err = tx.QueryRow("SELECT user_id, status FROM carts WHERE id = $1 FOR UPDATE", guestID).Scan(&owner, &status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if status != "active" || (owner.Valid && int(owner.Int64) != userID) {
+		return "", nil
+	}
+	if owner.Valid {
+		// Déjà rattaché à ce compte
+		return guestID, tx.Commit()
+	}
+
+	var userCartID string
+	err = tx.QueryRow("SELECT id FROM carts WHERE user_id = $1 AND status = 'active' FOR UPDATE", userID).Scan(&userCartID)
+	if err == sql.ErrNoRows {
+		if _, err := tx.Exec("UPDATE carts SET user_id = $1, updated_at = NOW() WHERE id = $2", userID, guestID); err != nil {
+			return "", err
+		}
+		return guestID, tx.Commit()
+	} else if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO cart_items (cart_id, product_id, quantity)
+        SELECT $1, product_id, quantity FROM cart_items WHERE cart_id = $2
+        ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`,
+		userCartID, guestID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE carts SET status = 'merged', updated_at = NOW() WHERE id = $1", guestID); err != nil {
+		return "", err
+	}
+	if err := touchCart(tx, userCartID); err != nil {
+		return "", err
+	}
+	return userCartID, tx.Commit()
+}
+
+// loadCartForCheckout verrouille le panier à commander et renvoie ses articles
+func loadCartForCheckout(tx *sql.Tx, cartID string) ([]models.CartItem, error) {
+	if !isCartID(cartID) {
+		return nil, newOrderError(http.StatusBadRequest, "Panier introuvable")
+	}
+	var status string
+	err := tx.QueryRow("SELECT status FROM carts WHERE id = $1 FOR UPDATE", cartID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, newOrderError(http.StatusBadRequest, "Panier introuvable")
+	} else if err != nil {
+		return nil, err
+	}
+	if status != "active" {
+		return nil, newOrderError(http.StatusConflict, "Ce panier a déjà été commandé")
+	}
+
+	rows, err := tx.Query("SELECT product_id, quantity FROM cart_items WHERE cart_id = $1 ORDER BY added_at, id", cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.CartItem, 0)
+	for rows.Next() {
+		var item models.CartItem
+		if err := rows.Scan(&item.ID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// convertCart clôt le panier commandé (dans la transaction de CreateOrder) et marque
+// sa dernière relance comme récupérée
+func convertCart(tx *sql.Tx, cartID string, orderID int) error {
+	_, err := tx.Exec("UPDATE carts SET status = 'converted', order_id = $1, updated_at = NOW() WHERE id = $2", orderID, cartID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+        UPDATE abandoned_cart_reminders SET recovered_order_id = $1
+        WHERE id = (SELECT id FROM abandoned_cart_reminders
+                    WHERE cart_id = $2 AND status = 'sent' ORDER BY sent_at DESC LIMIT 1)`,
+		orderID, cartID)
+	return err
+}
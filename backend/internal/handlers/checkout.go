@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+// orderError est une erreur métier du tunnel de commande : son message (en français)
+// est renvoyé tel quel au client avec le code HTTP associé.
+type orderError struct {
+	status  int
+	message string
+}
+
+func (e *orderError) Error() string { return e.message }
+
+func newOrderError(status int, format string, args ...interface{}) *orderError {
+	return &orderError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// writeOrderError renvoie l'erreur métier au client, ou logue l'erreur technique
+// et renvoie un message générique (ne jamais exposer l'erreur SQL).
+func writeOrderError(w http.ResponseWriter, context string, err error) {
+	var oe *orderError
+	if errors.As(err, &oe) {
+		w.WriteHeader(oe.status)
+		json.NewEncoder(w).Encode(map[string]string{"message": oe.message})
+		return
+	}
+	fmt.Printf("Erreur BDD %s : %v\n", context, err)
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement commande"})
+}
+
+// orderLine est un article du panier résolu côté serveur : produit lu en base
+// (snapshot) et prix calculé à l'instant de la commande.
+type orderLine struct {
+	Product        models.Product
+	Quantity       int
+	RegistryItemID *int // Achat depuis une liste de naissance
+	Preorder       bool // Stock insuffisant : servie à la réception (decrementStock)
+	// Coffret : composition au moment de la vente (decrementStock), conservée par
+	// insertOrderItems pour la remise en stock
+	Components []models.BundleComponent
+}
+
+func (l orderLine) total() float64 {
+	return roundAmount(l.Product.FinalPrice * float64(l.Quantity))
+}
+
+func linesSubtotal(lines []orderLine) float64 {
+	var subtotal float64
+	for _, l := range lines {
+		subtotal += l.total()
+	}
+	return roundAmount(subtotal)
+}
+
+// roundAmount arrondit au centime (colonnes NUMERIC(10, 2))
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// loadOrderLines lit chaque produit du panier (dans la transaction de commande, ou
+// directement en base pour une simple vérification) et résout son prix.
+// Le prix envoyé par le frontend est ignoré : seul le catalogue fait foi.
+func loadOrderLines(q queryer, items []models.CartItem) ([]orderLine, error) {
+	if len(items) == 0 {
+		return nil, newOrderError(http.StatusBadRequest, "Le panier est vide")
+	}
+
+	promos, err := loadActivePromotions(q)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT p.id, p.name, COALESCE(p.sku, ''), COALESCE(p.image_url, ''), p.price, ` + productStockColumn + `,
+               p.category_id, p.subcategory_id, p.promotion_percent, p.is_bundle, ` + productPreorderColumns + `, p.archived_at IS NOT NULL
+        FROM products p WHERE p.id = $1`
+
+	lines := make([]orderLine, 0, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, newOrderError(http.StatusBadRequest, "Quantité invalide pour le produit %d", item.ID)
+		}
+
+		var p models.Product
+		var archived bool
+		err := q.QueryRow(query, item.ID).Scan(&p.ID, &p.Name, &p.SKU, &p.ImageURL, &p.Price, &p.StockQuantity,
+			&p.CategoryID, &p.SubcategoryID, &p.PromotionPercent, &p.IsBundle, &p.PreorderEnabled, &p.PreorderAvailableAt, &p.PreorderCap, &archived)
+		if err == sql.ErrNoRows {
+			return nil, newOrderError(http.StatusBadRequest, "Produit introuvable (id=%d)", item.ID)
+		} else if err != nil {
+			return nil, err
+		}
+		if archived {
+			return nil, newOrderError(http.StatusConflict, "%s n'est plus disponible", p.Name)
+		}
+
+		resolvePrice(&p, promos)
+		lines = append(lines, orderLine{Product: p, Quantity: item.Quantity, RegistryItemID: item.RegistryItemID})
+	}
+	return lines, nil
+}
+
+// insertOrderItems enregistre les lignes avec leur snapshot produit et promotion
+func insertOrderItems(tx *sql.Tx, orderID int, lines []orderLine) error {
+	query := `
+        INSERT INTO order_items
+        (order_id, product_id, quantity, price,
+         product_name, product_sku, product_image_url, original_price,
+         promotion_percent, promotion_id, promotion_name, registry_item_id, is_preorder)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $12, $13)
+        RETURNING id`
+
+	for _, l := range lines {
+		p := l.Product
+		var promoPercent *float64
+		var promoID *int
+		var promoName *string
+		if ap := p.AppliedPromotion; ap != nil {
+			promoID = ap.ID
+			promoName = &ap.Name
+			if ap.DiscountType == "percent" {
+				promoPercent = &ap.DiscountValue
+			}
+		}
+
+		var itemID int
+		err := tx.QueryRow(query, orderID, p.ID, l.Quantity, p.FinalPrice,
+			p.Name, p.SKU, p.ImageURL, p.Price, promoPercent, promoID, promoName, l.RegistryItemID, l.Preorder,
+		).Scan(&itemID)
+		if err != nil {
+			return fmt.Errorf("article id=%d : %w", p.ID, err)
+		}
+		for _, c := range l.Components {
+			_, err := tx.Exec(
+				"INSERT INTO order_item_components (order_item_id, component_id, component_name, quantity) VALUES ($1, $2, $3, $4)",
+				itemID, c.ProductID, c.Name, c.Quantity)
+			if err != nil {
+				return fmt.Errorf("composant id=%d du coffret id=%d : %w", c.ProductID, p.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lowStockCrossing est un produit passé sous son seuil d'alerte par une commande
+type lowStockCrossing struct {
+	ProductID     int
+	Name          string
+	StockQuantity int
+	Threshold     int
+}
+
+// decrementStock réserve le stock des articles dans la transaction de commande.
+// L'UPDATE conditionnel (adjustStock) empêche deux commandes simultanées de vendre la même
+// dernière unité. Sans stock suffisant, un produit précommandable devient une précommande
+// (lines[i].Preorder, aucun stock pris). Renvoie les produits dont le stock vient de passer
+// au seuil d'alerte ou en dessous.
+func decrementStock(tx *sql.Tx, orderID int, lines []orderLine) ([]lowStockCrossing, error) {
+	crossings := make([]lowStockCrossing, 0)
+	for i, l := range lines {
+		// Coffret : le stock pris est celui des composants
+		if l.Product.IsBundle {
+			components, err := decrementBundleStock(tx, orderID, &lines[i])
+			if err != nil {
+				return nil, err
+			}
+			crossings = append(crossings, components...)
+			continue
+		}
+		stock, threshold, err := adjustStock(tx, l.Product.ID, -l.Quantity, stockMovement{Type: "sale", OrderID: orderID})
+		if err == errInsufficientStock && l.Product.PreorderEnabled {
+			if err := reservePreorder(tx, l); err != nil {
+				return nil, err
+			}
+			lines[i].Preorder = true
+			continue
+		} else if err == errInsufficientStock {
+			return nil, newOrderError(http.StatusConflict, "Stock insuffisant pour %s", l.Product.Name)
+		} else if err != nil {
+			return nil, err
+		}
+		if stock <= threshold && stock+l.Quantity > threshold {
+			crossings = append(crossings, lowStockCrossing{l.Product.ID, l.Product.Name, stock, threshold})
+		}
+	}
+	return crossings, nil
+}
+
+// itemComponent est un composant d'un coffret vendu (table order_item_components)
+type itemComponent struct {
+	ProductID *int // NULL si le composant a été supprimé depuis
+	Name      string
+	Quantity  int // Par coffret
+}
+
+// loadItemComponents lit la composition d'un coffret au moment de sa vente (vide pour
+// un produit simple)
+func loadItemComponents(q queryer, itemID int) ([]itemComponent, error) {
+	rows, err := q.Query(
+		"SELECT component_id, component_name, quantity FROM order_item_components WHERE order_item_id = $1 ORDER BY id", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make([]itemComponent, 0)
+	for rows.Next() {
+		var c itemComponent
+		if err := rows.Scan(&c.ProductID, &c.Name, &c.Quantity); err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// restoreStock remet en stock des articles annulés ou retournés. Pour un coffret, ce sont
+// les composants vendus (order_item_components) qui reviennent en stock, même si la
+// composition du coffret a changé depuis.
+func restoreStock(tx *sql.Tx, itemID, productID, quantity, orderID, actorID int, reason string) error {
+	components, err := loadItemComponents(tx, itemID)
+	if err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		_, err := receiveStock(tx, productID, quantity, stockMovement{
+			Type: "cancellation", OrderID: orderID, ActorID: actorID, Reason: reason,
+		})
+		return err
+	}
+	for _, c := range components {
+		if c.ProductID == nil {
+			continue // Composant supprimé : plus rien à remettre en stock
+		}
+		if _, err := receiveStock(tx, *c.ProductID, c.Quantity*quantity, stockMovement{
+			Type: "cancellation", OrderID: orderID, ActorID: actorID, Reason: reason,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkoutInput regroupe ce qui détermine le montant d'une commande
+type checkoutInput struct {
+	Items           []models.CartItem
+	CouponCode      string
+	Email           string
+	DeliveryMethod  string
+	ShippingCity    string
+	ShippingCommune string
+}
+
+// checkoutTotals est le détail du calcul : identique pour le devis et la commande
+type checkoutTotals struct {
+	Lines    []orderLine
+	Subtotal float64
+	Coupon   *appliedCoupon
+	Discount float64
+	Shipping *shippingQuote
+	Total    float64
+}
+
+// computeCheckout calcule sous-total, remise, frais de port et total.
+// forUpdate est utilisé par CreateOrder (dans sa transaction) pour verrouiller le coupon.
+func computeCheckout(q queryer, in checkoutInput, forUpdate bool) (*checkoutTotals, error) {
+	lines, err := loadOrderLines(q, in.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &checkoutTotals{Lines: lines, Subtotal: linesSubtotal(lines)}
+
+	if strings.TrimSpace(in.CouponCode) != "" {
+		t.Coupon, err = evaluateCoupon(q, in.CouponCode, in.Email, lines, forUpdate)
+		if err != nil {
+			return nil, err
+		}
+		t.Discount = t.Coupon.Discount
+	}
+
+	freeShipping := t.Coupon != nil && t.Coupon.FreeShipping
+	t.Shipping, err = quoteShipping(q, in.DeliveryMethod, in.ShippingCity, in.ShippingCommune, t.Subtotal-t.Discount, freeShipping)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Total = roundAmount(t.Subtotal - t.Discount + t.Shipping.Fee)
+	return t, nil
+}
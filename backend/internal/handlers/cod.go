@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type CODHandler struct {
+	DB *sql.DB
+}
+
+// Colonnes lues pour une commande en paiement à la livraison (table orders aliasée "o")
+const codOrderColumns = `o.id, o.customer_firstname || ' ' || o.customer_lastname, COALESCE(o.customer_phone, ''),
+               COALESCE(o.shipping_city, ''), COALESCE(o.shipping_commune, ''), COALESCE(o.shipping_address, ''),
+               o.status, o.rider_id, o.rider_assigned_at, o.total_amount,
+               o.cod_collected_amount, o.cod_collected_at, o.cod_discrepancy`
+
+func scanCODOrder(row interface{ Scan(...interface{}) error }) (models.CODOrder, error) {
+	var o models.CODOrder
+	err := row.Scan(&o.OrderID, &o.CustomerName, &o.CustomerPhone, &o.ShippingCity, &o.ShippingCommune,
+		&o.ShippingAddress, &o.Status, &o.RiderID, &o.AssignedAt, &o.ExpectedAmount,
+		&o.CollectedAmount, &o.CollectedAt, &o.Discrepancy)
+	o.Flagged = o.Discrepancy != nil && *o.Discrepancy != 0
+	return o, err
+}
+
+// codOrderIDFromPath extrait l'ID de /cod/orders/{id}/{action}
+func codOrderIDFromPath(path, action string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/cod/orders/"), "/"+action))
+}
+
+// PUT /cod/orders/{id}/rider — Assigner un livreur à une commande [ADMIN]
+func (h *CODHandler) AssignRider(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := codOrderIDFromPath(r.URL.Path, "rider")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.AssignRiderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	var isRider bool
+	err = h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'rider')", req.RiderID).Scan(&isRider)
+	if err != nil {
+		fmt.Printf("Erreur BDD AssignRider : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if !isRider {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce livreur n'existe pas"})
+		return
+	}
+
+	// Réassignation possible tant que l'encaissement n'a pas été déclaré
+	res, err := h.DB.Exec(`
+        UPDATE orders SET rider_id = $1, rider_assigned_at = NOW()
+        WHERE id = $2 AND delivery_method = 'shipping' AND status <> 'annulé' AND cod_collected_amount IS NULL`,
+		req.RiderID, orderID,
+	)
+	if err != nil {
+		fmt.Printf("Erreur BDD AssignRider order=%d : %v\n", orderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'assignation"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Commande introuvable, annulée, en retrait magasin ou déjà encaissée"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Livreur assigné"})
+}
+
+// POST /cod/orders/{id}/collect — Déclarer le montant encaissé [RIDER, ADMIN]
+// Le livreur ne déclare qu'une fois, pour ses propres commandes ; l'admin peut corriger.
+// La commande passe en "livré" et l'écart avec le montant attendu est enregistré.
+func (h *CODHandler) RecordCollection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := codOrderIDFromPath(r.URL.Path, "collect")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var in models.CODCollectionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if in.Amount < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le montant ne peut pas être négatif"})
+		return
+	}
+	amount := roundAmount(in.Amount)
+	userID := middleware.UserID(r)
+	// Gestionnaire de commandes : peut encaisser pour n'importe quel livreur
+	isAdmin := middleware.HasPermission(middleware.Role(r), middleware.PermOrdersManage)
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	var paymentMethod, status string
+	var riderID *int
+	var expected float64
+	var collected *float64
+	err = tx.QueryRow(
+		"SELECT payment_method, status, rider_id, total_amount, cod_collected_amount FROM orders WHERE id = $1 FOR UPDATE", orderID,
+	).Scan(&paymentMethod, &status, &riderID, &expected, &collected)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Commande introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD RecordCollection order=%d : %v\n", orderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if paymentMethod != "cod" || status == "annulé" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cette commande n'est pas payable à la livraison"})
+		return
+	}
+	if !isAdmin {
+		if riderID == nil || *riderID != userID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Cette commande ne vous est pas assignée"})
+			return
+		}
+		if collected != nil {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Encaissement déjà déclaré : contactez un administrateur pour le corriger"})
+			return
+		}
+	}
+
+	discrepancy := roundAmount(amount - expected)
+	_, err = tx.Exec(`
+        UPDATE orders
+        SET cod_collected_amount = $1, cod_collected_at = NOW(), cod_discrepancy = $2,
+            status = CASE WHEN status = 'pending' THEN 'livré' ELSE status END
+        WHERE id = $3`,
+		amount, discrepancy, orderID,
+	)
+	if err == nil {
+		_, err = tx.Exec(
+			"INSERT INTO cod_collections (order_id, rider_id, recorded_by, amount, note) VALUES ($1, $2, $3, $4, NULLIF($5, ''))",
+			orderID, riderID, userID, amount, strings.TrimSpace(in.Note),
+		)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD RecordCollection order=%d : %v\n", orderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'enregistrement de l'encaissement"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Encaissement enregistré",
+		"expected":    expected,
+		"collected":   amount,
+		"discrepancy": discrepancy,
+		"flagged":     discrepancy != 0,
+	})
+}
+
+// GET /cod/my-orders — Commandes à livrer du livreur connecté [RIDER]
+// Commandes non encore encaissées, plus celles encaissées aujourd'hui.
+func (h *CODHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := h.DB.Query(`
+        SELECT `+codOrderColumns+`
+        FROM orders o
+        WHERE o.rider_id = $1 AND o.status <> 'annulé'
+          AND (o.cod_collected_at IS NULL OR o.cod_collected_at::date = CURRENT_DATE)
+        ORDER BY o.rider_assigned_at ASC`,
+		middleware.UserID(r),
+	)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetMyOrders rider : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des commandes"})
+		return
+	}
+	defer rows.Close()
+
+	orders := make([]models.CODOrder, 0)
+	for rows.Next() {
+		o, err := scanCODOrder(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan cod orders : %v\n", err)
+			continue
+		}
+		orders = append(orders, o)
+	}
+
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GET /cod/reconciliation?date=2026-10-19&rider_id=5 — Rapprochement journalier [RIDER, ADMIN]
+// Commandes payables à la livraison regroupées par livreur, pour le jour d'assignation.
+// Un livreur ne voit que son propre rapport.
+func (h *CODHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	date := time.Now().Format("2006-01-02")
+	if d := q.Get("date"); d != "" {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Paramètre date invalide (AAAA-MM-JJ)"})
+			return
+		}
+		date = d
+	}
+
+	query := `
+        SELECT u.id, u.full_name, ` + codOrderColumns + `
+        FROM orders o
+        JOIN users u ON u.id = o.rider_id
+        WHERE o.payment_method = 'cod' AND o.status <> 'annulé' AND o.rider_assigned_at::date = $1::date`
+	args := []interface{}{date}
+	if !middleware.HasPermission(middleware.Role(r), middleware.PermOrdersManage) {
+		args = append(args, middleware.UserID(r))
+		query += " AND o.rider_id = $2"
+	} else if riderID, err := strconv.Atoi(q.Get("rider_id")); err == nil {
+		args = append(args, riderID)
+		query += " AND o.rider_id = $2"
+	}
+	query += " ORDER BY u.full_name ASC, o.rider_assigned_at ASC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetReconciliation : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors du calcul du rapprochement"})
+		return
+	}
+	defer rows.Close()
+
+	reports := make([]*models.RiderReconciliation, 0)
+	byRider := make(map[int]*models.RiderReconciliation)
+	for rows.Next() {
+		var riderID int
+		var riderName string
+		o, err := scanCODOrder(&prefixedScanner{rows: rows, prefix: []interface{}{&riderID, &riderName}})
+		if err != nil {
+			fmt.Printf("Erreur Scan reconciliation : %v\n", err)
+			continue
+		}
+
+		rep, ok := byRider[riderID]
+		if !ok {
+			rep = &models.RiderReconciliation{RiderID: riderID, RiderName: riderName, Date: date, Orders: make([]models.CODOrder, 0)}
+			byRider[riderID] = rep
+			reports = append(reports, rep)
+		}
+		rep.OrdersCount++
+		rep.ExpectedTotal += o.ExpectedAmount
+		if o.CollectedAmount != nil {
+			rep.CollectedCount++
+			rep.CollectedTotal += *o.CollectedAmount
+			rep.Difference += *o.Discrepancy
+		} else {
+			rep.OutstandingTotal += o.ExpectedAmount
+		}
+		if o.Flagged {
+			rep.FlaggedCount++
+		}
+		rep.Orders = append(rep.Orders, o)
+	}
+
+	for _, rep := range reports {
+		rep.ExpectedTotal = roundAmount(rep.ExpectedTotal)
+		rep.CollectedTotal = roundAmount(rep.CollectedTotal)
+		rep.OutstandingTotal = roundAmount(rep.OutstandingTotal)
+		rep.Difference = roundAmount(rep.Difference)
+	}
+
+	json.NewEncoder(w).Encode(reports)
+}
+
+// prefixedScanner lit des colonnes supplémentaires placées avant celles de scanCODOrder
+type prefixedScanner struct {
+	rows   *sql.Rows
+	prefix []interface{}
+}
+
+func (s *prefixedScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(s.prefix, dest...)...)
+}
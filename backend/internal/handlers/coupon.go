@@ -0,0 +1,453 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type CouponHandler struct {
+	DB *sql.DB
+}
+
+// appliedCoupon est le résultat de l'évaluation d'un code sur un panier
+type appliedCoupon struct {
+	ID           int
+	Code         string
+	DiscountType string
+	Discount     float64 // Montant déduit du sous-total des articles
+	FreeShipping bool
+}
+
+// evaluateCoupon vérifie qu'un code est utilisable pour ce panier et calcule la remise.
+// Avec forUpdate, la ligne du coupon est verrouillée jusqu'à la fin de la transaction :
+// les commandes concurrentes sur le même code sont sérialisées et ne peuvent pas
+// dépasser max_uses / max_uses_per_customer.
+func evaluateCoupon(q queryer, code, email string, lines []orderLine, forUpdate bool) (*appliedCoupon, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, newOrderError(http.StatusBadRequest, "Code promo requis")
+	}
+
+	query := `
+        SELECT id, code, discount_type, discount_value, min_basket, max_uses, max_uses_per_customer,
+               used_count, is_active,
+               (starts_at IS NULL OR starts_at <= NOW()) AS started,
+               (ends_at IS NULL OR ends_at > NOW())      AS not_ended
+        FROM coupons WHERE UPPER(code) = UPPER($1)`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var c appliedCoupon
+	var value, minBasket float64
+	var maxUses, maxPerCustomer *int
+	var usedCount int
+	var isActive, started, notEnded bool
+	err := q.QueryRow(query, code).Scan(&c.ID, &c.Code, &c.DiscountType, &value, &minBasket, &maxUses, &maxPerCustomer,
+		&usedCount, &isActive, &started, &notEnded)
+	if err == sql.ErrNoRows {
+		return nil, newOrderError(http.StatusNotFound, "Code promo invalide")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !isActive || !started {
+		return nil, newOrderError(http.StatusBadRequest, "Code promo invalide")
+	}
+	if !notEnded {
+		return nil, newOrderError(http.StatusBadRequest, "Ce code promo a expiré")
+	}
+	if maxUses != nil && usedCount >= *maxUses {
+		return nil, newOrderError(http.StatusConflict, "Ce code promo a atteint sa limite d'utilisation")
+	}
+
+	if maxPerCustomer != nil {
+		// La limite par client se base sur l'email (les commandes ne sont pas liées à users par FK)
+		if strings.TrimSpace(email) == "" {
+			return nil, newOrderError(http.StatusBadRequest, "Email requis pour utiliser ce code promo")
+		}
+		var customerUses int
+		err := q.QueryRow(
+			"SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND LOWER(customer_email) = LOWER($2)",
+			c.ID, strings.TrimSpace(email),
+		).Scan(&customerUses)
+		if err != nil {
+			return nil, err
+		}
+		if customerUses >= *maxPerCustomer {
+			return nil, newOrderError(http.StatusConflict, "Vous avez déjà utilisé ce code promo")
+		}
+	}
+
+	// Restriction catégories : seuls les articles éligibles comptent pour la remise
+	rows, err := q.Query("SELECT category_id FROM coupon_categories WHERE coupon_id = $1", c.ID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[int]bool)
+	for rows.Next() {
+		var categoryID int
+		if err := rows.Scan(&categoryID); err == nil {
+			allowed[categoryID] = true
+		}
+	}
+	rows.Close()
+
+	if err := applyCouponDiscount(&c, value, minBasket, allowed, lines); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// applyCouponDiscount calcule la remise du code sur le panier : seuls les articles des
+// catégories autorisées comptent (toutes si allowed est vide), le panier minimum porte
+// sur le sous-total complet et une remise fixe ne dépasse jamais le montant éligible.
+func applyCouponDiscount(c *appliedCoupon, value, minBasket float64, allowed map[int]bool, lines []orderLine) error {
+	subtotal := linesSubtotal(lines)
+	eligible := subtotal
+	if len(allowed) > 0 {
+		eligible = 0
+		for _, l := range lines {
+			if allowed[l.Product.CategoryID] {
+				eligible += l.total()
+			}
+		}
+		if eligible == 0 {
+			return newOrderError(http.StatusBadRequest, "Ce code promo ne s'applique à aucun article du panier")
+		}
+	}
+
+	if subtotal < minBasket {
+		return newOrderError(http.StatusBadRequest, "Panier minimum de %.0f FCFA requis pour ce code promo", minBasket)
+	}
+
+	switch c.DiscountType {
+	case "percent":
+		c.Discount = roundAmount(eligible * value / 100)
+	case "fixed":
+		c.Discount = value
+		if c.Discount > eligible {
+			c.Discount = eligible
+		}
+	case "free_shipping":
+		c.FreeShipping = true
+	}
+	return nil
+}
+
+// redeemCoupon enregistre l'utilisation du code (dans la transaction de CreateOrder,
+// après evaluateCoupon avec forUpdate)
+func redeemCoupon(tx *sql.Tx, c *appliedCoupon, orderID int, email string) error {
+	_, err := tx.Exec(
+		`INSERT INTO coupon_redemptions (coupon_id, order_id, customer_email, discount_amount) VALUES ($1, $2, $3, $4)`,
+		c.ID, orderID, strings.TrimSpace(email), c.Discount,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE coupons SET used_count = used_count + 1 WHERE id = $1", c.ID)
+	return err
+}
+
+// POST /cart/validate-coupon — Vérifier un code promo sur le panier (public)
+// Ne consomme pas le code : l'utilisation est enregistrée uniquement par CreateOrder.
+func (h *CouponHandler) ValidateCoupon(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.ValidateCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	lines, err := loadOrderLines(h.DB, req.Items)
+	if err != nil {
+		writeOrderError(w, "ValidateCoupon", err)
+		return
+	}
+
+	c, err := evaluateCoupon(h.DB, req.Code, req.Email, lines, false)
+	if err != nil {
+		writeOrderError(w, "ValidateCoupon", err)
+		return
+	}
+
+	subtotal := linesSubtotal(lines)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":           true,
+		"code":            c.Code,
+		"discount_type":   c.DiscountType,
+		"discount_amount": c.Discount,
+		"free_shipping":   c.FreeShipping,
+		"subtotal":        subtotal,
+		"total":           roundAmount(subtotal - c.Discount),
+	})
+}
+
+// validateCouponInput renvoie un message d'erreur en français, ou "" si la saisie est valide
+func validateCouponInput(in *models.CouponInput) string {
+	in.Code = strings.ToUpper(strings.TrimSpace(in.Code))
+	if in.Code == "" {
+		return "Le code est requis"
+	}
+	switch in.DiscountType {
+	case "percent":
+		if in.DiscountValue <= 0 || in.DiscountValue > 100 {
+			return "Pourcentage invalide (1-100 requis)"
+		}
+	case "fixed":
+		if in.DiscountValue <= 0 {
+			return "Le montant de la remise doit être positif"
+		}
+	case "free_shipping":
+		in.DiscountValue = 0
+	default:
+		return "discount_type doit valoir \"percent\", \"fixed\" ou \"free_shipping\""
+	}
+	if in.MinBasket < 0 {
+		return "Le panier minimum ne peut pas être négatif"
+	}
+	if (in.MaxUses != nil && *in.MaxUses <= 0) || (in.MaxUsesPerCustomer != nil && *in.MaxUsesPerCustomer <= 0) {
+		return "Les limites d'utilisation doivent être positives"
+	}
+	if in.StartsAt != nil && in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+		return "Dates invalides : ends_at doit être postérieure à starts_at"
+	}
+	return ""
+}
+
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// replaceCouponCategories remplace la restriction catégories (dans la transaction appelante)
+func replaceCouponCategories(tx *sql.Tx, couponID int, categoryIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM coupon_categories WHERE coupon_id = $1", couponID); err != nil {
+		return err
+	}
+	for _, categoryID := range categoryIDs {
+		_, err := tx.Exec(
+			"INSERT INTO coupon_categories (coupon_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			couponID, categoryID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /coupons — Liste des codes promo [ADMIN]
+func (h *CouponHandler) GetCoupons(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := h.DB.Query(`
+        SELECT c.id, c.code, COALESCE(c.description, ''), c.discount_type, c.discount_value, c.min_basket,
+               c.max_uses, c.max_uses_per_customer, c.used_count, c.starts_at, c.ends_at, c.is_active, c.created_at,
+               ARRAY(SELECT category_id FROM coupon_categories cc WHERE cc.coupon_id = c.id ORDER BY category_id)
+        FROM coupons c
+        ORDER BY c.created_at DESC`)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetCoupons : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des codes promo"})
+		return
+	}
+	defer rows.Close()
+
+	coupons := make([]models.Coupon, 0)
+	for rows.Next() {
+		var c models.Coupon
+		var categoryIDs []int64
+		if err := rows.Scan(&c.ID, &c.Code, &c.Description, &c.DiscountType, &c.DiscountValue, &c.MinBasket,
+			&c.MaxUses, &c.MaxUsesPerCustomer, &c.UsedCount, &c.StartsAt, &c.EndsAt, &c.IsActive, &c.CreatedAt,
+			pq.Array(&categoryIDs)); err != nil {
+			fmt.Printf("Erreur Scan coupons : %v\n", err)
+			continue
+		}
+		c.CategoryIDs = make([]int, 0, len(categoryIDs))
+		for _, id := range categoryIDs {
+			c.CategoryIDs = append(c.CategoryIDs, int(id))
+		}
+		coupons = append(coupons, c)
+	}
+
+	json.NewEncoder(w).Encode(coupons)
+}
+
+// POST /coupons — Créer un code promo [ADMIN]
+func (h *CouponHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in models.CouponInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateCouponInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	var id int
+	err = tx.QueryRow(
+		`INSERT INTO coupons (code, description, discount_type, discount_value, min_basket, max_uses,
+                              max_uses_per_customer, starts_at, ends_at, is_active)
+         VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		in.Code, in.Description, in.DiscountType, in.DiscountValue, in.MinBasket, in.MaxUses,
+		in.MaxUsesPerCustomer, utcOrNil(in.StartsAt), utcOrNil(in.EndsAt), isActive,
+	).Scan(&id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		tx.Rollback()
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce code existe déjà"})
+		return
+	}
+	if err == nil {
+		err = replaceCouponCategories(tx, id, in.CategoryIDs)
+	}
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD CreateCoupon : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la création du code promo"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur validation transaction"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Code promo créé"})
+}
+
+// PUT /coupons/{id} — Modifier un code promo [ADMIN]
+// used_count n'est pas modifiable : il reflète les commandes réellement passées.
+func (h *CouponHandler) UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/coupons/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var in models.CouponInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateCouponInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	res, err := tx.Exec(
+		`UPDATE coupons SET code=$1, description=NULLIF($2, ''), discount_type=$3, discount_value=$4, min_basket=$5,
+                max_uses=$6, max_uses_per_customer=$7, starts_at=$8, ends_at=$9, is_active=$10
+         WHERE id=$11`,
+		in.Code, in.Description, in.DiscountType, in.DiscountValue, in.MinBasket, in.MaxUses,
+		in.MaxUsesPerCustomer, utcOrNil(in.StartsAt), utcOrNil(in.EndsAt), isActive, id,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		tx.Rollback()
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce code existe déjà"})
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD UpdateCoupon id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification du code promo"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		tx.Rollback()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Code promo introuvable"})
+		return
+	}
+
+	if err := replaceCouponCategories(tx, id, in.CategoryIDs); err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD UpdateCoupon catégories id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification du code promo"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur validation transaction"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Code promo mis à jour avec succès"})
+}
+
+// DELETE /coupons/{id} — Supprimer un code promo [ADMIN]
+// Les commandes passées conservent coupon_code et discount_amount.
+func (h *CouponHandler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/coupons/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM coupons WHERE id = $1", id)
+	if err != nil {
+		fmt.Printf("Erreur BDD DeleteCoupon id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la suppression"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Code promo introuvable"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Code promo supprimé"})
+}
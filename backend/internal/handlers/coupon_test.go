@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+func couponTestLine(categoryID int, price float64, quantity int) orderLine {
+	return orderLine{Product: models.Product{CategoryID: categoryID, FinalPrice: price}, Quantity: quantity}
+}
+
+func TestApplyCouponDiscount(t *testing.T) {
+	// Sous-total : 2 x 10 000 (catégorie 1) + 5 000 (catégorie 2) = 25 000 FCFA
+	lines := []orderLine{couponTestLine(1, 10000, 2), couponTestLine(2, 5000, 1)}
+
+	tests := []struct {
+		name         string
+		discountType string
+		value        float64
+		minBasket    float64
+		allowed      map[int]bool
+		wantDiscount float64
+		wantFree     bool
+		wantStatus   int // 0 = pas d'erreur
+	}{
+		{name: "pourcentage sur tout le panier", discountType: "percent", value: 10, wantDiscount: 2500},
+		{name: "pourcentage arrondi au centime", discountType: "percent", value: 3.333, wantDiscount: 833.25},
+		{name: "pourcentage limité aux catégories", discountType: "percent", value: 10, allowed: map[int]bool{2: true}, wantDiscount: 500},
+		{name: "montant fixe", discountType: "fixed", value: 3000, wantDiscount: 3000},
+		{name: "montant fixe plafonné au montant éligible", discountType: "fixed", value: 8000, allowed: map[int]bool{2: true}, wantDiscount: 5000},
+		{name: "livraison offerte", discountType: "free_shipping", wantFree: true},
+		{name: "panier minimum atteint", discountType: "fixed", value: 1000, minBasket: 25000, wantDiscount: 1000},
+		{name: "panier minimum non atteint", discountType: "fixed", value: 1000, minBasket: 30000, wantStatus: http.StatusBadRequest},
+		{name: "aucun article éligible", discountType: "percent", value: 10, allowed: map[int]bool{3: true}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &appliedCoupon{ID: 1, Code: "BEBE", DiscountType: tt.discountType}
+			err := applyCouponDiscount(c, tt.value, tt.minBasket, tt.allowed, lines)
+
+			if tt.wantStatus != 0 {
+				var oe *orderError
+				if !errors.As(err, &oe) || oe.status != tt.wantStatus {
+					t.Fatalf("erreur = %v, statut %d attendu", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erreur inattendue : %v", err)
+			}
+			if c.Discount != tt.wantDiscount {
+				t.Errorf("remise = %v, %v attendu", c.Discount, tt.wantDiscount)
+			}
+			if c.FreeShipping != tt.wantFree {
+				t.Errorf("livraison offerte = %v, %v attendu", c.FreeShipping, tt.wantFree)
+			}
+		})
+	}
+}
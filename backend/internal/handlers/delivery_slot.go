@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+type DeliverySlotHandler struct {
+	DB *sql.DB
+}
+
+// Dates et heures formatées par la BDD : le frontend reçoit "2026-10-20" / "09:00"
+const deliverySlotColumns = `id, slot_type, shipping_zone_id, to_char(slot_date, 'YYYY-MM-DD'),
+               to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), capacity, booked_count, is_active`
+
+func scanDeliverySlot(row interface{ Scan(...interface{}) error }) (models.DeliverySlot, error) {
+	var s models.DeliverySlot
+	err := row.Scan(&s.ID, &s.SlotType, &s.ShippingZoneID, &s.SlotDate, &s.StartTime, &s.EndTime,
+		&s.Capacity, &s.BookedCount, &s.IsActive)
+	s.Remaining = s.Capacity - s.BookedCount
+	if s.Remaining < 0 {
+		s.Remaining = 0
+	}
+	return s, err
+}
+
+// slotTypeForMethod associe le mode de livraison de la commande au type de créneau
+func slotTypeForMethod(method string) string {
+	if method == "pickup" {
+		return "pickup"
+	}
+	return "delivery"
+}
+
+// bookDeliverySlot réserve une place sur le créneau dans la transaction de CreateOrder.
+// L'UPDATE conditionnel verrouille la ligne : deux commandes simultanées ne peuvent
+// pas prendre la dernière place.
+func bookDeliverySlot(tx *sql.Tx, slotID int, method string, zone *models.ShippingZone) error {
+	var slotType string
+	var slotZoneID *int
+	err := tx.QueryRow(`
+        UPDATE delivery_slots SET booked_count = booked_count + 1
+        WHERE id = $1 AND is_active AND booked_count < capacity AND (slot_date + start_time) > NOW()
+        RETURNING slot_type, shipping_zone_id`,
+		slotID,
+	).Scan(&slotType, &slotZoneID)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM delivery_slots WHERE id = $1 AND is_active)", slotID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return newOrderError(http.StatusBadRequest, "Créneau introuvable")
+		}
+		return newOrderError(http.StatusConflict, "Ce créneau est complet ou déjà passé, merci d'en choisir un autre")
+	} else if err != nil {
+		return err
+	}
+
+	// La réservation est annulée par le rollback si le créneau ne correspond pas à la commande
+	if slotType != slotTypeForMethod(method) {
+		return newOrderError(http.StatusBadRequest, "Ce créneau ne correspond pas au mode de livraison choisi")
+	}
+	if slotZoneID != nil && (zone == nil || zone.ID != *slotZoneID) {
+		return newOrderError(http.StatusBadRequest, "Ce créneau n'est pas disponible pour votre zone de livraison")
+	}
+	return nil
+}
+
+// GET /delivery-slots?type=delivery&city=Abidjan&commune=Cocody — Créneaux disponibles (public)
+// Paramètres : type (delivery | pickup), zone_id OU city/commune (requis pour delivery),
+// from (AAAA-MM-JJ, aujourd'hui par défaut), days (14 par défaut, 60 max).
+// Avec ?all=true [ADMIN] : tous les créneaux de la période, y compris complets ou désactivés.
+func (h *DeliverySlotHandler) GetSlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	all := q.Get("all") == "true"
+
+	from := time.Now().UTC().Format("2006-01-02")
+	if f := q.Get("from"); f != "" {
+		if _, err := time.Parse("2006-01-02", f); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Paramètre from invalide (AAAA-MM-JJ)"})
+			return
+		}
+		from = f
+	}
+	days := 14
+	if d, err := strconv.Atoi(q.Get("days")); err == nil && d > 0 && d <= 60 {
+		days = d
+	}
+
+	query := "SELECT " + deliverySlotColumns + " FROM delivery_slots WHERE slot_date >= $1::date AND slot_date < $1::date + $2::int"
+	args := []interface{}{from, days}
+
+	slotType := q.Get("type")
+	if slotType != "" {
+		if slotType != "delivery" && slotType != "pickup" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Paramètre type invalide (delivery ou pickup)"})
+			return
+		}
+		args = append(args, slotType)
+		query += fmt.Sprintf(" AND slot_type = $%d", len(args))
+	} else if !all {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Paramètre type requis (delivery ou pickup)"})
+		return
+	}
+
+	// Zone : explicite, ou déduite de l'adresse saisie au checkout
+	var zoneID *int
+	if z, err := strconv.Atoi(q.Get("zone_id")); err == nil {
+		zoneID = &z
+	} else if city := q.Get("city"); city != "" {
+		zone, err := findShippingZone(h.DB, city, q.Get("commune"))
+		if err != nil {
+			fmt.Printf("Erreur BDD GetSlots zone : %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des créneaux"})
+			return
+		}
+		if zone == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Livraison non disponible pour cette adresse"})
+			return
+		}
+		zoneID = &zone.ID
+	}
+	if zoneID != nil {
+		// Les créneaux sans zone (retrait boutique) restent proposés à tous
+		args = append(args, *zoneID)
+		query += fmt.Sprintf(" AND (shipping_zone_id = $%d OR shipping_zone_id IS NULL)", len(args))
+	} else if slotType == "delivery" && !all {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "zone_id ou city requis pour la livraison à domicile"})
+		return
+	}
+
+	if !all {
+		query += " AND is_active AND booked_count < capacity AND (slot_date + start_time) > NOW()"
+	}
+	query += " ORDER BY slot_date ASC, start_time ASC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetSlots : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des créneaux"})
+		return
+	}
+	defer rows.Close()
+
+	slots := make([]models.DeliverySlot, 0)
+	for rows.Next() {
+		s, err := scanDeliverySlot(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan delivery_slots : %v\n", err)
+			continue
+		}
+		slots = append(slots, s)
+	}
+
+	json.NewEncoder(w).Encode(slots)
+}
+
+// validateDeliverySlotInput renvoie un message d'erreur en français, ou "" si la saisie est valide
+func validateDeliverySlotInput(in *models.DeliverySlotInput) string {
+	if in.SlotType != "delivery" && in.SlotType != "pickup" {
+		return "slot_type doit valoir \"delivery\" ou \"pickup\""
+	}
+	if in.SlotType == "delivery" && in.ShippingZoneID == nil {
+		return "Une zone de livraison est requise pour un créneau de livraison"
+	}
+	if _, err := time.Parse("2006-01-02", in.SlotDate); err != nil {
+		return "slot_date invalide (AAAA-MM-JJ)"
+	}
+	start, errStart := time.Parse("15:04", in.StartTime)
+	end, errEnd := time.Parse("15:04", in.EndTime)
+	if errStart != nil || errEnd != nil {
+		return "Heures invalides (HH:MM)"
+	}
+	if !end.After(start) {
+		return "L'heure de fin doit être postérieure à l'heure de début"
+	}
+	if in.Capacity <= 0 {
+		return "La capacité doit être positive"
+	}
+	return ""
+}
+
+// POST /delivery-slots — Créer un créneau [ADMIN]
+func (h *DeliverySlotHandler) CreateSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in models.DeliverySlotInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateDeliverySlotInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	var id int
+	err := h.DB.QueryRow(
+		`INSERT INTO delivery_slots (slot_type, shipping_zone_id, slot_date, start_time, end_time, capacity, is_active)
+         VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		in.SlotType, in.ShippingZoneID, in.SlotDate, in.StartTime, in.EndTime, in.Capacity, isActive,
+	).Scan(&id)
+	if err != nil {
+		fmt.Printf("Erreur BDD CreateSlot : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la création du créneau"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Créneau créé"})
+}
+
+// PUT /delivery-slots/{id} — Modifier un créneau [ADMIN]
+// La capacité ne peut pas descendre sous le nombre de réservations existantes.
+func (h *DeliverySlotHandler) UpdateSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/delivery-slots/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var in models.DeliverySlotInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateDeliverySlotInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	var booked int
+	err = h.DB.QueryRow(
+		`UPDATE delivery_slots SET slot_type=$1, shipping_zone_id=$2, slot_date=$3, start_time=$4, end_time=$5,
+                capacity=$6, is_active=$7
+         WHERE id=$8 AND booked_count <= $6
+         RETURNING booked_count`,
+		in.SlotType, in.ShippingZoneID, in.SlotDate, in.StartTime, in.EndTime, in.Capacity, isActive, id,
+	).Scan(&booked)
+	if err == sql.ErrNoRows {
+		var exists bool
+		h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM delivery_slots WHERE id = $1)", id).Scan(&exists)
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Créneau introuvable"})
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "La capacité ne peut pas être inférieure au nombre de réservations"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD UpdateSlot id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification du créneau"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Créneau mis à jour avec succès"})
+}
+
+// DELETE /delivery-slots/{id} — Supprimer un créneau sans réservation [ADMIN]
+func (h *DeliverySlotHandler) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/delivery-slots/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var booked int
+	err = h.DB.QueryRow("SELECT booked_count FROM delivery_slots WHERE id = $1", id).Scan(&booked)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Créneau introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD DeleteSlot id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la suppression"})
+		return
+	}
+	if booked > 0 {
+		// Les commandes perdraient leur créneau : on désactive plutôt que de supprimer
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce créneau a des réservations : désactivez-le plutôt que de le supprimer"})
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM delivery_slots WHERE id = $1 AND booked_count = 0", id); err != nil {
+		fmt.Printf("Erreur BDD DeleteSlot id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la suppression"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Créneau supprimé"})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/notifications"
+)
+
+type InventoryHandler struct {
+	DB *sql.DB
+}
+
+// recordLowStockAlerts enregistre les passages sous le seuil dans la transaction de commande
+func recordLowStockAlerts(tx *sql.Tx, orderID int, crossings []lowStockCrossing) error {
+	for _, c := range crossings {
+		_, err := tx.Exec(`
+            INSERT INTO low_stock_alerts (product_id, order_id, stock_quantity, threshold)
+            VALUES ($1, $2, $3, $4)`,
+			c.ProductID, orderID, c.StockQuantity, c.Threshold)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyLowStock prévient l'équipe après la validation de la commande. Appelée dans une
+// goroutine : l'envoi ne retarde pas la réponse au client.
+func notifyLowStock(notifier *notifications.Notifier, orderID int, crossings []lowStockCrossing) {
+	if len(crossings) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(crossings))
+	for _, c := range crossings {
+		state := fmt.Sprintf("%d restant(s), seuil %d", c.StockQuantity, c.Threshold)
+		if c.StockQuantity == 0 {
+			state = "RUPTURE"
+		}
+		lines = append(lines, fmt.Sprintf("- %s (id %d) : %s", c.Name, c.ProductID, state))
+	}
+	subject := fmt.Sprintf("Stock bas : %d produit(s) à réapprovisionner", len(crossings))
+	body := fmt.Sprintf("La commande n°%d a fait passer sous le seuil d'alerte :\n%s\n\nDétail : GET /inventory",
+		orderID, strings.Join(lines, "\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := notifier.Alert(ctx, "low_stock", subject, body); err != nil {
+		fmt.Printf("Alerte stock bas commande n°%d : %v\n", orderID, err)
+	}
+}
+
+// GET /inventory?days=30&low_only=true — Tableau de bord des stocks [ADMIN]
+// Trié par jours de couverture croissants (stock / ventes quotidiennes sur les `days`
+// derniers jours) : les produits sans vente récente viennent en dernier.
+func (h *InventoryHandler) GetInventory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	days := 30
+	if d, err := strconv.Atoi(q.Get("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+
+	query := `
+        WITH sales AS (
+            SELECT oi.product_id, SUM(oi.quantity - oi.cancelled_quantity) AS units
+            FROM order_items oi
+            JOIN orders o ON o.id = oi.order_id
+            WHERE oi.product_id IS NOT NULL
+              AND o.created_at > NOW() - make_interval(days => $1)
+              AND o.status NOT IN ('annulé', 'pending_payment', 'payment_failed', 'refunded')
+            GROUP BY oi.product_id
+        ),
+        last_alerts AS (
+            SELECT product_id, MAX(created_at) AS last_alert_at FROM low_stock_alerts GROUP BY product_id
+        )
+        SELECT p.id, p.name, COALESCE(p.sku, ''), p.stock_quantity, p.low_stock_threshold,
+               COALESCE(s.units, 0), la.last_alert_at
+        FROM products p
+        LEFT JOIN sales s ON s.product_id = p.id
+        LEFT JOIN last_alerts la ON la.product_id = p.id
+        WHERE NOT p.is_bundle   -- Coffrets : le stock suivi est celui des composants
+          AND p.archived_at IS NULL`
+	if q.Get("low_only") == "true" {
+		query += " AND p.stock_quantity <= p.low_stock_threshold"
+	}
+	query += `
+        ORDER BY CASE WHEN COALESCE(s.units, 0) > 0 THEN p.stock_quantity::float8 / s.units END ASC NULLS LAST,
+                 p.stock_quantity ASC, p.id`
+
+	rows, err := h.DB.Query(query, days)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetInventory : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.InventoryItem, 0)
+	for rows.Next() {
+		var it models.InventoryItem
+		if err := rows.Scan(&it.ProductID, &it.Name, &it.SKU, &it.StockQuantity, &it.LowStockThreshold,
+			&it.UnitsSold, &it.LastAlertAt); err != nil {
+			fmt.Printf("Erreur scan inventaire : %v\n", err)
+			continue
+		}
+		it.IsLow = it.StockQuantity <= it.LowStockThreshold
+		it.DailySales = roundAmount(float64(it.UnitsSold) / float64(days))
+		if it.UnitsSold > 0 {
+			cover := roundAmount(float64(it.StockQuantity) * float64(days) / float64(it.UnitsSold))
+			it.DaysOfCover = &cover
+		}
+		items = append(items, it)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":  days,
+		"items": items,
+	})
+}
+
+// PUT /inventory/{product_id}/threshold — Seuil d'alerte d'un produit [ADMIN]
+func (h *InventoryHandler) UpdateThreshold(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/inventory/"), "/threshold"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.LowStockThresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LowStockThreshold == nil || *req.LowStockThreshold < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Seuil invalide (entier positif ou nul)"})
+		return
+	}
+
+	res, err := h.DB.Exec("UPDATE products SET low_stock_threshold = $1 WHERE id = $2", *req.LowStockThreshold, id)
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateThreshold : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur mise à jour du seuil"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":             "Seuil mis à jour",
+		"product_id":          id,
+		"low_stock_threshold": *req.LowStockThreshold,
+	})
+}
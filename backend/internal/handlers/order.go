@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"akwaba-bebe/backend/internal/config"
+	"akwaba-bebe/backend/internal/middleware"
 	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/notifications"
+	"akwaba-bebe/backend/internal/payments"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -14,7 +17,9 @@ import (
 )
 
 type OrderHandler struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Payments *payments.Registry
+	Notifier *notifications.Notifier // Alertes de stock bas
 }
 
 // jwtKeyOrder utilise la même source que auth.go via config.JWTKey().
@@ -36,6 +41,8 @@ type OrderSummary struct {
 	Status         string  `json:"status"`
 	CreatedAt      string  `json:"created_at"`
 	DeliveryMethod string  `json:"delivery_method"`
+	// Rempli uniquement par GetMyOrders (historique client lu depuis le snapshot)
+	Items []OrderItemResponse `json:"items,omitempty"`
 }
 
 // Pour le détail d'une commande (GetOrderDetails)
@@ -44,19 +51,66 @@ type OrderDetailResponse struct {
 	CustomerName    string              `json:"customer_name"`
 	CustomerEmail   string              `json:"customer_email"`
 	CustomerPhone   string              `json:"customer_phone"`
+	Subtotal        float64             `json:"subtotal"`
+	DiscountAmount  float64             `json:"discount_amount"`
+	CouponCode      *string             `json:"coupon_code"`
+	ShippingFee     float64             `json:"shipping_fee"`
 	Total           float64             `json:"total"`
+	RefundedAmount  float64             `json:"refunded_amount"`
+	PaymentMethod   string              `json:"payment_method"`
 	Status          string              `json:"status"`
 	DeliveryMethod  string              `json:"delivery_method"`
 	CreatedAt       string              `json:"created_at"`
 	ShippingCity    string              `json:"shipping_city"`    // Utile pour l'admin
+	ShippingCommune string              `json:"shipping_commune"` // Utile pour l'admin
 	ShippingAddress string              `json:"shipping_address"` // Utile pour l'admin
+	DeliverySlot    *OrderSlotResponse  `json:"delivery_slot"`    // null si aucun créneau choisi
+	RiderID         *int                `json:"rider_id"`
+	CODCollected    *float64            `json:"cod_collected_amount"` // Montant déclaré par le livreur
+	CODDiscrepancy  *float64            `json:"cod_discrepancy"`      // encaissé - attendu
+	CODFlagged      bool                `json:"cod_flagged"`          // true si l'écart est non nul
 	Items           []OrderItemResponse `json:"items"`
 }
 
+// Créneau réservé, affiché sur le détail de la commande
+type OrderSlotResponse struct {
+	ID        int    `json:"id"`
+	SlotType  string `json:"slot_type"`
+	SlotDate  string `json:"slot_date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// Lu depuis le snapshot order_items : reste fidèle à l'achat même si le produit
+// a été renommé ou supprimé depuis (product_id vaut alors null)
 type OrderItemResponse struct {
-	ProductName string  `json:"product_name"` // Important: correspond au frontend
-	Quantity    int     `json:"quantity"`
-	UnitPrice   float64 `json:"unit_price"` // Important: correspond au frontend
+	ID                int      `json:"id"`
+	ProductID         *int     `json:"product_id"`
+	ProductName       string   `json:"product_name"` // Important: correspond au frontend
+	ProductSKU        string   `json:"product_sku"`
+	ImageURL          string   `json:"image_url"`
+	Quantity          int      `json:"quantity"`
+	UnitPrice         float64  `json:"unit_price"` // Important: correspond au frontend
+	OriginalPrice     float64  `json:"original_price"`
+	PromotionPercent  *float64 `json:"promotion_percent"`
+	CancelledQuantity int      `json:"cancelled_quantity"` // Unités annulées par l'admin
+	RegistryItemID    *int     `json:"registry_item_id"`   // Cadeau acheté depuis une liste de naissance
+	IsPreorder        bool     `json:"is_preorder"`
+	PreorderAllocated *string  `json:"preorder_allocated_at"` // null : précommande en attente de stock
+}
+
+// Colonnes snapshot lues par toutes les requêtes d'articles de commande
+const orderItemColumns = `oi.id, oi.product_id, oi.product_name, COALESCE(oi.product_sku, ''), COALESCE(oi.product_image_url, ''),
+               oi.quantity, oi.price, oi.original_price, oi.promotion_percent, oi.cancelled_quantity,
+               oi.registry_item_id, oi.is_preorder, TO_CHAR(oi.preorder_allocated_at, 'YYYY-MM-DD"T"HH24:MI:SS')`
+
+func scanOrderItem(rows *sql.Rows, dest ...interface{}) (OrderItemResponse, error) {
+	var item OrderItemResponse
+	args := append(dest, &item.ID, &item.ProductID, &item.ProductName, &item.ProductSKU, &item.ImageURL,
+		&item.Quantity, &item.UnitPrice, &item.OriginalPrice, &item.PromotionPercent, &item.CancelledQuantity,
+		&item.RegistryItemID, &item.IsPreorder, &item.PreorderAllocated)
+	err := rows.Scan(args...)
+	return item, err
 }
 
 // CRÉER UNE COMMANDE
@@ -65,56 +119,171 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	var req models.OrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Données invalides", http.StatusBadRequest)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
 		return
 	}
 
+	// Paiement en ligne : la commande attend le paiement (POST /payments/initiate)
+	// avant d'être traitée ; le paiement à la livraison garde le circuit historique
+	paymentMethod := req.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = "cod"
+	}
+	status := "pending"
+	if paymentMethod != "cod" {
+		if _, err := h.Payments.Get(paymentMethod); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Moyen de paiement non disponible"})
+			return
+		}
+		status = "pending_payment"
+	}
+
 	tx, err := h.DB.Begin()
 	if err != nil {
-		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Commande depuis le panier serveur : ses articles remplacent la liste envoyée
+	if req.CartID != "" {
+		items, err := loadCartForCheckout(tx, req.CartID)
+		if err != nil {
+			tx.Rollback()
+			writeOrderError(w, "CreateOrder", err)
+			return
+		}
+		req.Items = items
+	}
+
+	// Montants calculés côté serveur : prix du moment (promotions actives),
+	// code promo évalué sous verrou (FOR UPDATE) pour que ses limites d'utilisation
+	// ne soient pas dépassées par des commandes simultanées, puis frais de port
+	totals, err := computeCheckout(tx, checkoutInput{
+		Items:           req.Items,
+		CouponCode:      req.CouponCode,
+		Email:           req.Email,
+		DeliveryMethod:  req.DeliveryMethod,
+		ShippingCity:    req.ShippingCity,
+		ShippingCommune: req.ShippingCommune,
+	}, true)
+	if err != nil {
+		tx.Rollback()
+		writeOrderError(w, "CreateOrder", err)
 		return
 	}
 
+	// Réservation du créneau dans la même transaction : libérée si la commande échoue
+	if req.DeliverySlotID != nil {
+		if err := bookDeliverySlot(tx, *req.DeliverySlotID, req.DeliveryMethod, totals.Shipping.Zone); err != nil {
+			tx.Rollback()
+			writeOrderError(w, "CreateOrder", err)
+			return
+		}
+	}
+
+	var couponCode *string
+	if totals.Coupon != nil {
+		couponCode = &totals.Coupon.Code
+	}
+	var shippingZoneID *int
+	if totals.Shipping.Zone != nil {
+		shippingZoneID = &totals.Shipping.Zone.ID
+	}
+
 	queryOrder := `
         INSERT INTO orders 
         (customer_firstname, customer_lastname, customer_email, customer_phone, 
          delivery_method, shipping_city, shipping_commune, shipping_address, 
-         order_note, create_account, total_amount)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+         order_note, create_account, subtotal_amount, discount_amount, coupon_code,
+         shipping_fee, shipping_zone_id, delivery_slot_id, total_amount, payment_method, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
         RETURNING id`
 
 	var orderID int
 	err = tx.QueryRow(queryOrder,
 		req.FirstName, req.LastName, req.Email, req.Phone,
 		req.DeliveryMethod, req.ShippingCity, req.ShippingCommune, req.ShippingAddress,
-		req.OrderNote, req.CreateAccount, req.Total,
+		req.OrderNote, req.CreateAccount, totals.Subtotal, totals.Discount, couponCode,
+		totals.Shipping.Fee, shippingZoneID, req.DeliverySlotID, totals.Total, paymentMethod, status,
 	).Scan(&orderID)
 
 	if err != nil {
 		tx.Rollback()
-		http.Error(w, "Erreur enregistrement commande: "+err.Error(), http.StatusInternalServerError)
+		writeOrderError(w, "CreateOrder", err)
 		return
 	}
 
-	queryItem := `INSERT INTO order_items (order_id, product_id, quantity, price) VALUES ($1, $2, $3, $4)`
-	for _, item := range req.Items {
-		_, err := tx.Exec(queryItem, orderID, item.ID, item.Quantity, item.Price)
-		if err != nil {
+	lowStock, err := decrementStock(tx, orderID, totals.Lines)
+	if err != nil {
+		tx.Rollback()
+		writeOrderError(w, "CreateOrder", err)
+		return
+	}
+	if err := recordLowStockAlerts(tx, orderID, lowStock); err != nil {
+		tx.Rollback()
+		writeOrderError(w, "CreateOrder", err)
+		return
+	}
+
+	// Snapshot du produit (nom, SKU, image, prix catalogue, promo) écrit dans la même
+	// transaction : la commande reste lisible même si le produit change ensuite
+	if err := insertOrderItems(tx, orderID, totals.Lines); err != nil {
+		tx.Rollback()
+		writeOrderError(w, "CreateOrder", err)
+		return
+	}
+
+	// Articles offerts depuis une liste de naissance
+	if err := recordRegistryPurchases(tx, totals.Lines); err != nil {
+		tx.Rollback()
+		writeOrderError(w, "CreateOrder", err)
+		return
+	}
+
+	if totals.Coupon != nil {
+		if err := redeemCoupon(tx, totals.Coupon, orderID, req.Email); err != nil {
 			tx.Rollback()
-			http.Error(w, "Erreur enregistrement article", http.StatusInternalServerError)
+			writeOrderError(w, "CreateOrder", err)
+			return
+		}
+	}
+
+	if req.CartID != "" {
+		if err := convertCart(tx, req.CartID, orderID); err != nil {
+			tx.Rollback()
+			writeOrderError(w, "CreateOrder", err)
 			return
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		http.Error(w, "Erreur validation transaction", http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur validation transaction"})
 		return
 	}
+	go notifyLowStock(h.Notifier, orderID, lowStock)
+
+	preorderItems := make([]int, 0)
+	for _, l := range totals.Lines {
+		if l.Preorder {
+			preorderItems = append(preorderItems, l.Product.ID)
+		}
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":  "Commande validée !",
-		"order_id": orderID,
+		"message":         "Commande validée !",
+		"order_id":        orderID,
+		"subtotal":        totals.Subtotal,
+		"discount_amount": totals.Discount,
+		"shipping_fee":    totals.Shipping.Fee,
+		"total":           totals.Total,
+		"payment_method":  paymentMethod,
+		"status":          status,
+		"preorder_items":  preorderItems, // IDs produits commandés en précommande
 	})
 }
 
@@ -164,16 +333,28 @@ func (h *OrderHandler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
 
 	// Info Commande
 	queryOrder := `
-        SELECT id, customer_firstname, customer_lastname, customer_email, customer_phone,
-               total_amount, status, created_at, delivery_method, shipping_city, shipping_address
-        FROM orders WHERE id = $1`
+        SELECT o.id, o.customer_firstname, o.customer_lastname, o.customer_email, o.customer_phone,
+               o.subtotal_amount, o.discount_amount, o.coupon_code, o.shipping_fee,
+               o.total_amount, o.refunded_amount, o.payment_method, o.status, o.created_at, o.delivery_method,
+               COALESCE(o.shipping_city, ''), COALESCE(o.shipping_commune, ''), COALESCE(o.shipping_address, ''),
+               ds.id, ds.slot_type, to_char(ds.slot_date, 'YYYY-MM-DD'),
+               to_char(ds.start_time, 'HH24:MI'), to_char(ds.end_time, 'HH24:MI'),
+               o.rider_id, o.cod_collected_amount, o.cod_discrepancy
+        FROM orders o
+        LEFT JOIN delivery_slots ds ON ds.id = o.delivery_slot_id
+        WHERE o.id = $1`
 
 	var o OrderDetailResponse
 	var first, last string
+	var slotID sql.NullInt64
+	var slotType, slotDate, slotStart, slotEnd sql.NullString
 
 	err = h.DB.QueryRow(queryOrder, id).Scan(
 		&o.ID, &first, &last, &o.CustomerEmail, &o.CustomerPhone,
-		&o.Total, &o.Status, &o.CreatedAt, &o.DeliveryMethod, &o.ShippingCity, &o.ShippingAddress,
+		&o.Subtotal, &o.DiscountAmount, &o.CouponCode, &o.ShippingFee,
+		&o.Total, &o.RefundedAmount, &o.PaymentMethod, &o.Status, &o.CreatedAt, &o.DeliveryMethod, &o.ShippingCity, &o.ShippingCommune, &o.ShippingAddress,
+		&slotID, &slotType, &slotDate, &slotStart, &slotEnd,
+		&o.RiderID, &o.CODCollected, &o.CODDiscrepancy,
 	)
 
 	if err == sql.ErrNoRows {
@@ -184,13 +365,23 @@ func (h *OrderHandler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	o.CustomerName = first + " " + last
+	o.CODFlagged = o.CODDiscrepancy != nil && *o.CODDiscrepancy != 0
+	if slotID.Valid {
+		o.DeliverySlot = &OrderSlotResponse{
+			ID:        int(slotID.Int64),
+			SlotType:  slotType.String,
+			SlotDate:  slotDate.String,
+			StartTime: slotStart.String,
+			EndTime:   slotEnd.String,
+		}
+	}
 
-	// Articles
+	// Articles — lus depuis le snapshot, sans jointure sur products
 	queryItems := `
-        SELECT p.name, oi.quantity, oi.price 
+        SELECT ` + orderItemColumns + `
         FROM order_items oi
-        JOIN products p ON oi.product_id = p.id
-        WHERE oi.order_id = $1`
+        WHERE oi.order_id = $1
+        ORDER BY oi.id ASC`
 
 	rows, err := h.DB.Query(queryItems, id)
 	if err != nil {
@@ -199,16 +390,14 @@ func (h *OrderHandler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var items []OrderItemResponse
+	items := make([]OrderItemResponse, 0)
 	for rows.Next() {
-		var item OrderItemResponse
-		if err := rows.Scan(&item.ProductName, &item.Quantity, &item.UnitPrice); err == nil {
-			items = append(items, item)
+		item, err := scanOrderItem(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan order_items commande id=%d : %v\n", id, err)
+			continue
 		}
-	}
-
-	if items == nil {
-		items = []OrderItemResponse{}
+		items = append(items, item)
 	}
 	o.Items = items
 
@@ -216,6 +405,10 @@ func (h *OrderHandler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
 }
 
 // METTRE À JOUR LE STATUT (ADMIN)
+// Passer une commande en "annulé" rend son stock, son créneau, son code promo et ses
+// articles de liste de naissance (releaseOrderResources) ; la réactiver les reprend
+// (409 si l'un n'est plus disponible). Le remboursement éventuel se fait ensuite avec
+// POST /orders/{id}/refunds (voir refund_due).
 func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -227,17 +420,64 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	query := `UPDATE orders SET status = $1 WHERE id = $2`
-	_, err := h.DB.Exec(query, req.Status, idStr)
-
+	tx, err := h.DB.Begin()
 	if err != nil {
 		http.Error(w, "Erreur BDD", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+
+	// Ancien statut renvoyé par la sous-requête verrouillée, pour l'historique
+	query := `
+        UPDATE orders o SET status = $1
+        FROM (SELECT id, status FROM orders WHERE id = $2 FOR UPDATE) old
+        WHERE o.id = old.id
+        RETURNING o.id, old.status`
+	var orderID int
+	var previous string
+	err = tx.QueryRow(query, req.Status, idStr).Scan(&orderID, &previous)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, "Commande introuvable", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Erreur BDD", http.StatusInternalServerError)
+		return
+	}
+
+	actorID := middleware.UserID(r)
+	if previous != req.Status {
+		switch {
+		case req.Status == "annulé":
+			err = releaseOrderResources(tx, orderID, actorID, "Commande annulée")
+		case previous == "annulé" || previous == "payment_failed":
+			err = reserveOrderResources(tx, orderID, actorID)
+		}
+		if err == nil {
+			err = logOrderHistory(tx, orderID, actorID, "status_changed",
+				fmt.Sprintf("Statut : %s → %s", previous, req.Status),
+				map[string]string{"from": previous, "to": req.Status})
+		}
+	}
+	var refundDue float64
+	if err == nil && req.Status == "annulé" {
+		refundDue, err = orderRefundDue(tx, orderID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeOrderError(w, "UpdateOrderStatus", err)
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	response := map[string]interface{}{
 		"message": "Statut mis à jour",
-	})
+	}
+	if req.Status == "annulé" {
+		response["refund_due"] = refundDue
+	}
+	json.NewEncoder(w).Encode(response)
 }
 
 // MES COMMANDES (CLIENT CONNECTÉ)
@@ -301,7 +541,8 @@ func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var orders []OrderSummary
+	orders := make([]OrderSummary, 0)
+	index := make(map[int]int) // order_id -> position dans orders
 	for rows.Next() {
 		var o OrderSummary
 		var first, last string
@@ -309,11 +550,36 @@ func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		o.CustomerName = first + " " + last
+		o.Items = make([]OrderItemResponse, 0)
+		index[o.ID] = len(orders)
 		orders = append(orders, o)
 	}
 
-	if orders == nil {
-		orders = []OrderSummary{}
+	// Articles de toutes les commandes du client en une seule requête (snapshot)
+	queryItems := `
+        SELECT oi.order_id, ` + orderItemColumns + `
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        WHERE o.customer_email = $1
+        ORDER BY oi.id ASC`
+
+	itemRows, err := h.DB.Query(queryItems, userEmail)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetMyOrders articles : %v\n", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var orderID int
+		item, err := scanOrderItem(itemRows, &orderID)
+		if err != nil {
+			continue
+		}
+		if pos, ok := index[orderID]; ok {
+			orders[pos].Items = append(orders[pos].Items, item)
+		}
 	}
 
 	json.NewEncoder(w).Encode(orders)
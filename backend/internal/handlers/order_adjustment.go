@@ -0,0 +1,598 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+// orderSubPath découpe /orders/{id}/... en ID de commande et reste du chemin
+func orderSubPath(path string) (int, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/orders/"), "/", 2)
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) < 2 {
+		return 0, "", fmt.Errorf("chemin invalide")
+	}
+	return id, parts[1], nil
+}
+
+// orderPaidAmount renvoie ce que le client a effectivement payé :
+// somme des paiements en ligne confirmés, ou montant encaissé par le livreur
+func orderPaidAmount(q queryer, orderID int) (float64, error) {
+	var paid float64
+	err := q.QueryRow(`
+        SELECT CASE WHEN o.payment_method = 'cod' THEN COALESCE(o.cod_collected_amount, 0)
+               ELSE COALESCE((SELECT SUM(p.amount) FROM payments p
+                              WHERE p.order_id = o.id AND p.status IN ('succeeded', 'refunded')), 0)
+               END
+        FROM orders o WHERE o.id = $1`,
+		orderID,
+	).Scan(&paid)
+	return paid, err
+}
+
+// orderRefundDue renvoie ce qui reste à rembourser sur une commande annulée :
+// encaissé, moins les remboursements effectués et en cours
+func orderRefundDue(q queryer, orderID int) (float64, error) {
+	paid, err := orderPaidAmount(q, orderID)
+	if err != nil {
+		return 0, err
+	}
+	var refunded float64
+	if err := q.QueryRow("SELECT refunded_amount FROM orders WHERE id = $1", orderID).Scan(&refunded); err != nil {
+		return 0, err
+	}
+	pending, err := pendingRefundAmount(q, "order_id", orderID)
+	if err != nil {
+		return 0, err
+	}
+	return max(roundAmount(paid-refunded-pending), 0), nil
+}
+
+// recordRefund enregistre un remboursement effectué (espèces), le cumule sur la commande
+// et le journalise
+func recordRefund(tx *sql.Tx, orderID int, paymentID *int, amount float64, method, reason string, actorID int) error {
+	var createdBy *int
+	if actorID > 0 {
+		createdBy = &actorID
+	}
+	_, err := tx.Exec(
+		"INSERT INTO refunds (order_id, payment_id, amount, method, reason, created_by) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)",
+		orderID, paymentID, amount, method, strings.TrimSpace(reason), createdBy,
+	)
+	if err != nil {
+		return err
+	}
+	return applyRefund(tx, orderID, paymentID, amount, method, reason, actorID)
+}
+
+// applyRefund cumule un remboursement effectué sur la commande et le journalise
+func applyRefund(tx *sql.Tx, orderID int, paymentID *int, amount float64, method, reason string, actorID int) error {
+	if _, err := tx.Exec("UPDATE orders SET refunded_amount = refunded_amount + $1 WHERE id = $2", amount, orderID); err != nil {
+		return err
+	}
+	return logOrderHistory(tx, orderID, actorID, "refund", fmt.Sprintf("Remboursement de %.0f FCFA", amount), map[string]interface{}{
+		"amount":     amount,
+		"method":     method,
+		"payment_id": paymentID,
+		"reason":     strings.TrimSpace(reason),
+	})
+}
+
+// beginProviderRefund enregistre un remboursement en ligne 'pending', avant l'appel au
+// prestataire (refundThroughProvider). Son montant est aussitôt déduit du remboursable.
+func beginProviderRefund(tx *sql.Tx, orderID, paymentID int, amount float64, reason string, actorID int) (int, error) {
+	var createdBy *int
+	if actorID > 0 {
+		createdBy = &actorID
+	}
+	var id int
+	err := tx.QueryRow(`
+        INSERT INTO refunds (order_id, payment_id, amount, method, reason, created_by, status)
+        VALUES ($1, $2, $3, 'provider', NULLIF($4, ''), $5, 'pending') RETURNING id`,
+		orderID, paymentID, amount, strings.TrimSpace(reason), createdBy,
+	).Scan(&id)
+	return id, err
+}
+
+// pendingRefundAmount renvoie le montant des remboursements en ligne en cours,
+// pour une commande (column = "order_id") ou un paiement ("payment_id")
+func pendingRefundAmount(q queryer, column string, id int) (float64, error) {
+	var amount float64
+	err := q.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE "+column+" = $1 AND status = 'pending'", id,
+	).Scan(&amount)
+	return amount, err
+}
+
+// recomputeOrderTotals recalcule les montants après une annulation de ligne.
+// La remise du code promo est réduite au prorata du nouveau sous-total ; les frais
+// de port restent dus tant qu'il reste un article. Une commande vidée passe en "annulé"
+// et rend son créneau de livraison et son code promo (releaseOrderResources).
+func recomputeOrderTotals(tx *sql.Tx, orderID, actorID int, reason string) (map[string]interface{}, error) {
+	var oldSubtotal, discount, shippingFee float64
+	err := tx.QueryRow(
+		"SELECT subtotal_amount, discount_amount, shipping_fee FROM orders WHERE id = $1", orderID,
+	).Scan(&oldSubtotal, &discount, &shippingFee)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtotal float64
+	err = tx.QueryRow(
+		"SELECT COALESCE(SUM(price * (quantity - cancelled_quantity)), 0) FROM order_items WHERE order_id = $1", orderID,
+	).Scan(&subtotal)
+	if err != nil {
+		return nil, err
+	}
+	subtotal = roundAmount(subtotal)
+
+	if oldSubtotal > 0 {
+		discount = roundAmount(discount * subtotal / oldSubtotal)
+	}
+	emptied := subtotal == 0
+	if emptied {
+		discount, shippingFee = 0, 0
+	}
+	total := roundAmount(subtotal - discount + shippingFee)
+
+	_, err = tx.Exec(`
+        UPDATE orders
+        SET subtotal_amount = $1, discount_amount = $2, shipping_fee = $3, total_amount = $4,
+            status = CASE WHEN $5 THEN 'annulé' ELSE status END
+        WHERE id = $6`,
+		subtotal, discount, shippingFee, total, emptied, orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if emptied {
+		if reason == "" {
+			reason = "Toutes les lignes annulées"
+		}
+		if err := releaseOrderResources(tx, orderID, actorID, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"subtotal":        subtotal,
+		"discount_amount": discount,
+		"shipping_fee":    shippingFee,
+		"total":           total,
+		"cancelled":       emptied,
+	}, nil
+}
+
+// POST /orders/{id}/items/{itemId}/cancel — Annuler tout ou partie d'une ligne [ADMIN]
+// Body optionnel : { "quantity": 1, "reason": "...", "restock": true }
+// Le remboursement éventuel se fait ensuite avec POST /orders/{id}/refunds (voir refund_due).
+func (h *OrderHandler) CancelOrderItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, rest, err := orderSubPath(r.URL.Path)
+	var itemID int
+	if err == nil {
+		itemID, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rest, "items/"), "/cancel"))
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.CancelItemRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+			return
+		}
+	}
+	restock := req.Restock == nil || *req.Restock
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Verrou sur la commande : annulations et remboursements concurrents sont sérialisés.
+	// released : stock et liste de naissance déjà rendus (paiement échoué ou expiré)
+	var status string
+	var released bool
+	err = tx.QueryRow(
+		"SELECT status, resources_released_at IS NOT NULL FROM orders WHERE id = $1 FOR UPDATE", orderID,
+	).Scan(&status, &released)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Commande introuvable"})
+		return
+	} else if err != nil {
+		writeOrderError(w, "CancelOrderItem", err)
+		return
+	}
+	if status == "annulé" || status == "refunded" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cette commande est déjà annulée ou remboursée"})
+		return
+	}
+
+	var productID, registryItemID *int
+	var productName string
+	var quantity, cancelled int
+	var pendingPreorder bool
+	err = tx.QueryRow(`
+        SELECT product_id, product_name, quantity, cancelled_quantity, registry_item_id,
+               is_preorder AND preorder_allocated_at IS NULL
+        FROM order_items WHERE id = $1 AND order_id = $2 FOR UPDATE`,
+		itemID, orderID,
+	).Scan(&productID, &productName, &quantity, &cancelled, &registryItemID, &pendingPreorder)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Article introuvable dans cette commande"})
+		return
+	} else if err != nil {
+		writeOrderError(w, "CancelOrderItem", err)
+		return
+	}
+
+	remaining := quantity - cancelled
+	qty := remaining
+	if req.Quantity != nil {
+		qty = *req.Quantity
+	}
+	if qty <= 0 || qty > remaining {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Quantité invalide : %d unité(s) annulable(s)", remaining)})
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE order_items SET cancelled_quantity = cancelled_quantity + $1 WHERE id = $2", qty, itemID); err != nil {
+		writeOrderError(w, "CancelOrderItem", err)
+		return
+	}
+	// Précommande pas encore servie : aucune unité n'a été prise sur le stock.
+	// Réservations déjà rendues : les unités sont déjà revenues en stock
+	restocked := restock && productID != nil && !pendingPreorder && !released
+	if restocked {
+		if err := restoreStock(tx, itemID, *productID, qty, orderID, middleware.UserID(r), strings.TrimSpace(req.Reason)); err != nil {
+			writeOrderError(w, "CancelOrderItem", err)
+			return
+		}
+	}
+
+	// L'article redevient disponible sur la liste de naissance
+	if registryItemID != nil && !released {
+		if err := releaseRegistryPurchase(tx, *registryItemID, qty); err != nil {
+			writeOrderError(w, "CancelOrderItem", err)
+			return
+		}
+	}
+
+	totals, err := recomputeOrderTotals(tx, orderID, middleware.UserID(r), strings.TrimSpace(req.Reason))
+	if err == nil {
+		err = logOrderHistory(tx, orderID, middleware.UserID(r), "item_cancelled",
+			fmt.Sprintf("Annulation de %d × %s", qty, productName),
+			map[string]interface{}{
+				"item_id":   itemID,
+				"quantity":  qty,
+				"restocked": restocked,
+				"reason":    strings.TrimSpace(req.Reason),
+				"totals":    totals,
+			})
+	}
+	var paid, refunded float64
+	if err == nil {
+		paid, err = orderPaidAmount(tx, orderID)
+	}
+	if err == nil {
+		err = tx.QueryRow("SELECT refunded_amount FROM orders WHERE id = $1", orderID).Scan(&refunded)
+	}
+	var pendingRefunds float64
+	if err == nil {
+		pendingRefunds, err = pendingRefundAmount(tx, "order_id", orderID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeOrderError(w, "CancelOrderItem", err)
+		return
+	}
+
+	// Montant payé en trop après l'annulation, à rembourser par l'admin
+	refundDue := roundAmount(paid - refunded - pendingRefunds - totals["total"].(float64))
+	if refundDue < 0 {
+		refundDue = 0
+	}
+	totals["message"] = "Article annulé"
+	totals["refund_due"] = refundDue
+	json.NewEncoder(w).Encode(totals)
+}
+
+// POST /orders/{id}/refunds — Rembourser une commande [ADMIN]
+// Paiement en ligne : remboursé chez le prestataire. Paiement à la livraison : remboursement
+// en espèces simplement enregistré. Le montant est plafonné à ce qui a été payé.
+func (h *OrderHandler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, _, err := orderSubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.OrderRefundRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+			return
+		}
+	}
+	actorID := middleware.UserID(r)
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	var paymentMethod string
+	var refunded float64
+	err = tx.QueryRow("SELECT payment_method, refunded_amount FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&paymentMethod, &refunded)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Commande introuvable"})
+		return
+	} else if err != nil {
+		writeOrderError(w, "RefundOrder", err)
+		return
+	}
+	paid, err := orderPaidAmount(tx, orderID)
+	var pending float64
+	if err == nil {
+		pending, err = pendingRefundAmount(tx, "order_id", orderID)
+	}
+	if err != nil {
+		writeOrderError(w, "RefundOrder", err)
+		return
+	}
+
+	refundable := roundAmount(paid - refunded - pending)
+	if refundable <= 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Aucun montant encaissé à rembourser"})
+		return
+	}
+	amount := refundable
+	if req.Amount != nil {
+		amount = roundAmount(*req.Amount)
+	}
+	if amount <= 0 || amount > refundable {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Montant invalide : %.0f FCFA remboursables au maximum", refundable)})
+		return
+	}
+
+	// Paiement à la livraison : remboursement en espèces, enregistré en une fois
+	if paymentMethod == "cod" {
+		err = recordRefund(tx, orderID, nil, amount, "cash", req.Reason, actorID)
+		if err == nil && amount == refundable {
+			_, err = tx.Exec("UPDATE orders SET status = 'refunded' WHERE id = $1 AND status <> 'annulé'", orderID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeOrderError(w, "RefundOrder", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":         "Remboursement effectué",
+			"amount":          amount,
+			"refunded_amount": roundAmount(refunded + amount),
+			"fully_refunded":  amount == refundable,
+		})
+		return
+	}
+
+	// Paiement en ligne : remboursements enregistrés 'pending' et validés avant
+	// l'appel aux prestataires, pour ne pas garder la commande verrouillée pendant l'appel
+	planned, err := planOnlineRefunds(tx, orderID, amount, req.Reason, actorID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeOrderError(w, "RefundOrder", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), paymentProviderTimeout)
+	defer cancel()
+	pendingAmount := 0.0
+	for i, pr := range planned {
+		err := refundThroughProvider(ctx, h.DB, h.Payments, pr.Payment, pr.RefundID, pr.Amount)
+		if err == errRefundPending {
+			// Finalisé par le webhook ou la vérification du paiement
+			pendingAmount += pr.Amount
+			continue
+		}
+		if err != nil {
+			// Les parts suivantes ne sont pas demandées : leur montant redevient remboursable
+			for _, next := range planned[i+1:] {
+				if err := failProviderRefund(h.DB, next.RefundID, "Non demandé : remboursement précédent en échec"); err != nil {
+					fmt.Printf("Erreur BDD RefundOrder refund=%d : %v\n", next.RefundID, err)
+				}
+			}
+			writeOrderError(w, "RefundOrder", err)
+			return
+		}
+	}
+	if pendingAmount > 0 {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":         "Remboursement en cours chez le prestataire : il sera comptabilisé à sa confirmation",
+			"amount":          amount,
+			"pending_amount":  roundAmount(pendingAmount),
+			"refunded_amount": roundAmount(refunded + amount - pendingAmount),
+			"fully_refunded":  false,
+		})
+		return
+	}
+	if amount == refundable {
+		if _, err := h.DB.Exec("UPDATE orders SET status = 'refunded' WHERE id = $1 AND status <> 'annulé'", orderID); err != nil {
+			fmt.Printf("Erreur BDD RefundOrder order=%d : %v\n", orderID, err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Remboursement effectué",
+		"amount":          amount,
+		"refunded_amount": roundAmount(refunded + amount),
+		"fully_refunded":  amount == refundable,
+	})
+}
+
+// plannedRefund est la part d'un remboursement de commande demandée sur un paiement
+type plannedRefund struct {
+	Payment  models.Payment
+	RefundID int
+	Amount   float64
+}
+
+// planOnlineRefunds répartit le remboursement sur les paiements confirmés de la commande,
+// du plus ancien au plus récent, et enregistre chaque part 'pending' (beginProviderRefund)
+func planOnlineRefunds(tx *sql.Tx, orderID int, amount float64, reason string, actorID int) ([]plannedRefund, error) {
+	rows, err := tx.Query(
+		"SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 AND status = 'succeeded' AND provider_reference IS NOT NULL ORDER BY id ASC FOR UPDATE",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.Payment, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	rows.Close()
+
+	planned := make([]plannedRefund, 0)
+	left := amount
+	for _, p := range list {
+		if left <= 0 {
+			break
+		}
+		pending, err := pendingRefundAmount(tx, "payment_id", p.ID)
+		if err != nil {
+			return nil, err
+		}
+		part := roundAmount(p.Amount - p.RefundedAmount - pending)
+		if part > left {
+			part = left
+		}
+		if part <= 0 {
+			continue
+		}
+		refundID, err := beginProviderRefund(tx, orderID, p.ID, part, reason, actorID)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, plannedRefund{Payment: p, RefundID: refundID, Amount: part})
+		left = roundAmount(left - part)
+	}
+	if left > 0 {
+		return nil, newOrderError(http.StatusConflict, "Paiements insuffisants pour rembourser %.0f FCFA", amount)
+	}
+	return planned, nil
+}
+
+// GET /orders/{id}/refunds — Remboursements d'une commande [ADMIN]
+func (h *OrderHandler) GetOrderRefunds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, _, err := orderSubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	rows, err := h.DB.Query(
+		"SELECT id, order_id, payment_id, amount, method, reason, status, failure_reason, created_by, created_at FROM refunds WHERE order_id = $1 ORDER BY created_at ASC",
+		orderID,
+	)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetOrderRefunds : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des remboursements"})
+		return
+	}
+	defer rows.Close()
+
+	refunds := make([]models.Refund, 0)
+	for rows.Next() {
+		var rf models.Refund
+		if err := rows.Scan(&rf.ID, &rf.OrderID, &rf.PaymentID, &rf.Amount, &rf.Method, &rf.Reason, &rf.Status, &rf.FailureReason, &rf.CreatedBy, &rf.CreatedAt); err != nil {
+			fmt.Printf("Erreur Scan refunds : %v\n", err)
+			continue
+		}
+		refunds = append(refunds, rf)
+	}
+
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// GET /orders/{id}/history — Journal des actions sur une commande [ADMIN]
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, _, err := orderSubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	rows, err := h.DB.Query(
+		"SELECT id, order_id, action, message, COALESCE(details, '{}'), actor_id, created_at FROM order_history WHERE order_id = $1 ORDER BY created_at ASC, id ASC",
+		orderID,
+	)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetOrderHistory : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération de l'historique"})
+		return
+	}
+	defer rows.Close()
+
+	entries := make([]models.OrderHistoryEntry, 0)
+	for rows.Next() {
+		var e models.OrderHistoryEntry
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.Action, &e.Message, &details, &e.ActorID, &e.CreatedAt); err != nil {
+			fmt.Printf("Erreur Scan order_history : %v\n", err)
+			continue
+		}
+		e.Details = details
+		entries = append(entries, e)
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// execer est satisfait par *sql.DB et *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// logOrderHistory ajoute une entrée au journal de la commande.
+// À appeler dans la transaction de l'action journalisée quand il y en a une.
+func logOrderHistory(db execer, orderID, actorID int, action, message string, details interface{}) error {
+	payload := []byte("{}")
+	if details != nil {
+		var err error
+		if payload, err = json.Marshal(details); err != nil {
+			return err
+		}
+	}
+	var actor *int
+	if actorID > 0 {
+		actor = &actorID
+	}
+	_, err := db.Exec(
+		"INSERT INTO order_history (order_id, action, message, details, actor_id) VALUES ($1, $2, $3, $4, $5)",
+		orderID, action, message, string(payload), actor,
+	)
+	return err
+}
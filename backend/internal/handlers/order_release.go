@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+)
+
+// reservedLine est la part encore active d'une ligne de commande (hors unités annulées)
+type reservedLine struct {
+	ItemID         int
+	ProductID      *int
+	ProductName    string
+	Quantity       int
+	RegistryItemID *int
+	// Précommande pas encore servie : aucune unité prise sur le stock
+	PendingPreorder bool
+}
+
+// loadReservedLines lit les lignes actives d'une commande
+func loadReservedLines(tx *sql.Tx, orderID int) ([]reservedLine, error) {
+	rows, err := tx.Query(`
+        SELECT id, product_id, product_name, quantity - cancelled_quantity, registry_item_id,
+               is_preorder AND preorder_allocated_at IS NULL
+        FROM order_items WHERE order_id = $1 AND quantity > cancelled_quantity
+        ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]reservedLine, 0)
+	for rows.Next() {
+		var l reservedLine
+		if err := rows.Scan(&l.ItemID, &l.ProductID, &l.ProductName, &l.Quantity, &l.RegistryItemID, &l.PendingPreorder); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// releaseOrderResources rend tout ce que la commande avait réservé à sa création : stock
+// (restoreStock, comme une annulation), place sur le créneau de livraison, utilisation du
+// code promo et articles de liste de naissance. Les lignes de la commande restent intactes
+// pour qu'une nouvelle tentative de paiement puisse tout reprendre (reserveOrderResources).
+// Sans effet si les réservations ont déjà été rendues.
+func releaseOrderResources(tx *sql.Tx, orderID, actorID int, reason string) error {
+	var slotID *int
+	var released bool
+	err := tx.QueryRow(
+		"SELECT delivery_slot_id, resources_released_at IS NOT NULL FROM orders WHERE id = $1 FOR UPDATE", orderID,
+	).Scan(&slotID, &released)
+	if err != nil || released {
+		return err
+	}
+
+	lines, err := loadReservedLines(tx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.ProductID != nil && !l.PendingPreorder {
+			if err := restoreStock(tx, l.ItemID, *l.ProductID, l.Quantity, orderID, actorID, reason); err != nil {
+				return err
+			}
+		}
+		if l.RegistryItemID != nil {
+			if err := releaseRegistryPurchase(tx, *l.RegistryItemID, l.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+
+	if slotID != nil {
+		if _, err := tx.Exec("UPDATE delivery_slots SET booked_count = booked_count - 1 WHERE id = $1 AND booked_count > 0", *slotID); err != nil {
+			return err
+		}
+	}
+
+	var couponID int
+	err = tx.QueryRow("DELETE FROM coupon_redemptions WHERE order_id = $1 RETURNING coupon_id", orderID).Scan(&couponID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE coupons SET used_count = GREATEST(used_count - 1, 0) WHERE id = $1", couponID)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET resources_released_at = NOW() WHERE id = $1", orderID); err != nil {
+		return err
+	}
+	return logOrderHistory(tx, orderID, actorID, "resources_released",
+		"Stock, créneau et code promo libérés", map[string]interface{}{"reason": strings.TrimSpace(reason)})
+}
+
+// reserveOrderResources reprend les réservations rendues par releaseOrderResources,
+// avec les mêmes garde-fous qu'à la création de la commande (stock, places du créneau,
+// limites du code promo, quantités souhaitées sur la liste de naissance).
+// Renvoie une orderError (409) si l'une d'elles n'est plus disponible : l'appelant
+// annule alors la transaction. Sans effet si rien n'a été rendu.
+func reserveOrderResources(tx *sql.Tx, orderID, actorID int) error {
+	var slotID *int
+	var couponCode *string
+	var email string
+	var discount float64
+	var released bool
+	err := tx.QueryRow(`
+        SELECT delivery_slot_id, coupon_code, customer_email, discount_amount, resources_released_at IS NOT NULL
+        FROM orders WHERE id = $1 FOR UPDATE`, orderID,
+	).Scan(&slotID, &couponCode, &email, &discount, &released)
+	if err != nil || !released {
+		return err
+	}
+
+	lines, err := loadReservedLines(tx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.ProductID != nil && !l.PendingPreorder {
+			if err := takeStock(tx, l, orderID); err != nil {
+				return err
+			}
+		}
+		if l.RegistryItemID != nil {
+			res, err := tx.Exec(`
+                UPDATE registry_items SET purchased_quantity = purchased_quantity + $1
+                WHERE id = $2 AND purchased_quantity + $1 <= desired_quantity`,
+				l.Quantity, *l.RegistryItemID)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return newOrderError(http.StatusConflict, "%s a déjà été offert sur la liste de naissance", l.ProductName)
+			}
+		}
+	}
+
+	if slotID != nil {
+		res, err := tx.Exec(`
+            UPDATE delivery_slots SET booked_count = booked_count + 1
+            WHERE id = $1 AND is_active AND booked_count < capacity AND (slot_date + start_time) > NOW()`,
+			*slotID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return newOrderError(http.StatusConflict, "Le créneau de livraison choisi est complet ou déjà passé")
+		}
+	}
+
+	if couponCode != nil {
+		var couponID int
+		var maxUses, maxPerCustomer *int
+		var usedCount, customerUses int
+		err := tx.QueryRow(`
+            SELECT id, max_uses, max_uses_per_customer, used_count,
+                   (SELECT COUNT(*) FROM coupon_redemptions r WHERE r.coupon_id = c.id AND LOWER(r.customer_email) = LOWER($2))
+            FROM coupons c WHERE UPPER(code) = UPPER($1) FOR UPDATE`,
+			*couponCode, strings.TrimSpace(email),
+		).Scan(&couponID, &maxUses, &maxPerCustomer, &usedCount, &customerUses)
+		if err == sql.ErrNoRows ||
+			(err == nil && ((maxUses != nil && usedCount >= *maxUses) || (maxPerCustomer != nil && customerUses >= *maxPerCustomer))) {
+			return newOrderError(http.StatusConflict, "Le code promo %s n'est plus disponible", *couponCode)
+		} else if err != nil {
+			return err
+		}
+		if err := redeemCoupon(tx, &appliedCoupon{ID: couponID, Discount: discount}, orderID, email); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET resources_released_at = NULL WHERE id = $1", orderID); err != nil {
+		return err
+	}
+	return logOrderHistory(tx, orderID, actorID, "resources_reserved", "Stock, créneau et code promo de nouveau réservés", nil)
+}
+
+// takeStock reprend sur le stock les articles d'une ligne (les composants vendus pour un
+// coffret, voir order_item_components) : symétrique de restoreStock
+func takeStock(tx *sql.Tx, l reservedLine, orderID int) error {
+	components, err := loadItemComponents(tx, l.ItemID)
+	if err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		_, _, err := adjustStock(tx, *l.ProductID, -l.Quantity, stockMovement{Type: "sale", OrderID: orderID})
+		if err == errInsufficientStock {
+			return newOrderError(http.StatusConflict, "Stock insuffisant pour %s", l.ProductName)
+		}
+		return err
+	}
+	for _, c := range components {
+		if c.ProductID == nil {
+			return newOrderError(http.StatusConflict, "Le coffret %s n'est plus disponible (composant %s supprimé)", l.ProductName, c.Name)
+		}
+		_, _, err := adjustStock(tx, *c.ProductID, -c.Quantity*l.Quantity, stockMovement{
+			Type: "sale", OrderID: orderID, Reason: "Coffret " + l.ProductName,
+		})
+		if err == errInsufficientStock {
+			return newOrderError(http.StatusConflict, "Stock insuffisant pour %s (composant %s)", l.ProductName, c.Name)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,731 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/payments"
+)
+
+type PaymentHandler struct {
+	DB       *sql.DB
+	Payments *payments.Registry
+}
+
+// Délai maximum accordé au prestataire pour répondre
+const paymentProviderTimeout = 30 * time.Second
+
+const paymentColumns = `id, order_id, provider, merchant_reference, provider_reference, amount, currency, phone,
+               status, refunded_amount, checkout_url, failure_reason, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...interface{}) error }) (models.Payment, error) {
+	var p models.Payment
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.MerchantReference, &p.ProviderReference, &p.Amount,
+		&p.Currency, &p.Phone, &p.Status, &p.RefundedAmount, &p.CheckoutURL, &p.FailureReason, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// setPaymentStatus applique au paiement et à sa commande un statut renvoyé par le prestataire.
+// Doit être appelée dans une transaction. Renvoie false si le statut était déjà enregistré
+// ou si la transition n'a pas de sens (ex : "pending" après "succeeded").
+func setPaymentStatus(tx *sql.Tx, paymentID int, status, reason string) (bool, error) {
+	var current string
+	var orderID int
+	err := tx.QueryRow("SELECT status, order_id FROM payments WHERE id = $1 FOR UPDATE", paymentID).Scan(&current, &orderID)
+	if err != nil {
+		return false, err
+	}
+
+	if !paymentTransitionAllowed(current, status) {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+        UPDATE payments
+        SET status = $1,
+            failure_reason = CASE WHEN $1 = 'failed' THEN NULLIF($2, '') ELSE failure_reason END,
+            updated_at = NOW()
+        WHERE id = $3`,
+		status, reason, paymentID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	switch status {
+	case payments.StatusSucceeded:
+		err = confirmOrderPayment(tx, orderID, paymentID)
+	case payments.StatusFailed:
+		err = failOrderPayment(tx, orderID, reason)
+	case payments.StatusRefunded:
+		_, err = tx.Exec(`UPDATE orders SET status = 'refunded' WHERE id = $1 AND status <> 'annulé'`, orderID)
+	}
+	return err == nil, err
+}
+
+// paymentTransitionAllowed indique si un paiement peut passer du statut current à status
+func paymentTransitionAllowed(current, status string) bool {
+	switch {
+	case current == payments.StatusPending && (status == payments.StatusSucceeded || status == payments.StatusFailed):
+	case current == payments.StatusFailed && status == payments.StatusSucceeded:
+		// Confirmation tardive d'un paiement d'abord signalé en échec
+	case current == payments.StatusSucceeded && status == payments.StatusRefunded:
+	default:
+		return false
+	}
+	return true
+}
+
+// failOrderPayment passe la commande en "payment_failed" et rend ses réservations
+// (releaseOrderResources). La commande reste en attente tant qu'une autre tentative
+// est en cours ou a réussi.
+func failOrderPayment(tx *sql.Tx, orderID int, reason string) error {
+	res, err := tx.Exec(`
+        UPDATE orders SET status = 'payment_failed'
+        WHERE id = $1 AND status = 'pending_payment'
+          AND NOT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND status IN ('pending', 'succeeded'))`,
+		orderID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if strings.TrimSpace(reason) == "" {
+		reason = "Paiement en ligne échoué"
+	}
+	return releaseOrderResources(tx, orderID, 0, reason)
+}
+
+// confirmOrderPayment passe la commande en "paid" et sert ses précommandes si le stock
+// est déjà arrivé. Après un échec ou une expiration, les réservations rendues sont
+// reprises ; si l'une n'est plus disponible (stock vendu entre-temps...), le paiement
+// reste acquis : la commande est payée sans réservation et l'incident est inscrit à son
+// historique pour que l'équipe rembourse ou complète.
+// Un paiement confirmé sur une commande qui n'attend plus de paiement (déjà payée,
+// annulée ou remboursée) est inscrit à l'historique comme montant à rembourser (refund_due).
+func confirmOrderPayment(tx *sql.Tx, orderID, paymentID int) error {
+	res, err := tx.Exec(`UPDATE orders SET status = 'paid' WHERE id = $1 AND status IN ('pending_payment', 'payment_failed')`, orderID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var status string
+		var amount float64
+		err := tx.QueryRow(`
+            SELECT o.status, p.amount FROM orders o JOIN payments p ON p.order_id = o.id
+            WHERE o.id = $1 AND p.id = $2`, orderID, paymentID,
+		).Scan(&status, &amount)
+		if err != nil {
+			return err
+		}
+		return logOrderHistory(tx, orderID, 0, "refund_due",
+			fmt.Sprintf("Paiement en ligne confirmé sur une commande %s : %.0f FCFA à rembourser", status, amount),
+			map[string]interface{}{"payment_id": paymentID, "amount": amount, "order_status": status})
+	}
+
+	// Point de sauvegarde : une reprise partielle est annulée sans perdre le paiement
+	if _, err := tx.Exec("SAVEPOINT reserve_order"); err != nil {
+		return err
+	}
+	err = reserveOrderResources(tx, orderID, 0)
+	var oe *orderError
+	if errors.As(err, &oe) {
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT reserve_order"); err != nil {
+			return err
+		}
+		err = logOrderHistory(tx, orderID, 0, "reservation_failed",
+			"Paiement confirmé après expiration : "+oe.message, map[string]interface{}{"reason": oe.message})
+	}
+	if err != nil {
+		return err
+	}
+	// Précommandes ignorées tant que le paiement était en attente
+	return allocateOrderPreorders(tx, orderID)
+}
+
+// ExpireOrderPayment clôt l'attente de paiement d'une commande restée en "pending_payment" :
+// ses tentatives en attente passent en échec et ses réservations sont rendues
+// (job PENDING_PAYMENT). Un paiement confirmé ensuite par le prestataire est toujours
+// accepté (voir confirmOrderPayment). Doit être appelée dans une transaction.
+func ExpireOrderPayment(tx *sql.Tx, orderID int) error {
+	const reason = "Délai de paiement expiré"
+	_, err := tx.Exec(`
+        UPDATE payments SET status = 'failed', failure_reason = $1, updated_at = NOW()
+        WHERE order_id = $2 AND status = 'pending'`,
+		reason, orderID,
+	)
+	if err != nil {
+		return err
+	}
+	return failOrderPayment(tx, orderID, reason)
+}
+
+// applyPaymentStatus exécute setPaymentStatus dans sa propre transaction
+func applyPaymentStatus(db *sql.DB, paymentID int, status, reason string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	changed, err := setPaymentStatus(tx, paymentID, status, reason)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return changed, tx.Commit()
+}
+
+// refundThroughProvider demande au prestataire le remboursement refundID, déjà enregistré
+// 'pending' et validé (beginProviderRefund) : aucune transaction n'est ouverte pendant l'appel.
+// Le résultat est ensuite appliqué dans une seconde transaction (completeProviderRefund ou
+// failProviderRefund). Sans réponse du prestataire, la ligne reste 'pending' pour être
+// rapprochée manuellement : on ne sait pas si le remboursement a eu lieu.
+// Remboursement accepté mais encore en cours chez le prestataire : la ligne reste 'pending'
+// et errRefundPending est renvoyée ; le webhook "refunded" ou la vérification du paiement
+// le finalise (completePendingRefunds).
+func refundThroughProvider(ctx context.Context, db *sql.DB, registry *payments.Registry, p models.Payment, refundID int, amount float64) error {
+	provider, err := registry.Get(p.Provider)
+	if err != nil {
+		if err := failProviderRefund(db, refundID, "Moyen de paiement non disponible"); err != nil {
+			fmt.Printf("Erreur BDD remboursement refund=%d : %v\n", refundID, err)
+		}
+		return newOrderError(http.StatusServiceUnavailable, "Moyen de paiement non disponible")
+	}
+	res, err := provider.Refund(ctx, *p.ProviderReference, amount)
+	if err != nil {
+		fmt.Printf("Erreur prestataire %s remboursement payment=%d refund=%d : %v\n", p.Provider, p.ID, refundID, err)
+		return newOrderError(http.StatusBadGateway, "Le prestataire n'a pas répondu : remboursement en attente de vérification")
+	}
+	if res.Status == payments.StatusPending {
+		return errRefundPending
+	}
+	if res.Status != payments.StatusSucceeded {
+		fmt.Printf("Erreur prestataire %s remboursement payment=%d refund=%d : refusé (%s)\n", p.Provider, p.ID, refundID, res.Message)
+		if err := failProviderRefund(db, refundID, res.Message); err != nil {
+			fmt.Printf("Erreur BDD remboursement refund=%d : %v\n", refundID, err)
+		}
+		return newOrderError(http.StatusBadGateway, "Le remboursement a été refusé par le prestataire")
+	}
+
+	// Le prestataire a remboursé : une erreur ici laisse la ligne 'pending', à rapprocher
+	if err := completeProviderRefund(db, refundID); err != nil {
+		fmt.Printf("Erreur BDD remboursement refund=%d (remboursé %.2f chez %s) : %v\n", refundID, amount, p.Provider, err)
+		return newOrderError(http.StatusInternalServerError, "Remboursement effectué mais non enregistré, contactez le support technique")
+	}
+	return nil
+}
+
+// errRefundPending : remboursement accepté par le prestataire mais pas encore effectué
+var errRefundPending = errors.New("remboursement en cours chez le prestataire")
+
+// completeProviderRefund finalise un remboursement accepté par le prestataire : cumul sur
+// le paiement (passé en "refunded" s'il est intégralement remboursé) et sur la commande
+func completeProviderRefund(db *sql.DB, refundID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := finishProviderRefund(tx, refundID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// completePendingRefunds finalise les remboursements 'pending' d'un paiement, quand le
+// prestataire le signale remboursé (webhook ou vérification). Renvoie leur nombre.
+func completePendingRefunds(tx *sql.Tx, paymentID int) (int, error) {
+	rows, err := tx.Query("SELECT id FROM refunds WHERE payment_id = $1 AND status = 'pending' ORDER BY id", paymentID)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := finishProviderRefund(tx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// finishProviderRefund applique un remboursement 'pending' dans la transaction tx
+func finishProviderRefund(tx *sql.Tx, refundID int) error {
+	var orderID, paymentID, actorID int
+	var amount float64
+	var method, reason string
+	err := tx.QueryRow(`
+        SELECT order_id, payment_id, amount, method, COALESCE(reason, ''), COALESCE(created_by, 0)
+        FROM refunds WHERE id = $1 AND status = 'pending' FOR UPDATE`, refundID,
+	).Scan(&orderID, &paymentID, &amount, &method, &reason, &actorID)
+	if err != nil {
+		return err
+	}
+	// Même ordre de verrouillage que les demandes de remboursement : commande puis paiement
+	if _, err := tx.Exec("SELECT id FROM orders WHERE id = $1 FOR UPDATE", orderID); err != nil {
+		return err
+	}
+
+	var total, refunded float64
+	err = tx.QueryRow(`
+        UPDATE payments SET refunded_amount = refunded_amount + $1, updated_at = NOW()
+        WHERE id = $2 RETURNING amount, refunded_amount`, amount, paymentID,
+	).Scan(&total, &refunded)
+	if err != nil {
+		return err
+	}
+	if refunded >= total {
+		if _, err := setPaymentStatus(tx, paymentID, payments.StatusRefunded, ""); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE refunds SET status = 'completed' WHERE id = $1", refundID); err != nil {
+		return err
+	}
+	return applyRefund(tx, orderID, &paymentID, amount, method, reason, actorID)
+}
+
+// failProviderRefund enregistre le refus du prestataire : le montant redevient remboursable
+func failProviderRefund(db *sql.DB, refundID int, reason string) error {
+	_, err := db.Exec(
+		"UPDATE refunds SET status = 'failed', failure_reason = NULLIF($1, '') WHERE id = $2 AND status = 'pending'",
+		strings.TrimSpace(reason), refundID,
+	)
+	return err
+}
+
+// paymentIDFromPath extrait l'ID de /payments/{id}/{action}
+func paymentIDFromPath(path, action string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/payments/"), "/"+action))
+}
+
+// GET /payments/providers — Moyens de paiement disponibles au checkout (public)
+func (h *PaymentHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers": append([]string{"cod"}, h.Payments.Names()...),
+	})
+}
+
+// POST /payments/initiate — Lancer (ou relancer) le paiement d'une commande (public)
+// La commande doit être en attente de paiement et l'email doit correspondre. Après un échec,
+// stock, créneau et code promo sont de nouveau réservés (409 s'ils ne sont plus disponibles).
+func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.InitiatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if req.OrderID <= 0 || strings.TrimSpace(req.Email) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "order_id et email requis"})
+		return
+	}
+
+	// Verrou sur la commande : deux clics sur "Payer" ne créent jamais deux tentatives
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	var status, email, method string
+	var total float64
+	err = tx.QueryRow(
+		"SELECT status, customer_email, payment_method, total_amount FROM orders WHERE id = $1 FOR UPDATE", req.OrderID,
+	).Scan(&status, &email, &method, &total)
+	if err == sql.ErrNoRows || (err == nil && !strings.EqualFold(strings.TrimSpace(req.Email), email)) {
+		// Même réponse dans les deux cas : on ne révèle pas l'existence de la commande
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Commande introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD InitiatePayment order=%d : %v\n", req.OrderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if status != "pending_payment" && status != "payment_failed" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cette commande n'attend pas de paiement"})
+		return
+	}
+
+	// Tentative en cours : le client la reprend (checkout_url) ou la fait vérifier
+	// (POST /payments/{id}/verify) au lieu d'être débité deux fois
+	var pendingID int
+	var pendingURL *string
+	err = tx.QueryRow(
+		"SELECT id, checkout_url FROM payments WHERE order_id = $1 AND status = 'pending' ORDER BY id DESC LIMIT 1", req.OrderID,
+	).Scan(&pendingID, &pendingURL)
+	if err == nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":      "Un paiement est déjà en cours pour cette commande",
+			"payment_id":   pendingID,
+			"checkout_url": pendingURL,
+		})
+		return
+	} else if err != sql.ErrNoRows {
+		fmt.Printf("Erreur BDD InitiatePayment order=%d : %v\n", req.OrderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	providerName := req.Provider
+	if providerName == "" {
+		providerName = method
+	}
+	provider, err := h.Payments.Get(providerName)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Moyen de paiement non disponible"})
+		return
+	}
+
+	// La tentative est enregistrée avant l'appel au prestataire : une réponse perdue
+	// reste traçable par sa merchant_reference
+	merchantRef := fmt.Sprintf("AKW-%d-%d", req.OrderID, time.Now().UnixNano())
+	// Nouvelle tentative après un échec : les réservations rendues sont reprises d'abord
+	if status == "payment_failed" {
+		if err := reserveOrderResources(tx, req.OrderID, 0); err != nil {
+			writeOrderError(w, "InitiatePayment", err)
+			return
+		}
+	}
+	var paymentID int
+	err = tx.QueryRow(
+		`INSERT INTO payments (order_id, provider, merchant_reference, amount, phone)
+         VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING id`,
+		req.OrderID, provider.Name(), merchantRef, total, strings.TrimSpace(req.Phone),
+	).Scan(&paymentID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE orders SET status = 'pending_payment', payment_method = $1 WHERE id = $2", provider.Name(), req.OrderID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD InitiatePayment order=%d : %v\n", req.OrderID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'enregistrement du paiement"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), paymentProviderTimeout)
+	defer cancel()
+	res, err := provider.Initiate(ctx, payments.InitiateRequest{
+		Reference:     merchantRef,
+		Amount:        total,
+		Currency:      "XOF",
+		Phone:         strings.TrimSpace(req.Phone),
+		CustomerEmail: email,
+		Description:   fmt.Sprintf("Commande Akwaba Bébé n°%d", req.OrderID),
+	})
+	if err != nil {
+		fmt.Printf("Erreur prestataire %s InitiatePayment payment=%d : %v\n", provider.Name(), paymentID, err)
+		if _, dbErr := applyPaymentStatus(h.DB, paymentID, payments.StatusFailed, err.Error()); dbErr != nil {
+			fmt.Printf("Erreur BDD InitiatePayment payment=%d : %v\n", paymentID, dbErr)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le service de paiement est indisponible, merci de réessayer"})
+		return
+	}
+
+	_, err = h.DB.Exec(
+		`UPDATE payments SET provider_reference = NULLIF($1, ''), checkout_url = NULLIF($2, ''), updated_at = NOW() WHERE id = $3`,
+		res.ProviderReference, res.CheckoutURL, paymentID,
+	)
+	if err == nil && res.Status != payments.StatusPending {
+		_, err = applyPaymentStatus(h.DB, paymentID, res.Status, res.Message)
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD InitiatePayment payment=%d : %v\n", paymentID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'enregistrement du paiement"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            "Paiement initié",
+		"payment_id":         paymentID,
+		"merchant_reference": merchantRef,
+		"provider":           provider.Name(),
+		"status":             res.Status,
+		"checkout_url":       res.CheckoutURL,
+	})
+}
+
+// POST /payments/{id}/verify — Interroger le prestataire et mettre à jour le paiement (public)
+// Body : { "email": "..." } — doit correspondre à la commande.
+func (h *PaymentHandler) VerifyPayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := paymentIDFromPath(r.URL.Path, "verify")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	p, err := scanPayment(h.DB.QueryRow(`
+        SELECT `+paymentColumns+` FROM payments
+        WHERE id = $1 AND order_id IN (SELECT id FROM orders WHERE LOWER(customer_email) = LOWER($2))`,
+		id, strings.TrimSpace(req.Email),
+	))
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Paiement introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD VerifyPayment id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Seul un paiement en attente peut encore évoluer côté prestataire
+	if p.Status == payments.StatusPending && p.ProviderReference != nil {
+		provider, err := h.Payments.Get(p.Provider)
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Moyen de paiement non disponible"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), paymentProviderTimeout)
+		defer cancel()
+		res, err := provider.Verify(ctx, *p.ProviderReference)
+		if err != nil {
+			fmt.Printf("Erreur prestataire %s VerifyPayment id=%d : %v\n", p.Provider, id, err)
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Le service de paiement est indisponible, merci de réessayer"})
+			return
+		}
+		if _, err := applyPaymentStatus(h.DB, id, res.Status, res.Message); err != nil {
+			fmt.Printf("Erreur BDD VerifyPayment id=%d : %v\n", id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+			return
+		}
+		if p, err = scanPayment(h.DB.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id = $1", id)); err != nil {
+			fmt.Printf("Erreur BDD VerifyPayment id=%d : %v\n", id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+			return
+		}
+	} else if p.Status == payments.StatusSucceeded && p.ProviderReference != nil {
+		// Remboursement en cours chez le prestataire : finalisé s'il signale le paiement remboursé
+		settled, err := h.settlePendingRefunds(r.Context(), p)
+		if err != nil {
+			fmt.Printf("Erreur VerifyPayment remboursements id=%d : %v\n", id, err)
+		} else if settled {
+			if p, err = scanPayment(h.DB.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id = $1", id)); err != nil {
+				fmt.Printf("Erreur BDD VerifyPayment id=%d : %v\n", id, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+				return
+			}
+		}
+	}
+
+	json.NewEncoder(w).Encode(p)
+}
+
+// settlePendingRefunds interroge le prestataire quand le paiement a des remboursements
+// 'pending' et les finalise (completePendingRefunds) s'il le signale remboursé
+func (h *PaymentHandler) settlePendingRefunds(ctx context.Context, p models.Payment) (bool, error) {
+	pending, err := pendingRefundAmount(h.DB, "payment_id", p.ID)
+	if err != nil || pending == 0 {
+		return false, err
+	}
+	provider, err := h.Payments.Get(p.Provider)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, paymentProviderTimeout)
+	defer cancel()
+	res, err := provider.Verify(ctx, *p.ProviderReference)
+	if err != nil || res.Status != payments.StatusRefunded {
+		return false, err
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err := completePendingRefunds(tx, p.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// POST /payments/{id}/refund — Rembourser tout ou partie d'un paiement confirmé [ADMIN]
+// Le remboursement est enregistré 'pending' avant l'appel au prestataire, sans verrou
+// pendant l'appel : deux remboursements simultanés ne peuvent pas dépasser le montant payé.
+func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := paymentIDFromPath(r.URL.Path, "refund")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.RefundPaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+			return
+		}
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Verrou sur la commande puis le paiement, comme POST /orders/{id}/refunds
+	_, err = tx.Exec("SELECT o.id FROM orders o JOIN payments p ON p.order_id = o.id WHERE p.id = $1 FOR UPDATE OF o", id)
+	var p models.Payment
+	if err == nil {
+		p, err = scanPayment(tx.QueryRow("SELECT "+paymentColumns+" FROM payments WHERE id = $1 FOR UPDATE", id))
+	}
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Paiement introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD RefundPayment id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if p.Status != payments.StatusSucceeded || p.ProviderReference == nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Seul un paiement confirmé peut être remboursé"})
+		return
+	}
+	pending, err := pendingRefundAmount(tx, "payment_id", p.ID)
+	if err != nil {
+		fmt.Printf("Erreur BDD RefundPayment id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	remaining := roundAmount(p.Amount - p.RefundedAmount - pending)
+	amount := remaining
+	if req.Amount != nil {
+		amount = roundAmount(*req.Amount)
+	}
+	if amount <= 0 || amount > remaining {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Montant invalide : %.0f FCFA remboursables au maximum", remaining)})
+		return
+	}
+
+	// Remboursement enregistré 'pending' et validé avant l'appel au prestataire
+	refundID, err := beginProviderRefund(tx, p.OrderID, p.ID, amount, req.Reason, middleware.UserID(r))
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD RefundPayment id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), paymentProviderTimeout)
+	defer cancel()
+	err = refundThroughProvider(ctx, h.DB, h.Payments, p, refundID, amount)
+	if err == errRefundPending {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":   "Remboursement en cours chez le prestataire : il sera comptabilisé à sa confirmation",
+			"refund_id": refundID,
+			"status":    "pending",
+		})
+		return
+	} else if err != nil {
+		writeOrderError(w, "RefundPayment", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":         "Remboursement effectué",
+		"refund_id":       refundID,
+		"refunded_amount": roundAmount(p.RefundedAmount + amount),
+		"fully_refunded":  amount == roundAmount(p.Amount-p.RefundedAmount),
+	})
+}
+
+// GET /payments?order_id={id} — Tentatives de paiement d'une commande [ADMIN]
+func (h *PaymentHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderID, err := strconv.Atoi(r.URL.Query().Get("order_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Paramètre order_id requis"})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 ORDER BY created_at DESC", orderID)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetPayments : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des paiements"})
+		return
+	}
+	defer rows.Close()
+
+	list := make([]models.Payment, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan payments : %v\n", err)
+			continue
+		}
+		list = append(list, p)
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"akwaba-bebe/backend/internal/payments"
+)
+
+func TestPaymentTransitionAllowed(t *testing.T) {
+	tests := []struct {
+		current, status string
+		want            bool
+	}{
+		{payments.StatusPending, payments.StatusSucceeded, true},
+		{payments.StatusPending, payments.StatusFailed, true},
+		{payments.StatusFailed, payments.StatusSucceeded, true}, // confirmation tardive
+		{payments.StatusSucceeded, payments.StatusRefunded, true},
+
+		// Statut déjà enregistré (webhook rejoué)
+		{payments.StatusPending, payments.StatusPending, false},
+		{payments.StatusSucceeded, payments.StatusSucceeded, false},
+		{payments.StatusFailed, payments.StatusFailed, false},
+		{payments.StatusRefunded, payments.StatusRefunded, false},
+
+		// Retours en arrière
+		{payments.StatusSucceeded, payments.StatusPending, false},
+		{payments.StatusSucceeded, payments.StatusFailed, false},
+		{payments.StatusFailed, payments.StatusPending, false},
+		{payments.StatusRefunded, payments.StatusSucceeded, false},
+		{payments.StatusRefunded, payments.StatusFailed, false},
+
+		// Remboursement d'un paiement non encaissé
+		{payments.StatusPending, payments.StatusRefunded, false},
+		{payments.StatusFailed, payments.StatusRefunded, false},
+
+		{payments.StatusPending, "inconnu", false},
+	}
+
+	for _, tt := range tests {
+		if got := paymentTransitionAllowed(tt.current, tt.status); got != tt.want {
+			t.Errorf("paymentTransitionAllowed(%q, %q) = %v, %v attendu", tt.current, tt.status, got, tt.want)
+		}
+	}
+}
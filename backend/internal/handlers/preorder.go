@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+type PreorderHandler struct {
+	DB *sql.DB
+}
+
+// Statuts de commande dont les précommandes ne sont plus à servir
+const preorderClosedStatuses = `('annulé', 'payment_failed', 'refunded')`
+
+// pendingPreorderUnits compte les unités précommandées pas encore servies
+func pendingPreorderUnits(q queryer, productID int) (int, error) {
+	var units int
+	err := q.QueryRow(`
+        SELECT COALESCE(SUM(oi.quantity - oi.cancelled_quantity), 0)
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        WHERE oi.product_id = $1 AND oi.is_preorder AND oi.preorder_allocated_at IS NULL
+          AND o.status NOT IN `+preorderClosedStatuses, productID).Scan(&units)
+	return units, err
+}
+
+// reservePreorder accepte la ligne en précommande si le produit l'est toujours et
+// que le plafond n'est pas atteint (produit verrouillé : pas de dépassement concurrent)
+func reservePreorder(tx *sql.Tx, l orderLine) error {
+	var enabled bool
+	var limit sql.NullInt64
+	err := tx.QueryRow("SELECT preorder_enabled, preorder_cap FROM products WHERE id = $1 FOR UPDATE", l.Product.ID).
+		Scan(&enabled, &limit)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return newOrderError(http.StatusConflict, "Stock insuffisant pour %s", l.Product.Name)
+	}
+	if limit.Valid {
+		pending, err := pendingPreorderUnits(tx, l.Product.ID)
+		if err != nil {
+			return err
+		}
+		if pending+l.Quantity > int(limit.Int64) {
+			return newOrderError(http.StatusConflict, "Précommandes complètes pour %s (%d place(s) restante(s))",
+				l.Product.Name, max(int(limit.Int64)-pending, 0))
+		}
+	}
+	return nil
+}
+
+// allocatePreorders sert les précommandes en attente du produit, dans l'ordre des
+// commandes, tant que le stock couvre la ligne suivante. Renvoie les unités servies.
+// Une commande en attente de paiement en ligne garde sa place dans le plafond mais n'est
+// servie qu'une fois payée (voir allocateOrderPreorders) : le stock ne part pas vers une
+// commande qui peut encore échouer.
+func allocatePreorders(tx *sql.Tx, productID, actorID int) (int, error) {
+	rows, err := tx.Query(`
+        SELECT oi.id, oi.order_id, oi.quantity - oi.cancelled_quantity
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        WHERE oi.product_id = $1 AND oi.is_preorder AND oi.preorder_allocated_at IS NULL
+          AND oi.quantity > oi.cancelled_quantity
+          AND o.status NOT IN `+preorderClosedStatuses+` AND o.status <> 'pending_payment'
+        ORDER BY oi.id
+        FOR UPDATE OF oi`, productID)
+	if err != nil {
+		return 0, err
+	}
+	type pendingLine struct{ itemID, orderID, quantity int }
+	pending := make([]pendingLine, 0)
+	for rows.Next() {
+		var l pendingLine
+		if err := rows.Scan(&l.itemID, &l.orderID, &l.quantity); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	allocated := 0
+	for _, l := range pending {
+		_, _, err := adjustStock(tx, productID, -l.quantity, stockMovement{
+			Type: "sale", OrderID: l.orderID, ActorID: actorID, Reason: "Précommande servie",
+		})
+		if err == errInsufficientStock {
+			break // Premier arrivé, premier servi : pas de saut de file
+		} else if err != nil {
+			return allocated, err
+		}
+		if _, err := tx.Exec("UPDATE order_items SET preorder_allocated_at = NOW() WHERE id = $1", l.itemID); err != nil {
+			return allocated, err
+		}
+		err = logOrderHistory(tx, l.orderID, actorID, "preorder_allocated",
+			fmt.Sprintf("Précommande servie : %d unité(s)", l.quantity),
+			map[string]interface{}{"item_id": l.itemID, "product_id": productID, "quantity": l.quantity})
+		if err != nil {
+			return allocated, err
+		}
+		allocated += l.quantity
+	}
+	return allocated, nil
+}
+
+// allocateOrderPreorders sert, si le stock le permet, les précommandes d'une commande
+// qui vient d'être payée (elles étaient ignorées tant que le paiement était en attente)
+func allocateOrderPreorders(tx *sql.Tx, orderID int) error {
+	rows, err := tx.Query(`
+        SELECT DISTINCT product_id FROM order_items
+        WHERE order_id = $1 AND product_id IS NOT NULL AND is_preorder AND preorder_allocated_at IS NULL
+          AND quantity > cancelled_quantity`, orderID)
+	if err != nil {
+		return err
+	}
+	productIDs := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		productIDs = append(productIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range productIDs {
+		if _, err := allocatePreorders(tx, id, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveStock applique une variation de stock (adjustStock) puis, si c'est une entrée,
+// sert les précommandes en attente. Renvoie le stock restant après service.
+func receiveStock(tx *sql.Tx, productID, delta int, m stockMovement) (int, error) {
+	stock, _, err := adjustStock(tx, productID, delta, m)
+	if err != nil || delta <= 0 {
+		return stock, err
+	}
+	allocated, err := allocatePreorders(tx, productID, m.ActorID)
+	return stock - allocated, err
+}
+
+// PUT /products/{id}/preorder — Ouvrir ou fermer la précommande d'un produit [ADMIN]
+// Fermer la précommande n'annule pas les lignes en attente : elles restent servies à la réception.
+func (h *PreorderHandler) SetPreorder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/products/"), "/preorder"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.PreorderSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if req.AvailableAt != nil && *req.AvailableAt != "" {
+		if _, err := time.Parse("2006-01-02", *req.AvailableAt); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Date de disponibilité invalide (AAAA-MM-JJ)"})
+			return
+		}
+	} else {
+		req.AvailableAt = nil
+	}
+	if req.Cap != nil && *req.Cap <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le plafond doit être positif"})
+		return
+	}
+
+	res, err := h.DB.Exec(`
+        UPDATE products SET preorder_enabled = $1, preorder_available_at = $2::date, preorder_cap = $3
+        WHERE id = $4`,
+		req.Enabled, req.AvailableAt, req.Cap, id)
+	if err != nil {
+		fmt.Printf("Erreur BDD SetPreorder id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur mise à jour précommande"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	}
+
+	pending, err := pendingPreorderUnits(h.DB, id)
+	if err != nil {
+		fmt.Printf("Erreur BDD SetPreorder id=%d : %v\n", id, err)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Précommande mise à jour",
+		"product_id":    id,
+		"enabled":       req.Enabled,
+		"available_at":  req.AvailableAt,
+		"cap":           req.Cap,
+		"pending_units": pending,
+	})
+}
+
+// GET /preorders?status=pending&product_id=4 — Lignes en précommande [ADMIN]
+// status : "pending" (en attente de stock) ou "allocated" (servies) ; toutes par défaut.
+// Les commandes annulées, en échec de paiement ou remboursées sont exclues.
+func (h *PreorderHandler) GetPreorders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := `
+        SELECT oi.id, o.id, o.status, o.customer_firstname || ' ' || o.customer_lastname, o.customer_email,
+               oi.product_id, oi.product_name, oi.quantity - oi.cancelled_quantity,
+               TO_CHAR(o.created_at, 'YYYY-MM-DD"T"HH24:MI:SS'),
+               TO_CHAR(p.preorder_available_at, 'YYYY-MM-DD'),
+               TO_CHAR(oi.preorder_allocated_at, 'YYYY-MM-DD"T"HH24:MI:SS')
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        LEFT JOIN products p ON p.id = oi.product_id
+        WHERE oi.is_preorder AND oi.quantity > oi.cancelled_quantity
+          AND o.status NOT IN ` + preorderClosedStatuses
+	args := []interface{}{}
+	switch q.Get("status") {
+	case "pending":
+		query += " AND oi.preorder_allocated_at IS NULL"
+	case "allocated":
+		query += " AND oi.preorder_allocated_at IS NOT NULL"
+	}
+	if productID, err := strconv.Atoi(q.Get("product_id")); err == nil {
+		args = append(args, productID)
+		query += fmt.Sprintf(" AND oi.product_id = $%d", len(args))
+	}
+	query += " ORDER BY oi.id"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetPreorders : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	lines := make([]models.PreorderLine, 0)
+	for rows.Next() {
+		var l models.PreorderLine
+		if err := rows.Scan(&l.ItemID, &l.OrderID, &l.OrderStatus, &l.CustomerName, &l.CustomerEmail,
+			&l.ProductID, &l.ProductName, &l.Quantity, &l.OrderedAt, &l.AvailableAt, &l.AllocatedAt); err != nil {
+			fmt.Printf("Erreur scan précommande : %v\n", err)
+			continue
+		}
+		lines = append(lines, l)
+	}
+	json.NewEncoder(w).Encode(lines)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+// queryer est satisfait par *sql.DB et *sql.Tx : la résolution des prix
+// peut ainsi être faite aussi bien en lecture simple que dans la transaction de commande.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// activePromotion : campagne en cours avec ses cibles, chargée une fois par requête
+type activePromotion struct {
+	applied       models.AppliedPromotion
+	products      map[int]bool
+	categories    map[int]bool
+	subcategories map[int]bool
+}
+
+func (p *activePromotion) targets(prod *models.Product) bool {
+	if p.products[prod.ID] || p.categories[prod.CategoryID] {
+		return true
+	}
+	return prod.SubcategoryID != nil && p.subcategories[*prod.SubcategoryID]
+}
+
+// loadActivePromotions charge les campagnes actives à l'instant de la requête
+// (is_active ET starts_at <= NOW() < ends_at) avec leurs cibles.
+func loadActivePromotions(q queryer) ([]*activePromotion, error) {
+	rows, err := q.Query(`
+        SELECT p.id, p.name, p.discount_type, p.discount_value, t.target_type, t.target_id
+        FROM promotions p
+        JOIN promotion_targets t ON t.promotion_id = p.id
+        WHERE p.is_active AND p.starts_at <= NOW() AND p.ends_at > NOW()
+        ORDER BY p.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promos := make([]*activePromotion, 0)
+	byID := make(map[int]*activePromotion)
+	for rows.Next() {
+		var id, targetID int
+		var name, discountType, targetType string
+		var value float64
+		if err := rows.Scan(&id, &name, &discountType, &value, &targetType, &targetID); err != nil {
+			return nil, err
+		}
+
+		promo, ok := byID[id]
+		if !ok {
+			promoID := id
+			promo = &activePromotion{
+				applied:       models.AppliedPromotion{ID: &promoID, Name: name, DiscountType: discountType, DiscountValue: value},
+				products:      make(map[int]bool),
+				categories:    make(map[int]bool),
+				subcategories: make(map[int]bool),
+			}
+			byID[id] = promo
+			promos = append(promos, promo)
+		}
+
+		switch targetType {
+		case "product":
+			promo.products[targetID] = true
+		case "category":
+			promo.categories[targetID] = true
+		case "subcategory":
+			promo.subcategories[targetID] = true
+		}
+	}
+	return promos, rows.Err()
+}
+
+// discountedPrice applique une remise sans jamais descendre sous 0.
+// Arrondi au centime : la colonne price est en NUMERIC(10, 2).
+func discountedPrice(price float64, discountType string, value float64) float64 {
+	var final float64
+	switch discountType {
+	case "percent":
+		final = price * (1 - value/100)
+	case "fixed":
+		final = price - value
+	default:
+		final = price
+	}
+	if final < 0 {
+		final = 0
+	}
+	return roundAmount(final)
+}
+
+// resolvePrice renseigne FinalPrice et AppliedPromotion avec la promotion
+// la plus avantageuse pour le client parmi :
+//   - la promotion immédiate historique (products.promotion_percent)
+//   - les campagnes actives qui ciblent le produit, sa catégorie ou sa sous-catégorie
+func resolvePrice(p *models.Product, promos []*activePromotion) {
+	p.FinalPrice = p.Price
+	p.AppliedPromotion = nil
+
+	if p.PromotionPercent != nil && *p.PromotionPercent > 0 {
+		p.FinalPrice = discountedPrice(p.Price, "percent", *p.PromotionPercent)
+		p.AppliedPromotion = &models.AppliedPromotion{
+			Name:          "Promotion",
+			DiscountType:  "percent",
+			DiscountValue: *p.PromotionPercent,
+		}
+	}
+
+	for _, promo := range promos {
+		if !promo.targets(p) {
+			continue
+		}
+		candidate := discountedPrice(p.Price, promo.applied.DiscountType, promo.applied.DiscountValue)
+		if candidate < p.FinalPrice {
+			applied := promo.applied
+			p.FinalPrice = candidate
+			p.AppliedPromotion = &applied
+		}
+	}
+}
+
+// applyPricing résout le prix de chaque produit de la liste en une seule
+// lecture des campagnes actives.
+func applyPricing(q queryer, products []models.Product) error {
+	promos, err := loadActivePromotions(q)
+	if err != nil {
+		// Le catalogue reste servi au prix de base plutôt que d'échouer
+		for i := range products {
+			resolvePrice(&products[i], nil)
+		}
+		return err
+	}
+	for i := range products {
+		resolvePrice(&products[i], promos)
+	}
+	return nil
+}
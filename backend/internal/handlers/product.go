@@ -8,18 +8,62 @@ import (
 	"strconv"
 	"strings"
 
+	"akwaba-bebe/backend/internal/middleware"
 	"akwaba-bebe/backend/internal/models"
 	"akwaba-bebe/backend/internal/utils"
+
+	"github.com/lib/pq"
 )
 
 type ProductHandler struct {
 	DB *sql.DB
 }
 
+// Stock vendable : celui du produit, ou pour un coffret le nombre de coffrets
+// réalisables avec le stock des composants
+const productStockColumn = `CASE WHEN p.is_bundle THEN COALESCE((
+            SELECT MIN(c.stock_quantity / bi.quantity) FROM bundle_items bi
+            JOIN products c ON c.id = bi.component_id WHERE bi.bundle_id = p.id), 0)
+        ELSE p.stock_quantity END`
+
+// Champs de précommande lus avec le produit (date au format AAAA-MM-JJ)
+const productPreorderColumns = `p.preorder_enabled, TO_CHAR(p.preorder_available_at, 'YYYY-MM-DD'), p.preorder_cap`
+
+// Note moyenne et nombre d'avis approuvés, joints aux lectures du catalogue
+const productRatingColumns = `COALESCE(rs.rating_average, 0), COALESCE(rs.rating_count, 0)`
+const productRatingJoin = `
+        LEFT JOIN (
+            SELECT product_id, ROUND(AVG(rating)::numeric, 1)::float8 AS rating_average, COUNT(*) AS rating_count
+            FROM reviews WHERE status = 'approved' GROUP BY product_id
+        ) rs ON rs.product_id = p.id`
+
+// isDuplicateSKU reconnaît la violation de la contrainte UNIQUE sur products.sku (migration 005)
+func isDuplicateSKU(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505" && pqErr.Constraint == "products_sku_key"
+}
+
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	rows, err := h.DB.Query("SELECT id, name, description, price, stock_quantity, image_url, category_id, subcategory_id, promotion_percent FROM products ORDER BY id ASC")
+	// Produits archivés : exclus du catalogue public, visibles par le back-office avec
+	// ?archived=true (archivés uniquement) ou ?archived=all
+	where := "WHERE p.archived_at IS NULL"
+	if middleware.HasPermission(middleware.Role(r), middleware.PermCatalogManage) {
+		switch r.URL.Query().Get("archived") {
+		case "true":
+			where = "WHERE p.archived_at IS NOT NULL"
+		case "all":
+			where = ""
+		}
+	}
+
+	rows, err := h.DB.Query(`
+        SELECT p.id, p.name, COALESCE(p.sku, ''), p.description, p.price, ` + productStockColumn + `, p.image_url,
+               p.category_id, p.subcategory_id, p.promotion_percent, p.is_bundle, ` + productPreorderColumns + `, ` + productRatingColumns + `, p.archived_at
+        FROM products p ` + productRatingJoin + `
+        ` + where + `
+        ORDER BY p.id ASC`)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur BDD"})
@@ -30,12 +74,18 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 	products := make([]models.Product, 0)
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.ImageURL, &p.CategoryID, &p.SubcategoryID, &p.PromotionPercent); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.SKU, &p.Description, &p.Price, &p.StockQuantity, &p.ImageURL, &p.CategoryID, &p.SubcategoryID, &p.PromotionPercent,
+			&p.IsBundle, &p.PreorderEnabled, &p.PreorderAvailableAt, &p.PreorderCap, &p.RatingAverage, &p.RatingCount, &p.ArchivedAt); err != nil {
 			continue
 		}
 		products = append(products, p)
 	}
 
+	// Prix résolu au moment de la requête (campagnes programmées + promo immédiate)
+	if err := applyPricing(h.DB, products); err != nil {
+		fmt.Printf("Erreur BDD promotions GetAllProducts : %v\n", err)
+	}
+
 	json.NewEncoder(w).Encode(products)
 }
 
@@ -51,8 +101,13 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var p models.Product
-	row := h.DB.QueryRow("SELECT id, name, description, price, stock_quantity, image_url, category_id, subcategory_id, promotion_percent FROM products WHERE id=$1", id)
-	err = row.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.ImageURL, &p.CategoryID, &p.SubcategoryID, &p.PromotionPercent)
+	row := h.DB.QueryRow(`
+        SELECT p.id, p.name, COALESCE(p.sku, ''), p.description, p.price, `+productStockColumn+`, p.image_url,
+               p.category_id, p.subcategory_id, p.promotion_percent, p.is_bundle, `+productPreorderColumns+`, `+productRatingColumns+`, p.archived_at
+        FROM products p `+productRatingJoin+`
+        WHERE p.id = $1`, id)
+	err = row.Scan(&p.ID, &p.Name, &p.SKU, &p.Description, &p.Price, &p.StockQuantity, &p.ImageURL, &p.CategoryID, &p.SubcategoryID, &p.PromotionPercent,
+		&p.IsBundle, &p.PreorderEnabled, &p.PreorderAvailableAt, &p.PreorderCap, &p.RatingAverage, &p.RatingCount, &p.ArchivedAt)
 
 	if err == sql.ErrNoRows {
 		w.WriteHeader(http.StatusNotFound)
@@ -65,6 +120,25 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	promos, err := loadActivePromotions(h.DB)
+	if err != nil {
+		// Le produit reste servi au prix de base
+		fmt.Printf("Erreur BDD promotions GetProduct id=%d : %v\n", id, err)
+	}
+	resolvePrice(&p, promos)
+
+	// Composition du coffret
+	if p.IsBundle {
+		if p.BundleItems, err = loadBundleComponents(h.DB, id); err != nil {
+			fmt.Printf("Erreur BDD coffret GetProduct id=%d : %v\n", id, err)
+		}
+	}
+
+	// Questions / réponses publiques de la fiche produit
+	if p.Questions, err = loadPublicQuestions(h.DB, id); err != nil {
+		fmt.Printf("Erreur BDD questions GetProduct id=%d : %v\n", id, err)
+	}
+
 	json.NewEncoder(w).Encode(p)
 }
 
@@ -78,11 +152,41 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if p.StockQuantity < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le stock ne peut pas être négatif"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		fmt.Printf("Erreur BDD CreateProduct : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la création du produit"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Stock à 0 puis mouvement 'initial' : le journal des stocks couvre tout le stock
 	id := 0
-	err := h.DB.QueryRow(
-		`INSERT INTO products (name, description, price, stock_quantity, image_url, category_id, subcategory_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
-		p.Name, p.Description, p.Price, p.StockQuantity, p.ImageURL, p.CategoryID, p.SubcategoryID,
+	err = tx.QueryRow(
+		// NULLIF : un SKU vide est stocké NULL pour ne pas violer la contrainte UNIQUE
+		`INSERT INTO products (name, sku, description, price, stock_quantity, image_url, category_id, subcategory_id) VALUES ($1, NULLIF($2, ''), $3, $4, 0, $5, $6, $7) RETURNING id`,
+		p.Name, p.SKU, p.Description, p.Price, p.ImageURL, p.CategoryID, p.SubcategoryID,
 	).Scan(&id)
+	if isDuplicateSKU(err) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le SKU " + strings.TrimSpace(p.SKU) + " est déjà utilisé par un autre produit"})
+		return
+	}
+	if err == nil && p.StockQuantity > 0 {
+		_, _, err = adjustStock(tx, id, p.StockQuantity, stockMovement{
+			Type: "initial", ActorID: middleware.UserID(r), Reason: "Création du produit",
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
 		fmt.Printf("Erreur BDD CreateProduct : %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -105,30 +209,72 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var p models.Product
+	var p models.ProductUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
 		return
 	}
 
-	res, err := h.DB.Exec(
-		`UPDATE products SET name=$1, description=$2, price=$3, stock_quantity=$4, image_url=$5, category_id=$6, subcategory_id=$7 WHERE id=$8`,
-		p.Name, p.Description, p.Price, p.StockQuantity, p.ImageURL, p.CategoryID, p.SubcategoryID, id,
-	)
+	if p.StockQuantity < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le stock ne peut pas être négatif"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
 	if err != nil {
 		fmt.Printf("Erreur BDD UpdateProduct id=%d : %v\n", id, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification du produit"})
 		return
 	}
-
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
+	defer tx.Rollback()
+
+	// Le stock n'est plus écrasé : une modification explicite (stock_quantity différent de
+	// expected_stock_quantity) devient un mouvement 'correction', refusée si le stock a
+	// bougé depuis la lecture du formulaire. Sans expected_stock_quantity, stock_quantity
+	// est ignoré (les réceptions et inventaires passent par /stock/...).
+	// Coffret : le stock affiché est calculé, il n'est jamais corrigé ici
+	var current int
+	var isBundle bool
+	err = tx.QueryRow(
+		`UPDATE products SET name=$1, sku=NULLIF($2, ''), description=$3, price=$4, image_url=$5, category_id=$6, subcategory_id=$7 WHERE id=$8 RETURNING stock_quantity, is_bundle`,
+		p.Name, p.SKU, p.Description, p.Price, p.ImageURL, p.CategoryID, p.SubcategoryID, id,
+	).Scan(&current, &isBundle)
+	if err == sql.ErrNoRows {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Aucun produit trouvé avec cet ID"})
 		return
 	}
+	if isDuplicateSKU(err) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le SKU " + strings.TrimSpace(p.SKU) + " est déjà utilisé par un autre produit"})
+		return
+	}
+	stockChange := !isBundle && p.ExpectedStockQuantity != nil && p.StockQuantity != *p.ExpectedStockQuantity
+	if err == nil && stockChange && current != *p.ExpectedStockQuantity {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":        fmt.Sprintf("Le stock a changé depuis l'ouverture de la fiche (%d en stock) : rechargez-la avant de le corriger", current),
+			"stock_quantity": current,
+		})
+		return
+	}
+	if err == nil && stockChange {
+		_, err = receiveStock(tx, id, p.StockQuantity-current, stockMovement{
+			Type: "correction", ActorID: middleware.UserID(r), Reason: "Modification de la fiche produit",
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateProduct id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification du produit"})
+		return
+	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Produit mis à jour avec succès"})
 }
@@ -149,8 +295,24 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.DB.Exec("DELETE FROM products WHERE id = $1", id)
+	// Composant d'un coffret : bundle_items.component_id est en ON DELETE RESTRICT
+	var bundles []string
+	err = h.DB.QueryRow(`
+        SELECT COALESCE(array_agg(b.name ORDER BY b.name), '{}')
+        FROM bundle_items bi JOIN products b ON b.id = bi.bundle_id
+        WHERE bi.component_id = $1`, id).Scan(pq.Array(&bundles))
+	if err == nil && len(bundles) > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Ce produit compose les coffrets suivants : " + strings.Join(bundles, ", ") + ". Retirez-le de leur composition (ou archivez-le) avant de le supprimer",
+		})
+		return
+	}
+	if err == nil {
+		_, err = h.DB.Exec("DELETE FROM products WHERE id = $1", id)
+	}
 	if err != nil {
+		fmt.Printf("Erreur BDD DeleteProduct id=%d : %v\n", id, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur suppression BDD"})
 		return
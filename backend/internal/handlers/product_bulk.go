@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const maxBulkProducts = 1000
+
+// Produit verrouillé par une action groupée
+type bulkProduct struct {
+	id            int
+	name          string
+	price         float64
+	stock         int
+	isBundle      bool
+	categoryID    int
+	subcategoryID *int
+	archived      bool
+}
+
+// BulkUpdateProducts — POST /products/bulk (admin)
+// Applique une action à une sélection (product_ids) ou à un filtre de produits, dans une
+// seule transaction : la moindre erreur de produit, ou dry_run, annule l'ensemble.
+func (h *ProductHandler) BulkUpdateProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.ProductBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	if msg := validateBulkRequest(h.DB, &req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		fmt.Printf("Erreur BDD BulkUpdateProducts : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'action groupée"})
+		return
+	}
+	defer tx.Rollback()
+
+	products, err := lockBulkProducts(tx, req)
+	if err != nil {
+		fmt.Printf("Erreur BDD BulkUpdateProducts sélection : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'action groupée"})
+		return
+	}
+	if len(products) > maxBulkProducts {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Sélection trop large : %d produits (maximum %d)", len(products), maxBulkProducts)})
+		return
+	}
+
+	result := models.ProductBulkResult{Action: req.Action, DryRun: req.DryRun, Items: make([]models.ProductBulkItem, 0)}
+
+	// IDs demandés mais inexistants
+	found := make(map[int]bool)
+	for _, p := range products {
+		found[p.id] = true
+	}
+	for _, id := range req.ProductIDs {
+		if !found[id] {
+			found[id] = true
+			result.Items = append(result.Items, models.ProductBulkItem{ProductID: id, Status: "error", Message: "Produit introuvable"})
+		}
+	}
+
+	actorID := middleware.UserID(r)
+	for _, p := range products {
+		item, err := applyBulkAction(tx, req, p, actorID)
+		if err != nil {
+			fmt.Printf("Erreur BDD BulkUpdateProducts produit=%d : %v\n", p.id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Erreur lors de l'action groupée (produit %d)", p.id)})
+			return
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	for _, item := range result.Items {
+		switch item.Status {
+		case "ok":
+			result.Succeeded++
+		case "unchanged":
+			result.Unchanged++
+		default:
+			result.Failed++
+		}
+	}
+	result.Matched = len(products)
+
+	if result.Failed > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	if !req.DryRun {
+		if err := tx.Commit(); err != nil {
+			fmt.Printf("Erreur BDD BulkUpdateProducts commit : %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'action groupée"})
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// validateBulkRequest vérifie l'action, ses paramètres et la sélection ("" si valide)
+func validateBulkRequest(db *sql.DB, req *models.ProductBulkRequest) string {
+	switch req.Action {
+	case "archive", "unarchive":
+	case "set_category":
+		if req.CategoryID == nil {
+			return "category_id requis"
+		}
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", *req.CategoryID).Scan(&exists); err != nil || !exists {
+			return "Catégorie introuvable"
+		}
+		if req.SubcategoryID != nil {
+			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM subcategories WHERE id = $1 AND category_id = $2)",
+				*req.SubcategoryID, *req.CategoryID).Scan(&exists)
+			if err != nil || !exists {
+				return "Sous-catégorie introuvable dans cette catégorie"
+			}
+		}
+	case "adjust_price":
+		if req.Percent == nil || *req.Percent == 0 || *req.Percent < -90 || *req.Percent > 500 {
+			return "percent requis (entre -90 et 500, non nul)"
+		}
+	case "set_stock":
+		if req.Stock == nil || *req.Stock < 0 {
+			return "stock requis (entier positif ou nul)"
+		}
+	default:
+		return "Action invalide (set_category, archive, unarchive, adjust_price ou set_stock)"
+	}
+
+	if (len(req.ProductIDs) > 0) == (req.Filter != nil) {
+		return "product_ids ou filter requis (l'un ou l'autre)"
+	}
+	if len(req.ProductIDs) > maxBulkProducts {
+		return fmt.Sprintf("Maximum %d produits par action", maxBulkProducts)
+	}
+	if f := req.Filter; f != nil {
+		f.Search = strings.TrimSpace(f.Search)
+		// Garde-fou : pas d'action sur tout le catalogue par un filtre vide
+		if f.CategoryID == nil && f.SubcategoryID == nil && f.Search == "" && f.Archived == nil {
+			return "Le filtre doit contenir au moins un critère"
+		}
+	}
+	return ""
+}
+
+// lockBulkProducts lit et verrouille les produits visés, par ID croissant
+func lockBulkProducts(tx *sql.Tx, req models.ProductBulkRequest) ([]bulkProduct, error) {
+	query := `
+        SELECT id, name, price, stock_quantity, is_bundle, category_id, subcategory_id, archived_at IS NOT NULL
+        FROM products WHERE 1=1`
+	args := []interface{}{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(" AND "+clause, len(args))
+	}
+
+	if len(req.ProductIDs) > 0 {
+		add("id = ANY($%d)", pq.Array(req.ProductIDs))
+	} else {
+		f := req.Filter
+		if f.CategoryID != nil {
+			add("category_id = $%d", *f.CategoryID)
+		}
+		if f.SubcategoryID != nil {
+			add("subcategory_id = $%d", *f.SubcategoryID)
+		}
+		if f.Search != "" {
+			add("(name ILIKE '%%' || $%[1]d || '%%' OR sku ILIKE '%%' || $%[1]d || '%%')", f.Search)
+		}
+		if f.Archived != nil {
+			if *f.Archived {
+				query += " AND archived_at IS NOT NULL"
+			} else {
+				query += " AND archived_at IS NULL"
+			}
+		}
+	}
+	// Une ligne de plus que le maximum suffit à détecter une sélection trop large
+	query += fmt.Sprintf(" ORDER BY id LIMIT %d FOR UPDATE", maxBulkProducts+1)
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]bulkProduct, 0)
+	for rows.Next() {
+		var p bulkProduct
+		if err := rows.Scan(&p.id, &p.name, &p.price, &p.stock, &p.isBundle, &p.categoryID, &p.subcategoryID, &p.archived); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// applyBulkAction applique l'action à un produit. Une erreur renvoyée est une erreur BDD ;
+// un refus métier est un item en statut "error"
+func applyBulkAction(tx *sql.Tx, req models.ProductBulkRequest, p bulkProduct, actorID int) (models.ProductBulkItem, error) {
+	item := models.ProductBulkItem{ProductID: p.id, Name: p.name, Status: "ok"}
+
+	switch req.Action {
+	case "archive", "unarchive":
+		archive := req.Action == "archive"
+		item.OldValue, item.NewValue = archiveState(p.archived), archiveState(archive)
+		if p.archived == archive {
+			item.Status = "unchanged"
+			return item, nil
+		}
+		query := "UPDATE products SET archived_at = NOW() WHERE id = $1"
+		if !archive {
+			query = "UPDATE products SET archived_at = NULL WHERE id = $1"
+		}
+		_, err := tx.Exec(query, p.id)
+		return item, err
+
+	case "set_category":
+		item.OldValue = categoryPair(p.categoryID, p.subcategoryID)
+		item.NewValue = categoryPair(*req.CategoryID, req.SubcategoryID)
+		if item.OldValue == item.NewValue {
+			item.Status = "unchanged"
+			return item, nil
+		}
+		_, err := tx.Exec("UPDATE products SET category_id = $1, subcategory_id = $2 WHERE id = $3",
+			*req.CategoryID, req.SubcategoryID, p.id)
+		return item, err
+
+	case "adjust_price":
+		price := roundAmount(p.price * (1 + *req.Percent/100))
+		item.OldValue = strconv.FormatFloat(p.price, 'f', -1, 64)
+		item.NewValue = strconv.FormatFloat(price, 'f', -1, 64)
+		if price <= 0 {
+			item.Status = "error"
+			item.Message = "Le prix obtenu doit rester positif"
+			return item, nil
+		}
+		_, err := tx.Exec("UPDATE products SET price = $1 WHERE id = $2", price, p.id)
+		return item, err
+
+	case "set_stock":
+		item.OldValue = strconv.Itoa(p.stock)
+		item.NewValue = strconv.Itoa(*req.Stock)
+		if p.isBundle {
+			item.Status = "error"
+			item.Message = "Coffret : le stock est calculé à partir des composants"
+			return item, nil
+		}
+		if p.stock == *req.Stock {
+			item.Status = "unchanged"
+			return item, nil
+		}
+		reason := strings.TrimSpace(req.Reason)
+		if reason == "" {
+			reason = "Action groupée"
+		}
+		// Passe par le journal des stocks et sert les précommandes en attente
+		_, err := receiveStock(tx, p.id, *req.Stock-p.stock, stockMovement{
+			Type: "correction", ActorID: actorID, Reason: reason,
+		})
+		return item, err
+	}
+	return item, nil
+}
+
+func archiveState(archived bool) string {
+	if archived {
+		return "archived"
+	}
+	return "active"
+}
+
+// categoryPair : "catégorie/sous-catégorie" ("5/12", ou "5" sans sous-catégorie)
+func categoryPair(categoryID int, subcategoryID *int) string {
+	if subcategoryID == nil {
+		return strconv.Itoa(categoryID)
+	}
+	return fmt.Sprintf("%d/%d", categoryID, *subcategoryID)
+}
@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/utils"
+)
+
+// Colonnes du fichier catalogue, communes à l'import et à l'export
+var catalogueColumns = []string{"sku", "name", "description", "price", "stock_quantity", "category", "subcategory", "image_url"}
+
+// Colonnes obligatoires dans l'en-tête du fichier importé
+var catalogueRequiredColumns = []string{"sku", "name", "price", "category"}
+
+const maxImportRows = 5000
+
+// Ligne du fichier après validation
+type catalogueRow struct {
+	line          int
+	sku           string
+	name          string
+	description   string
+	price         float64
+	stock         *int // nil = colonne vide : stock inchangé (0 à la création)
+	categoryID    int
+	subcategoryID *int
+	imageURL      string
+}
+
+// ImportProducts crée ou met à jour les produits d'un fichier CSV (clé : SKU).
+// Tout est exécuté dans une transaction : la moindre erreur de ligne, ou ?dry_run=true,
+// l'annule et la réponse liste les erreurs ligne par ligne.
+func (h *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	r.ParseMultipartForm(10 << 20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Fichier invalide ou absent"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Fichier illisible"})
+		return
+	}
+	// BOM UTF-8 ajouté par Excel
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Fichier CSV vide ou mal formé"})
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range catalogueRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Colonne obligatoire absente : " + name})
+			return
+		}
+	}
+
+	categories, subcategories, err := loadCategoryNames(h.DB)
+	if err != nil {
+		fmt.Printf("Erreur BDD catégories ImportProducts : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur BDD"})
+		return
+	}
+
+	result := models.ProductImportResult{DryRun: dryRun, Errors: make([]models.ProductImportError, 0)}
+	rows := make([]catalogueRow, 0)
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			}
+			result.Errors = append(result.Errors, models.ProductImportError{Line: line, Message: "Ligne CSV mal formée"})
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		result.Rows++
+		if result.Rows > maxImportRows {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Maximum %d lignes par import", maxImportRows)})
+			return
+		}
+
+		row, rowErrors := parseCatalogueRow(line, record, columns, categories, subcategories)
+		if row.sku != "" {
+			if first, ok := seen[strings.ToLower(row.sku)]; ok {
+				rowErrors = append(rowErrors, models.ProductImportError{
+					Line: line, Field: "sku", Message: fmt.Sprintf("SKU déjà présent ligne %d", first),
+				})
+			} else {
+				seen[strings.ToLower(row.sku)] = line
+			}
+		}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		fmt.Printf("Erreur BDD ImportProducts : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'import du catalogue"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Les lignes valides sont écrites même en dry-run : les compteurs reflètent
+	// exactement ce que ferait l'import, puis la transaction est annulée
+	actorID := middleware.UserID(r)
+	for _, row := range rows {
+		created, err := upsertCatalogueRow(tx, row, actorID)
+		if err != nil {
+			fmt.Printf("Erreur BDD ImportProducts ligne %d : %v\n", row.line, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Erreur lors de l'import (ligne %d)", row.line)})
+			return
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			fmt.Printf("Erreur BDD ImportProducts commit : %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'import du catalogue"})
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// detectDelimiter choisit ';' (Excel FR) ou ',' selon la première ligne
+func detectDelimiter(data []byte) rune {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+// loadCategoryNames indexe catégories et sous-catégories par nom (insensible à la casse).
+// Sous-catégories : clé "categoryID/nom"
+func loadCategoryNames(db *sql.DB) (map[string]int, map[string]int, error) {
+	categories := make(map[string]int)
+	rows, err := db.Query(`SELECT id, name FROM categories`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, nil, err
+		}
+		categories[strings.ToLower(strings.TrimSpace(name))] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	subcategories := make(map[string]int)
+	subRows, err := db.Query(`SELECT id, name, category_id FROM subcategories`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer subRows.Close()
+	for subRows.Next() {
+		var id, categoryID int
+		var name string
+		if err := subRows.Scan(&id, &name, &categoryID); err != nil {
+			return nil, nil, err
+		}
+		subcategories[fmt.Sprintf("%d/%s", categoryID, strings.ToLower(strings.TrimSpace(name)))] = id
+	}
+	return categories, subcategories, subRows.Err()
+}
+
+// parseCatalogueRow valide une ligne et renvoie toutes ses erreurs d'un coup
+func parseCatalogueRow(line int, record []string, columns map[string]int, categories, subcategories map[string]int) (catalogueRow, []models.ProductImportError) {
+	row := catalogueRow{line: line}
+	rowErrors := make([]models.ProductImportError, 0)
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, models.ProductImportError{Line: line, Field: field, Message: message})
+	}
+	cell := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row.sku = cell("sku")
+	if row.sku == "" {
+		fail("sku", "SKU obligatoire")
+	}
+	row.name = cell("name")
+	if row.name == "" {
+		fail("name", "Nom obligatoire")
+	}
+	row.description = cell("description")
+
+	// Prix : "12500", "12 500" ou "12500,50"
+	priceStr := strings.ReplaceAll(strings.ReplaceAll(cell("price"), " ", ""), ",", ".")
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil || price <= 0 {
+		fail("price", "Prix invalide (nombre positif attendu)")
+	}
+	row.price = price
+
+	if stockStr := cell("stock_quantity"); stockStr != "" {
+		stock, err := strconv.Atoi(stockStr)
+		if err != nil || stock < 0 {
+			fail("stock_quantity", "Stock invalide (entier positif ou nul attendu)")
+		} else {
+			row.stock = &stock
+		}
+	}
+
+	category := cell("category")
+	categoryID, ok := categories[strings.ToLower(category)]
+	if category == "" {
+		fail("category", "Catégorie obligatoire")
+	} else if !ok {
+		fail("category", "Catégorie inconnue : "+category)
+	}
+	row.categoryID = categoryID
+
+	if subcategory := cell("subcategory"); subcategory != "" && ok {
+		id, found := subcategories[fmt.Sprintf("%d/%s", categoryID, strings.ToLower(subcategory))]
+		if !found {
+			fail("subcategory", "Sous-catégorie inconnue dans "+category+" : "+subcategory)
+		} else {
+			row.subcategoryID = &id
+		}
+	}
+
+	row.imageURL = cell("image_url")
+	if row.imageURL != "" {
+		u, err := url.Parse(row.imageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("image_url", "URL d'image invalide (http ou https attendu)")
+		}
+	}
+
+	return row, rowErrors
+}
+
+// upsertCatalogueRow met à jour le produit du SKU ou le crée. Les cellules vides
+// (description, sous-catégorie, image, stock) laissent la valeur actuelle inchangée,
+// sauf la sous-catégorie quand la catégorie change : elle appartient à l'ancienne
+// catégorie, le produit passe donc sans sous-catégorie si la cellule est vide.
+// Le stock passe par le journal : 'correction' en mise à jour, 'initial' à la création
+func upsertCatalogueRow(tx *sql.Tx, row catalogueRow, actorID int) (bool, error) {
+	var id, current int
+	var isBundle bool
+	err := tx.QueryRow(`
+        UPDATE products SET name = $1, description = COALESCE(NULLIF($2, ''), description), price = $3,
+               category_id = $4,
+               subcategory_id = CASE WHEN category_id = $4 THEN COALESCE($5, subcategory_id) ELSE $5 END,
+               image_url = COALESCE(NULLIF($6, ''), image_url)
+        WHERE sku = $7
+        RETURNING id, stock_quantity, is_bundle`,
+		row.name, row.description, row.price, row.categoryID, row.subcategoryID, row.imageURL, row.sku,
+	).Scan(&id, &current, &isBundle)
+	if err == nil {
+		// Coffret : stock calculé à partir des composants, la colonne est ignorée
+		if row.stock != nil && !isBundle && *row.stock != current {
+			_, err = receiveStock(tx, id, *row.stock-current, stockMovement{
+				Type: "correction", ActorID: actorID, Reason: "Import CSV",
+			})
+		}
+		return false, err
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO products (name, sku, description, price, stock_quantity, image_url, category_id, subcategory_id) VALUES ($1, $2, $3, $4, 0, $5, $6, $7) RETURNING id`,
+		row.name, row.sku, row.description, row.price, row.imageURL, row.categoryID, row.subcategoryID,
+	).Scan(&id)
+	if err == nil && row.stock != nil && *row.stock > 0 {
+		_, _, err = adjustStock(tx, id, *row.stock, stockMovement{
+			Type: "initial", ActorID: actorID, Reason: "Import CSV",
+		})
+	}
+	return true, err
+}
+
+// ExportProducts exporte le catalogue au format de l'import (?format=csv|xlsx).
+// Comme la liste des produits, les archivés sont exclus sauf ?archived=true|all.
+// Les produits sans SKU ne peuvent pas être réimportés : ils sont écartés et comptés
+// dans l'en-tête X-Skipped-Without-SKU.
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Format invalide (csv ou xlsx)"})
+		return
+	}
+
+	where := "WHERE p.archived_at IS NULL"
+	switch r.URL.Query().Get("archived") {
+	case "true":
+		where = "WHERE p.archived_at IS NOT NULL"
+	case "all":
+		where = ""
+	}
+
+	rows, err := h.DB.Query(`
+        SELECT COALESCE(p.sku, ''), p.name, COALESCE(p.description, ''), p.price, ` + productStockColumn + `,
+               COALESCE(c.name, ''), COALESCE(s.name, ''), COALESCE(p.image_url, '')
+        FROM products p
+        LEFT JOIN categories c ON c.id = p.category_id
+        LEFT JOIN subcategories s ON s.id = p.subcategory_id
+        ` + where + `
+        ORDER BY p.id ASC`)
+	if err != nil {
+		fmt.Printf("Erreur BDD ExportProducts : %v\n", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur BDD"})
+		return
+	}
+	defer rows.Close()
+
+	records := [][]string{catalogueColumns}
+	skipped := 0
+	for rows.Next() {
+		var sku, name, description, category, subcategory, imageURL string
+		var price float64
+		var stock int
+		if err := rows.Scan(&sku, &name, &description, &price, &stock, &category, &subcategory, &imageURL); err != nil {
+			continue
+		}
+		if strings.TrimSpace(sku) == "" {
+			skipped++
+			continue
+		}
+		records = append(records, []string{
+			sku, name, description, strconv.FormatFloat(price, 'f', -1, 64), strconv.Itoa(stock), category, subcategory, imageURL,
+		})
+	}
+
+	w.Header().Set("X-Skipped-Without-SKU", strconv.Itoa(skipped))
+	filename := "catalogue-" + time.Now().Format("2006-01-02") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		// Prix et stock en nombres pour les calculs dans Excel
+		if err := utils.WriteXLSX(w, "Catalogue", records, map[int]bool{3: true, 4: true}); err != nil {
+			fmt.Printf("Erreur export XLSX : %v\n", err)
+		}
+		return
+	}
+
+	// CSV ';' avec BOM : ouverture directe dans Excel FR, réimportable tel quel
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Write([]byte("\xef\xbb\xbf"))
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	writer.WriteAll(records)
+	if err := writer.Error(); err != nil {
+		fmt.Printf("Erreur export CSV : %v\n", err)
+	}
+}
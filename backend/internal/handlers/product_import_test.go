@@ -0,0 +1,124 @@
+package handlers
+
+import "testing"
+
+func TestParseCatalogueRow(t *testing.T) {
+	columns := map[string]int{"sku": 0, "name": 1, "description": 2, "price": 3, "stock_quantity": 4, "category": 5, "subcategory": 6, "image_url": 7}
+	categories := map[string]int{"vêtements": 1, "puériculture": 2}
+	subcategories := map[string]int{"1/bodies": 10, "2/poussettes": 20}
+
+	tests := []struct {
+		name            string
+		record          []string
+		wantFields      []string // champs en erreur, dans l'ordre
+		wantPrice       float64
+		wantStock       *int
+		wantCategory    int
+		wantSubcategory *int
+	}{
+		{
+			name:            "ligne complète",
+			record:          []string{"BODY-01", "Body coton", "Doux", "12500", "8", "Vêtements", "Bodies", "https://cdn.example.com/body.jpg"},
+			wantPrice:       12500,
+			wantStock:       intPtr(8),
+			wantCategory:    1,
+			wantSubcategory: intPtr(10),
+		},
+		{
+			name:         "prix avec espace et virgule décimale",
+			record:       []string{"BODY-02", "Body", "", "12 500,50", "", "vêtements", "", ""},
+			wantPrice:    12500.50,
+			wantCategory: 1,
+		},
+		{
+			name:         "cellules manquantes en fin de ligne",
+			record:       []string{"POUS-01", "Poussette", "", "150000", "", "Puériculture"},
+			wantPrice:    150000,
+			wantCategory: 2,
+		},
+		{
+			name:       "champs obligatoires vides",
+			record:     []string{" ", "", "", "", "", "", "", ""},
+			wantFields: []string{"sku", "name", "price", "category"},
+		},
+		{
+			name:         "prix et stock invalides",
+			record:       []string{"BODY-03", "Body", "", "-5", "deux", "Vêtements", "", ""},
+			wantFields:   []string{"price", "stock_quantity"},
+			wantCategory: 1,
+		},
+		{
+			name:       "catégorie inconnue",
+			record:     []string{"BODY-04", "Body", "", "1000", "", "Jouets", "Bodies", ""},
+			wantFields: []string{"category"},
+		},
+		{
+			name:         "sous-catégorie d'une autre catégorie",
+			record:       []string{"BODY-05", "Body", "", "1000", "", "Vêtements", "Poussettes", ""},
+			wantFields:   []string{"subcategory"},
+			wantCategory: 1,
+		},
+		{
+			name:         "URL d'image invalide",
+			record:       []string{"BODY-06", "Body", "", "1000", "", "Vêtements", "", "ftp://cdn.example.com/body.jpg"},
+			wantFields:   []string{"image_url"},
+			wantCategory: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, errs := parseCatalogueRow(3, tt.record, columns, categories, subcategories)
+
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("erreurs = %+v, champs %v attendus", errs, tt.wantFields)
+			}
+			for i, e := range errs {
+				if e.Field != tt.wantFields[i] || e.Line != 3 {
+					t.Errorf("erreur %d = %+v, champ %q ligne 3 attendu", i, e, tt.wantFields[i])
+				}
+			}
+			if len(errs) > 0 {
+				return
+			}
+
+			if row.price != tt.wantPrice {
+				t.Errorf("prix = %v, %v attendu", row.price, tt.wantPrice)
+			}
+			if !equalIntPtr(row.stock, tt.wantStock) {
+				t.Errorf("stock = %v, %v attendu", row.stock, tt.wantStock)
+			}
+			if row.categoryID != tt.wantCategory {
+				t.Errorf("catégorie = %d, %d attendue", row.categoryID, tt.wantCategory)
+			}
+			if !equalIntPtr(row.subcategoryID, tt.wantSubcategory) {
+				t.Errorf("sous-catégorie = %v, %v attendue", row.subcategoryID, tt.wantSubcategory)
+			}
+		})
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		data string
+		want rune
+	}{
+		{"sku;name;price;category\nA;B;1,5;C", ';'},
+		{"sku,name,price,category\nA,B,1.5,C", ','},
+		{"sku", ','},
+	}
+	for _, tt := range tests {
+		if got := detectDelimiter([]byte(tt.data)); got != tt.want {
+			t.Errorf("detectDelimiter(%q) = %q, %q attendu", tt.data, got, tt.want)
+		}
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/notifications"
+)
+
+type ProductQuestionHandler struct {
+	DB       *sql.DB
+	Notifier *notifications.Notifier
+}
+
+const productQuestionColumns = `q.id, q.product_id, p.name, q.asker_name, COALESCE(q.asker_email, ''), q.question,
+        q.answer, q.answered_at, q.is_public, q.notified_at, q.created_at`
+
+func scanProductQuestion(row interface{ Scan(...interface{}) error }) (models.ProductQuestion, error) {
+	var qa models.ProductQuestion
+	err := row.Scan(&qa.ID, &qa.ProductID, &qa.ProductName, &qa.AskerName, &qa.AskerEmail, &qa.Question,
+		&qa.Answer, &qa.AnsweredAt, &qa.IsPublic, &qa.NotifiedAt, &qa.CreatedAt)
+	return qa, err
+}
+
+// loadPublicQuestions renvoie les questions répondues et publiques d'un produit (GetProduct)
+func loadPublicQuestions(q queryer, productID int) ([]models.ProductQuestion, error) {
+	rows, err := q.Query(`
+        SELECT id, product_id, asker_name, question, answer, answered_at, created_at
+        FROM product_questions
+        WHERE product_id = $1 AND answer IS NOT NULL AND is_public
+        ORDER BY answered_at DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := make([]models.ProductQuestion, 0)
+	for rows.Next() {
+		qa := models.ProductQuestion{IsPublic: true}
+		if err := rows.Scan(&qa.ID, &qa.ProductID, &qa.AskerName, &qa.Question, &qa.Answer, &qa.AnsweredAt, &qa.CreatedAt); err != nil {
+			return nil, err
+		}
+		questions = append(questions, qa)
+	}
+	return questions, rows.Err()
+}
+
+// POST /products/{id}/questions — Poser une question sur un produit
+// Sans token, le nom et l'email sont obligatoires (pour prévenir de la réponse).
+func (h *ProductQuestionHandler) CreateQuestion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/products/"), "/questions"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.ProductQuestionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	input.Question = strings.TrimSpace(input.Question)
+	input.Name = strings.TrimSpace(input.Name)
+	input.Email = strings.TrimSpace(input.Email)
+	if len(input.Question) < 5 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "La question est trop courte"})
+		return
+	}
+
+	// Client connecté : nom et email du compte
+	var userID *int
+	if id := middleware.UserID(r); id > 0 {
+		var fullName, email string
+		if err := h.DB.QueryRow("SELECT COALESCE(full_name, ''), email FROM users WHERE id = $1", id).Scan(&fullName, &email); err == nil {
+			userID = &id
+			input.Email = email
+			if input.Name == "" {
+				input.Name = reviewAuthorName(fullName)
+			}
+		}
+	}
+	if input.Name == "" || !strings.Contains(input.Email, "@") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Nom et email valides obligatoires"})
+		return
+	}
+
+	var id int
+	err = h.DB.QueryRow(`
+        INSERT INTO product_questions (product_id, user_id, asker_name, asker_email, question)
+        SELECT $1, $2, $3, $4, $5 WHERE EXISTS (SELECT 1 FROM products WHERE id = $1)
+        RETURNING id`,
+		productID, userID, input.Name, input.Email, input.Question,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD CreateQuestion : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement question"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Question envoyée : vous serez prévenu(e) par email dès la réponse",
+		"id":      id,
+	})
+}
+
+// GET /product-questions?status=unanswered&product_id=7 — Questions reçues [ADMIN]
+// status : "unanswered" ou "answered" (toutes par défaut)
+func (h *ProductQuestionHandler) GetQuestions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := "SELECT " + productQuestionColumns + " FROM product_questions q JOIN products p ON p.id = q.product_id WHERE 1=1"
+	args := []interface{}{}
+	switch q.Get("status") {
+	case "unanswered":
+		query += " AND q.answer IS NULL"
+	case "answered":
+		query += " AND q.answer IS NOT NULL"
+	}
+	if productID, err := strconv.Atoi(q.Get("product_id")); err == nil {
+		args = append(args, productID)
+		query += fmt.Sprintf(" AND q.product_id = $%d", len(args))
+	}
+	query += " ORDER BY q.created_at DESC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetQuestions : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	questions := make([]models.ProductQuestion, 0)
+	for rows.Next() {
+		qa, err := scanProductQuestion(rows)
+		if err != nil {
+			fmt.Printf("Erreur scan question : %v\n", err)
+			continue
+		}
+		questions = append(questions, qa)
+	}
+	json.NewEncoder(w).Encode(questions)
+}
+
+// PUT /product-questions/{id}/answer — Répondre (ou corriger la réponse) [ADMIN]
+// Le client est notifié à la première réponse uniquement.
+func (h *ProductQuestionHandler) AnswerQuestion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/product-questions/"), "/answer"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.AnswerQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Answer) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "La réponse est obligatoire"})
+		return
+	}
+	isPublic := req.IsPublic == nil || *req.IsPublic
+
+	res, err := h.DB.Exec(`
+        UPDATE product_questions
+        SET answer = $1, is_public = $2, answered_by = $3, answered_at = COALESCE(answered_at, NOW())
+        WHERE id = $4`,
+		strings.TrimSpace(req.Answer), isPublic, middleware.UserID(r), id)
+	if err != nil {
+		fmt.Printf("Erreur BDD AnswerQuestion : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement réponse"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Question introuvable"})
+		return
+	}
+
+	qa, err := scanProductQuestion(h.DB.QueryRow(
+		"SELECT "+productQuestionColumns+" FROM product_questions q JOIN products p ON p.id = q.product_id WHERE q.id = $1", id))
+	if err != nil {
+		fmt.Printf("Erreur BDD AnswerQuestion : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Notification du client : un échec d'envoi n'annule pas la réponse (visible dans notifications)
+	if qa.NotifiedAt == nil && qa.AskerEmail != "" {
+		_, _, err := h.Notifier.Send(context.Background(), notifications.Message{
+			Kind:    "product_question_answered",
+			Email:   qa.AskerEmail,
+			Subject: "Réponse à votre question sur " + qa.ProductName,
+			Body: fmt.Sprintf("Bonjour %s,\n\nVous nous avez demandé à propos de %s :\n« %s »\n\nNotre réponse :\n%s\n\nL'équipe Akwaba Bébé",
+				qa.AskerName, qa.ProductName, qa.Question, *qa.Answer),
+		})
+		if err != nil {
+			fmt.Printf("Notification question id=%d : %v\n", id, err)
+		} else if err := h.DB.QueryRow("UPDATE product_questions SET notified_at = NOW() WHERE id = $1 RETURNING notified_at", id).Scan(&qa.NotifiedAt); err != nil {
+			fmt.Printf("Erreur BDD notified_at question id=%d : %v\n", id, err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(qa)
+}
+
+// DELETE /product-questions/{id} — Supprimer une question [ADMIN]
+func (h *ProductQuestionHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/product-questions/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM product_questions WHERE id = $1", id)
+	if err != nil {
+		fmt.Printf("Erreur BDD DeleteQuestion : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur suppression question"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Question introuvable"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Question supprimée"})
+}
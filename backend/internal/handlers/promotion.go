@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+type PromotionHandler struct {
+	DB *sql.DB
+}
+
+// Statut calculé par la BDD pour rester cohérent avec la résolution des prix (NOW())
+const promotionStatusSQL = `CASE
+            WHEN NOT is_active THEN 'disabled'
+            WHEN starts_at > NOW() THEN 'scheduled'
+            WHEN ends_at <= NOW() THEN 'expired'
+            ELSE 'active'
+        END`
+
+// validatePromotionInput renvoie un message d'erreur en français, ou "" si la saisie est valide
+func validatePromotionInput(in *models.PromotionInput) string {
+	if strings.TrimSpace(in.Name) == "" {
+		return "Le nom de la campagne est requis"
+	}
+	switch in.DiscountType {
+	case "percent":
+		if in.DiscountValue <= 0 || in.DiscountValue > 100 {
+			return "Pourcentage invalide (1-100 requis)"
+		}
+	case "fixed":
+		if in.DiscountValue <= 0 {
+			return "Le montant de la remise doit être positif"
+		}
+	default:
+		return "discount_type doit valoir \"percent\" ou \"fixed\""
+	}
+	if in.StartsAt.IsZero() || in.EndsAt.IsZero() || !in.EndsAt.After(in.StartsAt) {
+		return "Dates invalides : ends_at doit être postérieure à starts_at"
+	}
+	if len(in.Targets) == 0 {
+		return "Au moins une cible (produit, catégorie ou sous-catégorie) est requise"
+	}
+	for _, t := range in.Targets {
+		if t.TargetType != "product" && t.TargetType != "category" && t.TargetType != "subcategory" {
+			return "target_type doit valoir \"product\", \"category\" ou \"subcategory\""
+		}
+		if t.TargetID <= 0 {
+			return "target_id invalide"
+		}
+	}
+	return ""
+}
+
+// insertPromotionTargets remplace les cibles d'une campagne (dans la transaction appelante)
+func insertPromotionTargets(tx *sql.Tx, promotionID int, targets []models.PromotionTarget) error {
+	if _, err := tx.Exec("DELETE FROM promotion_targets WHERE promotion_id = $1", promotionID); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		_, err := tx.Exec(
+			`INSERT INTO promotion_targets (promotion_id, target_type, target_id) VALUES ($1, $2, $3)
+             ON CONFLICT (promotion_id, target_type, target_id) DO NOTHING`,
+			promotionID, t.TargetType, t.TargetID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /promotions?status=active|scheduled|expired|disabled — Liste des campagnes [ADMIN]
+func (h *PromotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := `
+        SELECT id, name, discount_type, discount_value, starts_at, ends_at, is_active, created_at, status
+        FROM (SELECT *, ` + promotionStatusSQL + ` AS status FROM promotions) p`
+	args := []interface{}{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY starts_at DESC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetPromotions : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des promotions"})
+		return
+	}
+	defer rows.Close()
+
+	promotions := make([]models.Promotion, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var p models.Promotion
+		if err := rows.Scan(&p.ID, &p.Name, &p.DiscountType, &p.DiscountValue, &p.StartsAt, &p.EndsAt, &p.IsActive, &p.CreatedAt, &p.Status); err != nil {
+			fmt.Printf("Erreur Scan promotions : %v\n", err)
+			continue
+		}
+		p.Targets = make([]models.PromotionTarget, 0)
+		index[p.ID] = len(promotions)
+		promotions = append(promotions, p)
+	}
+
+	// Cibles de toutes les campagnes en une seule requête
+	targetRows, err := h.DB.Query("SELECT promotion_id, target_type, target_id FROM promotion_targets ORDER BY id ASC")
+	if err != nil {
+		fmt.Printf("Erreur BDD GetPromotions cibles : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des promotions"})
+		return
+	}
+	defer targetRows.Close()
+
+	for targetRows.Next() {
+		var promotionID int
+		var t models.PromotionTarget
+		if err := targetRows.Scan(&promotionID, &t.TargetType, &t.TargetID); err != nil {
+			continue
+		}
+		if pos, ok := index[promotionID]; ok {
+			promotions[pos].Targets = append(promotions[pos].Targets, t)
+		}
+	}
+
+	json.NewEncoder(w).Encode(promotions)
+}
+
+// POST /promotions — Programmer une campagne [ADMIN]
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in models.PromotionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validatePromotionInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Dates converties en UTC : la colonne TIMESTAMP ignore le fuseau fourni
+	var id int
+	err = tx.QueryRow(
+		`INSERT INTO promotions (name, discount_type, discount_value, starts_at, ends_at, is_active)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		strings.TrimSpace(in.Name), in.DiscountType, in.DiscountValue, in.StartsAt.UTC(), in.EndsAt.UTC(), isActive,
+	).Scan(&id)
+	if err == nil {
+		err = insertPromotionTargets(tx, id, in.Targets)
+	}
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD CreatePromotion : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la création de la promotion"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur validation transaction"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Promotion programmée"})
+}
+
+// PUT /promotions/{id} — Modifier une campagne et ses cibles [ADMIN]
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/promotions/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var in models.PromotionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validatePromotionInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	res, err := tx.Exec(
+		`UPDATE promotions SET name=$1, discount_type=$2, discount_value=$3, starts_at=$4, ends_at=$5, is_active=$6 WHERE id=$7`,
+		strings.TrimSpace(in.Name), in.DiscountType, in.DiscountValue, in.StartsAt.UTC(), in.EndsAt.UTC(), isActive, id,
+	)
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD UpdatePromotion id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification de la promotion"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		tx.Rollback()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Promotion introuvable"})
+		return
+	}
+
+	if err := insertPromotionTargets(tx, id, in.Targets); err != nil {
+		tx.Rollback()
+		fmt.Printf("Erreur BDD UpdatePromotion cibles id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification de la promotion"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur validation transaction"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Promotion mise à jour avec succès"})
+}
+
+// DELETE /promotions/{id} — Supprimer une campagne [ADMIN]
+// Les commandes passées gardent promotion_name (promotion_id passe à NULL).
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/promotions/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		fmt.Printf("Erreur BDD DeletePromotion id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la suppression"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Promotion introuvable"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Promotion supprimée"})
+}
@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type RegistryHandler struct {
+	DB *sql.DB
+}
+
+const registryColumns = `id, user_id, title, to_char(due_date, 'YYYY-MM-DD'), COALESCE(message, ''),
+        public_token, is_active, created_at, updated_at`
+
+func scanRegistry(row interface{ Scan(...interface{}) error }) (models.Registry, error) {
+	var reg models.Registry
+	err := row.Scan(&reg.ID, &reg.UserID, &reg.Title, &reg.DueDate, &reg.Message,
+		&reg.PublicToken, &reg.IsActive, &reg.CreatedAt, &reg.UpdatedAt)
+	return reg, err
+}
+
+func newRegistryToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// registrySubPath découpe /registries/{id}[/items[/{item_id}]]
+func registrySubPath(path string) (int, int, error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/registries/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("chemin invalide")
+	}
+	if len(parts) == 3 && parts[1] == "items" {
+		itemID, err := strconv.Atoi(parts[2])
+		return id, itemID, err
+	}
+	return id, 0, nil
+}
+
+// loadRegistryItems lit les produits de la liste avec les prix du moment.
+// withPurchases ajoute le détail des achats (vue propriétaire uniquement).
+func loadRegistryItems(q queryer, registryID int, withPurchases bool) ([]models.RegistryItem, error) {
+	promos, err := loadActivePromotions(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+        SELECT ri.id, ri.desired_quantity, ri.purchased_quantity, COALESCE(ri.note, ''),
+               p.id, p.name, COALESCE(p.image_url, ''), p.price, p.stock_quantity,
+               p.category_id, p.subcategory_id, p.promotion_percent
+        FROM registry_items ri
+        JOIN products p ON p.id = ri.product_id
+        WHERE ri.registry_id = $1
+        ORDER BY ri.created_at, ri.id`, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.RegistryItem, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var it models.RegistryItem
+		var p models.Product
+		if err := rows.Scan(&it.ID, &it.DesiredQuantity, &it.PurchasedQuantity, &it.Note,
+			&p.ID, &p.Name, &p.ImageURL, &p.Price, &p.StockQuantity,
+			&p.CategoryID, &p.SubcategoryID, &p.PromotionPercent); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		resolvePrice(&p, promos)
+		it.ProductID = p.ID
+		it.ProductName = p.Name
+		it.ImageURL = p.ImageURL
+		it.Price = p.Price
+		it.FinalPrice = p.FinalPrice
+		it.AppliedPromotion = p.AppliedPromotion
+		it.StockQuantity = p.StockQuantity
+		it.Remaining = it.DesiredQuantity - it.PurchasedQuantity
+		if it.Remaining < 0 {
+			it.Remaining = 0
+		}
+		index[it.ID] = len(items)
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !withPurchases || len(items) == 0 {
+		return items, nil
+	}
+
+	rows, err = q.Query(`
+        SELECT oi.registry_item_id, o.id, o.customer_firstname || ' ' || o.customer_lastname,
+               oi.quantity - oi.cancelled_quantity, o.created_at
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        JOIN registry_items ri ON ri.id = oi.registry_item_id
+        WHERE ri.registry_id = $1 AND oi.quantity > oi.cancelled_quantity AND o.status <> 'annulé'
+        ORDER BY o.created_at`, registryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID int
+		var pu models.RegistryPurchase
+		if err := rows.Scan(&itemID, &pu.OrderID, &pu.BuyerName, &pu.Quantity, &pu.CreatedAt); err != nil {
+			return nil, err
+		}
+		if pos, ok := index[itemID]; ok {
+			items[pos].Purchases = append(items[pos].Purchases, pu)
+		}
+	}
+	return items, rows.Err()
+}
+
+// ownedRegistry lit la liste en vérifiant qu'elle appartient à l'utilisateur connecté.
+// En cas d'échec, la réponse JSON est déjà écrite.
+func (h *RegistryHandler) ownedRegistry(w http.ResponseWriter, r *http.Request, id int) (*models.Registry, bool) {
+	reg, err := scanRegistry(h.DB.QueryRow("SELECT "+registryColumns+" FROM registries WHERE id = $1", id))
+	if err == sql.ErrNoRows || (err == nil && reg.UserID != middleware.UserID(r)) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Liste introuvable"})
+		return nil, false
+	} else if err != nil {
+		fmt.Printf("Erreur BDD registry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return nil, false
+	}
+	return &reg, true
+}
+
+func validateRegistryInput(input *models.RegistryInput) string {
+	input.Title = strings.TrimSpace(input.Title)
+	if input.Title == "" {
+		return "Le titre de la liste est obligatoire"
+	}
+	if input.DueDate != nil && *input.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", *input.DueDate); err != nil {
+			return "Date prévue invalide (AAAA-MM-JJ)"
+		}
+	} else {
+		input.DueDate = nil
+	}
+	return ""
+}
+
+// GET /registries — Mes listes de naissance [AUTH]
+func (h *RegistryHandler) GetMyRegistries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := h.DB.Query("SELECT "+registryColumns+" FROM registries WHERE user_id = $1 ORDER BY created_at DESC", middleware.UserID(r))
+	if err != nil {
+		fmt.Printf("Erreur BDD GetMyRegistries : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	registries := make([]models.Registry, 0)
+	for rows.Next() {
+		reg, err := scanRegistry(rows)
+		if err != nil {
+			continue
+		}
+		reg.Items = make([]models.RegistryItem, 0)
+		registries = append(registries, reg)
+	}
+	json.NewEncoder(w).Encode(registries)
+}
+
+// POST /registries — Créer une liste [AUTH]
+func (h *RegistryHandler) CreateRegistry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.RegistryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateRegistryInput(&input); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := input.IsActive == nil || *input.IsActive
+
+	token, err := newRegistryToken()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	reg, err := scanRegistry(h.DB.QueryRow(`
+        INSERT INTO registries (user_id, title, due_date, message, public_token, is_active)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+        RETURNING `+registryColumns,
+		middleware.UserID(r), input.Title, input.DueDate, input.Message, token, isActive))
+	if err != nil {
+		fmt.Printf("Erreur BDD CreateRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur création liste"})
+		return
+	}
+	reg.Items = make([]models.RegistryItem, 0)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reg)
+}
+
+// GET /registries/{id} — Détail d'une de mes listes, avec les achats [AUTH]
+func (h *RegistryHandler) GetRegistry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, _, err := registrySubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	reg, ok := h.ownedRegistry(w, r, id)
+	if !ok {
+		return
+	}
+
+	if reg.Items, err = loadRegistryItems(h.DB, reg.ID, true); err != nil {
+		fmt.Printf("Erreur BDD GetRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	json.NewEncoder(w).Encode(reg)
+}
+
+// PUT /registries/{id} — Modifier une liste [AUTH]
+func (h *RegistryHandler) UpdateRegistry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, _, err := registrySubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.RegistryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateRegistryInput(&input); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	current, ok := h.ownedRegistry(w, r, id)
+	if !ok {
+		return
+	}
+	isActive := current.IsActive
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	reg, err := scanRegistry(h.DB.QueryRow(`
+        UPDATE registries SET title = $1, due_date = $2, message = NULLIF($3, ''), is_active = $4, updated_at = NOW()
+        WHERE id = $5
+        RETURNING `+registryColumns,
+		input.Title, input.DueDate, input.Message, isActive, id))
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur mise à jour liste"})
+		return
+	}
+	reg.Items = make([]models.RegistryItem, 0)
+	json.NewEncoder(w).Encode(reg)
+}
+
+// DELETE /registries/{id} — Supprimer une liste [AUTH]
+// Les commandes passées depuis la liste sont conservées (registry_item_id passe à NULL).
+func (h *RegistryHandler) DeleteRegistry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, _, err := registrySubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	if _, ok := h.ownedRegistry(w, r, id); !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM registries WHERE id = $1", id); err != nil {
+		fmt.Printf("Erreur BDD DeleteRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur suppression liste"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Liste supprimée"})
+}
+
+// POST /registries/{id}/items — Ajouter un produit à la liste [AUTH]
+// Un produit déjà présent voit sa quantité souhaitée remplacée.
+func (h *RegistryHandler) AddRegistryItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, _, err := registrySubPath(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.RegistryItemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.ProductID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if input.DesiredQuantity == 0 {
+		input.DesiredQuantity = 1
+	}
+	if input.DesiredQuantity < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Quantité souhaitée invalide"})
+		return
+	}
+	if _, ok := h.ownedRegistry(w, r, id); !ok {
+		return
+	}
+
+	var exists bool
+	if err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND archived_at IS NULL)", input.ProductID).Scan(&exists); err != nil || !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	}
+
+	_, err = h.DB.Exec(`
+        INSERT INTO registry_items (registry_id, product_id, desired_quantity, note)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
+        ON CONFLICT (registry_id, product_id)
+        DO UPDATE SET desired_quantity = EXCLUDED.desired_quantity, note = EXCLUDED.note`,
+		id, input.ProductID, input.DesiredQuantity, strings.TrimSpace(input.Note))
+	if err == nil {
+		_, err = h.DB.Exec("UPDATE registries SET updated_at = NOW() WHERE id = $1", id)
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD AddRegistryItem : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur ajout produit"})
+		return
+	}
+
+	h.GetRegistry(w, r)
+}
+
+// PUT /registries/{id}/items/{item_id} — Modifier quantité souhaitée / note [AUTH]
+func (h *RegistryHandler) UpdateRegistryItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, itemID, err := registrySubPath(r.URL.Path)
+	if err != nil || itemID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.RegistryItemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.DesiredQuantity <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Quantité souhaitée invalide"})
+		return
+	}
+	if _, ok := h.ownedRegistry(w, r, id); !ok {
+		return
+	}
+
+	res, err := h.DB.Exec(
+		"UPDATE registry_items SET desired_quantity = $1, note = NULLIF($2, '') WHERE id = $3 AND registry_id = $4",
+		input.DesiredQuantity, strings.TrimSpace(input.Note), itemID, id)
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateRegistryItem : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur mise à jour produit"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit absent de la liste"})
+		return
+	}
+
+	r.URL.Path = fmt.Sprintf("/registries/%d", id)
+	h.GetRegistry(w, r)
+}
+
+// DELETE /registries/{id}/items/{item_id} — Retirer un produit de la liste [AUTH]
+func (h *RegistryHandler) DeleteRegistryItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, itemID, err := registrySubPath(r.URL.Path)
+	if err != nil || itemID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	if _, ok := h.ownedRegistry(w, r, id); !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM registry_items WHERE id = $1 AND registry_id = $2", itemID, id); err != nil {
+		fmt.Printf("Erreur BDD DeleteRegistryItem : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur suppression produit"})
+		return
+	}
+
+	r.URL.Path = fmt.Sprintf("/registries/%d", id)
+	h.GetRegistry(w, r)
+}
+
+// GET /registries/public/{token} — Liste partagée, pour les proches (public)
+func (h *RegistryHandler) GetPublicRegistry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token := strings.TrimPrefix(r.URL.Path, "/registries/public/")
+	var id int
+	var ownerName string
+	var pub models.PublicRegistry
+	err := h.DB.QueryRow(`
+        SELECT r.id, r.title, to_char(r.due_date, 'YYYY-MM-DD'), COALESCE(r.message, ''), COALESCE(u.full_name, '')
+        FROM registries r
+        JOIN users u ON u.id = r.user_id
+        WHERE r.public_token = $1 AND r.is_active`, token,
+	).Scan(&id, &pub.Title, &pub.DueDate, &pub.Message, &ownerName)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Liste introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD GetPublicRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	// Seul le prénom est partagé
+	pub.OwnerName = strings.SplitN(strings.TrimSpace(ownerName), " ", 2)[0]
+
+	if pub.Items, err = loadRegistryItems(h.DB, id, false); err != nil {
+		fmt.Printf("Erreur BDD GetPublicRegistry : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	json.NewEncoder(w).Encode(pub)
+}
+
+// recordRegistryPurchases comptabilise les articles achetés depuis une liste, dans la
+// transaction de commande. L'UPDATE conditionnel empêche d'offrir plus que souhaité,
+// même avec des commandes simultanées.
+func recordRegistryPurchases(tx *sql.Tx, lines []orderLine) error {
+	for _, l := range lines {
+		if l.RegistryItemID == nil {
+			continue
+		}
+		var remaining int
+		err := tx.QueryRow(`
+            SELECT ri.desired_quantity - ri.purchased_quantity
+            FROM registry_items ri
+            JOIN registries r ON r.id = ri.registry_id
+            WHERE ri.id = $1 AND ri.product_id = $2 AND r.is_active
+            FOR UPDATE OF ri`, *l.RegistryItemID, l.Product.ID).Scan(&remaining)
+		if err == sql.ErrNoRows {
+			return newOrderError(http.StatusBadRequest, "Article de liste de naissance introuvable pour %s", l.Product.Name)
+		} else if err != nil {
+			return err
+		}
+		if l.Quantity > remaining {
+			return newOrderError(http.StatusConflict, "%s a déjà été offert (%d restant(s) sur la liste)", l.Product.Name, max(remaining, 0))
+		}
+		if _, err := tx.Exec(
+			"UPDATE registry_items SET purchased_quantity = purchased_quantity + $1 WHERE id = $2",
+			l.Quantity, *l.RegistryItemID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseRegistryPurchase décompte un article de liste annulé
+func releaseRegistryPurchase(tx *sql.Tx, registryItemID, quantity int) error {
+	_, err := tx.Exec(
+		"UPDATE registry_items SET purchased_quantity = GREATEST(purchased_quantity - $1, 0) WHERE id = $2",
+		quantity, registryItemID)
+	return err
+}
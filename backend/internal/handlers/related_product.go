@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/models"
+)
+
+type RelatedProductHandler struct {
+	DB *sql.DB
+}
+
+const relatedProductColumns = `p.id, p.name, COALESCE(p.sku, ''), p.description, p.price, ` + productStockColumn + `, p.image_url,
+               p.category_id, p.subcategory_id, p.promotion_percent, p.is_bundle, ` + productPreorderColumns + `, ` + productRatingColumns
+
+// loadRelatedProducts lit une liste de produits proposés ; la requête renvoie
+// relatedProductColumns suivies de la source et du nombre de commandes communes
+func loadRelatedProducts(q queryer, query string, args ...interface{}) ([]models.RelatedProduct, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.RelatedProduct, 0)
+	for rows.Next() {
+		var rp models.RelatedProduct
+		p := &rp.Product
+		if err := rows.Scan(&p.ID, &p.Name, &p.SKU, &p.Description, &p.Price, &p.StockQuantity, &p.ImageURL, &p.CategoryID, &p.SubcategoryID, &p.PromotionPercent,
+			&p.IsBundle, &p.PreorderEnabled, &p.PreorderAvailableAt, &p.PreorderCap, &p.RatingAverage, &p.RatingCount, &rp.Source, &rp.OrderCount); err != nil {
+			return nil, err
+		}
+		products = append(products, rp)
+	}
+	return products, rows.Err()
+}
+
+// productRelatedID extrait l'ID produit de /products/{id}/related
+func productRelatedID(path string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/products/"), "/related"))
+}
+
+// GET /products/{id}/related?limit=8 — Produits associés et "souvent achetés ensemble"
+// related : liens de l'admin d'abord, complétés par la même sous-catégorie (en stock)
+// frequently_bought_together : calculé par jobs.CoPurchaseJob (en stock)
+func (h *RelatedProductHandler) GetRelatedProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := productRelatedID(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	limit := 8
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+
+	var subcategoryID *int
+	err = h.DB.QueryRow("SELECT subcategory_id FROM products WHERE id = $1", id).Scan(&subcategoryID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD GetRelatedProducts id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Liens manuels : affichés même en rupture, l'admin les a choisis
+	related, err := loadRelatedProducts(h.DB, `
+        SELECT `+relatedProductColumns+`, 'manual', 0
+        FROM product_links l
+        JOIN products p ON p.id = l.related_product_id `+productRatingJoin+`
+        WHERE l.product_id = $1 AND p.archived_at IS NULL
+        ORDER BY l.position, p.id
+        LIMIT $2`, id, limit)
+	if err == nil && len(related) < limit && subcategoryID != nil {
+		var same []models.RelatedProduct
+		same, err = loadRelatedProducts(h.DB, `
+            SELECT `+relatedProductColumns+`, 'subcategory', 0
+            FROM products p `+productRatingJoin+`
+            WHERE p.subcategory_id = $2 AND p.id <> $1 AND p.archived_at IS NULL AND `+productStockColumn+` > 0
+              AND NOT EXISTS (SELECT 1 FROM product_links l WHERE l.product_id = $1 AND l.related_product_id = p.id)
+            ORDER BY COALESCE(rs.rating_count, 0) DESC, p.id
+            LIMIT $3`, id, *subcategoryID, limit-len(related))
+		related = append(related, same...)
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD GetRelatedProducts id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	together, err := loadRelatedProducts(h.DB, `
+        SELECT `+relatedProductColumns+`, '', c.order_count
+        FROM product_copurchases c
+        JOIN products p ON p.id = c.related_product_id `+productRatingJoin+`
+        WHERE c.product_id = $1 AND p.archived_at IS NULL AND `+productStockColumn+` > 0
+        ORDER BY c.order_count DESC, c.confidence DESC, p.id
+        LIMIT $2`, id, limit)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetRelatedProducts id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+
+	// Prix du moment, comme le catalogue
+	promos, err := loadActivePromotions(h.DB)
+	if err != nil {
+		fmt.Printf("Erreur BDD promotions GetRelatedProducts id=%d : %v\n", id, err)
+	}
+	for i := range related {
+		resolvePrice(&related[i].Product, promos)
+	}
+	for i := range together {
+		resolvePrice(&together[i].Product, promos)
+	}
+
+	json.NewEncoder(w).Encode(models.RelatedProducts{Related: related, FrequentlyBoughtTogether: together})
+}
+
+// PUT /products/{id}/related — Remplacer les produits associés manuels [ADMIN]
+// L'ordre de product_ids est l'ordre d'affichage ; une liste vide supprime les liens.
+func (h *RelatedProductHandler) SetProductLinks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := productRelatedID(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.ProductLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	seen := make(map[int]bool, len(req.ProductIDs))
+	for _, relatedID := range req.ProductIDs {
+		if relatedID == id || seen[relatedID] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Produit associé invalide ou en double : %d", relatedID)})
+			return
+		}
+		seen[relatedID] = true
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		fmt.Printf("Erreur BDD SetProductLinks : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", id).Scan(&exists); err != nil || !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM product_links WHERE product_id = $1", id); err != nil {
+		fmt.Printf("Erreur BDD SetProductLinks : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement liens"})
+		return
+	}
+	for position, relatedID := range req.ProductIDs {
+		res, err := tx.Exec(`
+            INSERT INTO product_links (product_id, related_product_id, position)
+            SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM products WHERE id = $2)`,
+			id, relatedID, position)
+		if err != nil {
+			fmt.Printf("Erreur BDD SetProductLinks : %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement liens"})
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Produit associé introuvable : %d", relatedID)})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Erreur BDD SetProductLinks : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement liens"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Produits associés enregistrés",
+		"product_ids": req.ProductIDs,
+	})
+}
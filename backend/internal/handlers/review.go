@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+type ReviewHandler struct {
+	DB *sql.DB
+}
+
+// Nombre maximal de photos par avis
+const maxReviewPhotos = 4
+
+const reviewColumns = `r.id, r.product_id, p.name, r.user_id, COALESCE(u.full_name, ''), r.rating,
+        COALESCE(r.title, ''), COALESCE(r.comment, ''), r.photo_urls, r.verified_purchase, r.status,
+        COALESCE(r.moderation_note, ''), r.moderated_at, r.created_at`
+
+const reviewJoins = `
+        FROM reviews r
+        JOIN products p ON p.id = r.product_id
+        JOIN users u ON u.id = r.user_id`
+
+func scanReview(row interface{ Scan(...interface{}) error }) (models.Review, error) {
+	var rv models.Review
+	var fullName string
+	err := row.Scan(&rv.ID, &rv.ProductID, &rv.ProductName, &rv.UserID, &fullName, &rv.Rating,
+		&rv.Title, &rv.Comment, pq.Array(&rv.PhotoURLs), &rv.VerifiedPurchase, &rv.Status,
+		&rv.ModerationNote, &rv.ModeratedAt, &rv.CreatedAt)
+	rv.AuthorName = reviewAuthorName(fullName)
+	if rv.PhotoURLs == nil {
+		rv.PhotoURLs = make([]string, 0)
+	}
+	return rv, err
+}
+
+// reviewAuthorName affiche "Marie K." : le nom complet n'est jamais public
+func reviewAuthorName(fullName string) string {
+	parts := strings.Fields(fullName)
+	if len(parts) == 0 {
+		return "Client"
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[0] + " " + strings.ToUpper(string([]rune(parts[len(parts)-1])[0])) + "."
+}
+
+// hasPurchased indique si le client a acheté le produit (commande non annulée, non remboursée
+// et payée ou en paiement à la livraison, ligne non entièrement annulée)
+func hasPurchased(q queryer, userID, productID int) (bool, error) {
+	var verified bool
+	err := q.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM order_items oi
+            JOIN orders o ON o.id = oi.order_id
+            JOIN users u ON u.email = o.customer_email
+            WHERE u.id = $1 AND oi.product_id = $2
+              AND oi.quantity > oi.cancelled_quantity
+              AND o.status NOT IN ('annulé', 'pending_payment', 'payment_failed', 'refunded')
+        )`, userID, productID).Scan(&verified)
+	return verified, err
+}
+
+// productReviewsID extrait l'ID produit de /products/{id}/reviews
+func productReviewsID(path string) (int, error) {
+	return strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/products/"), "/reviews"))
+}
+
+// GET /products/{id}/reviews?rating=5 — Avis approuvés d'un produit
+func (h *ReviewHandler) GetProductReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := productReviewsID(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	query := "SELECT " + reviewColumns + reviewJoins + " WHERE r.product_id = $1 AND r.status = 'approved'"
+	args := []interface{}{productID}
+	if rating, err := strconv.Atoi(r.URL.Query().Get("rating")); err == nil && rating >= 1 && rating <= 5 {
+		query += " AND r.rating = $2"
+		args = append(args, rating)
+	}
+	query += " ORDER BY r.verified_purchase DESC, r.created_at DESC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetProductReviews : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	reviews := make([]models.Review, 0)
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			fmt.Printf("Erreur scan review : %v\n", err)
+			continue
+		}
+		rv.ProductName = ""
+		reviews = append(reviews, rv)
+	}
+
+	// Répartition des notes (1 à 5 étoiles) pour l'histogramme de la fiche produit
+	distribution := map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}
+	var average float64
+	var count int
+	distRows, err := h.DB.Query(
+		"SELECT rating, COUNT(*) FROM reviews WHERE product_id = $1 AND status = 'approved' GROUP BY rating", productID)
+	if err != nil {
+		fmt.Printf("Erreur BDD distribution avis : %v\n", err)
+	} else {
+		defer distRows.Close()
+		var sum int
+		for distRows.Next() {
+			var rating, n int
+			if distRows.Scan(&rating, &n) == nil {
+				distribution[strconv.Itoa(rating)] = n
+				sum += rating * n
+				count += n
+			}
+		}
+		if count > 0 {
+			average = math.Round(float64(sum)/float64(count)*10) / 10
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rating_average": average,
+		"rating_count":   count,
+		"distribution":   distribution,
+		"reviews":        reviews,
+	})
+}
+
+// POST /products/{id}/reviews — Publier un avis [AUTH]
+// L'avis est en attente de modération ; un seul avis par client et par produit.
+func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := productReviewsID(r.URL.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.ReviewInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if input.Rating < 1 || input.Rating > 5 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "La note doit être comprise entre 1 et 5"})
+		return
+	}
+	if len(input.PhotoURLs) > maxReviewPhotos {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("%d photos maximum", maxReviewPhotos)})
+		return
+	}
+	// Seules les photos envoyées via POST /reviews/photos sont acceptées
+	photoPrefix := utils.PublicS3URL("reviews/")
+	for _, u := range input.PhotoURLs {
+		if !strings.HasPrefix(u, photoPrefix) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Photo invalide : utiliser POST /reviews/photos"})
+			return
+		}
+	}
+	if input.PhotoURLs == nil {
+		input.PhotoURLs = make([]string, 0)
+	}
+
+	userID := middleware.UserID(r)
+	var exists bool
+	if err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists); err != nil || !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	}
+
+	verified, err := hasPurchased(h.DB, userID, productID)
+	if err != nil {
+		fmt.Printf("Erreur BDD hasPurchased : %v\n", err)
+	}
+
+	var id int
+	err = h.DB.QueryRow(`
+        INSERT INTO reviews (product_id, user_id, rating, title, comment, photo_urls, verified_purchase)
+        VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+        ON CONFLICT (product_id, user_id) DO NOTHING
+        RETURNING id`,
+		productID, userID, input.Rating, strings.TrimSpace(input.Title), strings.TrimSpace(input.Comment),
+		pq.Array(input.PhotoURLs), verified,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Vous avez déjà donné votre avis sur ce produit"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD CreateReview : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement avis"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":           "Merci ! Votre avis sera publié après validation",
+		"id":                id,
+		"status":            "pending",
+		"verified_purchase": verified,
+	})
+}
+
+// POST /reviews/photos — Envoyer une photo d'avis sur S3 (multipart, champ "file") [AUTH]
+func (h *ReviewHandler) UploadReviewPhoto(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	r.Body = http.MaxBytesReader(w, r.Body, 6<<20)
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Fichier trop volumineux (5 Mo maximum)"})
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Fichier invalide ou absent"})
+		return
+	}
+	defer file.Close()
+
+	if !strings.HasPrefix(handler.Header.Get("Content-Type"), "image/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Seules les images sont acceptées"})
+		return
+	}
+
+	url, err := utils.UploadToS3Folder("reviews", file, handler)
+	if err != nil {
+		fmt.Printf("Erreur S3 upload review : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de l'upload vers S3"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"url": url})
+}
+
+// GET /reviews?status=pending&product_id=7 — Avis à modérer [ADMIN]
+func (h *ReviewHandler) GetReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := "SELECT " + reviewColumns + reviewJoins + " WHERE 1=1"
+	args := []interface{}{}
+	if status := q.Get("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND r.status = $%d", len(args))
+	}
+	if productID, err := strconv.Atoi(q.Get("product_id")); err == nil {
+		args = append(args, productID)
+		query += fmt.Sprintf(" AND r.product_id = $%d", len(args))
+	}
+	query += " ORDER BY r.created_at DESC"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetReviews : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	reviews := make([]models.Review, 0)
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			fmt.Printf("Erreur scan review : %v\n", err)
+			continue
+		}
+		reviews = append(reviews, rv)
+	}
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// PUT /reviews/{id}/moderate — Approuver ou rejeter un avis [ADMIN]
+func (h *ReviewHandler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/reviews/"), "/moderate"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var req models.ModerateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Status != "approved" && req.Status != "rejected") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Statut invalide (approved ou rejected)"})
+		return
+	}
+
+	res, err := h.DB.Exec(`
+        UPDATE reviews SET status = $1, moderation_note = NULLIF($2, ''), moderated_by = $3,
+               moderated_at = NOW(), updated_at = NOW()
+        WHERE id = $4`,
+		req.Status, strings.TrimSpace(req.Note), middleware.UserID(r), id)
+	if err != nil {
+		fmt.Printf("Erreur BDD ModerateReview : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur modération"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Avis introuvable"})
+		return
+	}
+
+	rv, err := scanReview(h.DB.QueryRow("SELECT "+reviewColumns+reviewJoins+" WHERE r.id = $1", id))
+	if err != nil {
+		fmt.Printf("Erreur BDD ModerateReview : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	json.NewEncoder(w).Encode(rv)
+}
+
+// DELETE /reviews/{id} — Supprimer un avis [ADMIN]
+func (h *ReviewHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/reviews/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM reviews WHERE id = $1", id)
+	if err != nil {
+		fmt.Printf("Erreur BDD DeleteReview : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur suppression avis"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Avis introuvable"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Avis supprimé"})
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type ShippingHandler struct {
+	DB *sql.DB
+}
+
+// shippingQuote est le résultat du calcul des frais de port d'une commande
+type shippingQuote struct {
+	Zone         *models.ShippingZone
+	Fee          float64
+	FreeShipping bool // true si les frais ont été offerts (seuil de zone ou code promo)
+}
+
+const shippingZoneColumns = `id, name, city, commune, fee, free_shipping_threshold, is_active, created_at`
+
+func scanShippingZone(row interface{ Scan(...interface{}) error }) (models.ShippingZone, error) {
+	var z models.ShippingZone
+	err := row.Scan(&z.ID, &z.Name, &z.City, &z.Commune, &z.Fee, &z.FreeShippingThreshold, &z.IsActive, &z.CreatedAt)
+	return z, err
+}
+
+// findShippingZone cherche la zone active de la commune, puis à défaut celle de la ville entière
+func findShippingZone(q queryer, city, commune string) (*models.ShippingZone, error) {
+	row := q.QueryRow(`
+        SELECT `+shippingZoneColumns+`
+        FROM shipping_zones
+        WHERE is_active
+          AND LOWER(city) = LOWER($1)
+          AND (commune IS NULL OR LOWER(commune) = LOWER($2))
+        ORDER BY commune IS NULL ASC
+        LIMIT 1`,
+		strings.TrimSpace(city), strings.TrimSpace(commune),
+	)
+	z, err := scanShippingZone(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+// quoteShipping calcule les frais de port.
+// basket est le montant des articles après remise : c'est lui qui déclenche le seuil de gratuité.
+func quoteShipping(q queryer, method, city, commune string, basket float64, couponFreeShipping bool) (*shippingQuote, error) {
+	switch method {
+	case "pickup":
+		// Retrait magasin : jamais de frais
+		return &shippingQuote{}, nil
+	case "shipping":
+	default:
+		return nil, newOrderError(http.StatusBadRequest, "Mode de livraison invalide (shipping ou pickup)")
+	}
+
+	if strings.TrimSpace(city) == "" {
+		return nil, newOrderError(http.StatusBadRequest, "La ville de livraison est requise")
+	}
+
+	zone, err := findShippingZone(q, city, commune)
+	if err != nil {
+		return nil, err
+	}
+	if zone == nil {
+		return nil, newOrderError(http.StatusBadRequest, "Livraison non disponible pour %s", strings.TrimSpace(strings.Join([]string{commune, city}, " ")))
+	}
+
+	quote := &shippingQuote{Zone: zone, Fee: zone.Fee}
+	if couponFreeShipping || (zone.FreeShippingThreshold != nil && basket >= *zone.FreeShippingThreshold) {
+		quote.Fee = 0
+		quote.FreeShipping = zone.Fee > 0
+	}
+	return quote, nil
+}
+
+// POST /shipping/quote — Détail du total au checkout (public)
+// Même calcul que CreateOrder : sous-total, remise, frais de port, total.
+func (h *ShippingHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.ShippingQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	totals, err := computeCheckout(h.DB, checkoutInput{
+		Items:           req.Items,
+		CouponCode:      req.CouponCode,
+		Email:           req.Email,
+		DeliveryMethod:  req.DeliveryMethod,
+		ShippingCity:    req.ShippingCity,
+		ShippingCommune: req.ShippingCommune,
+	}, false)
+	if err != nil {
+		writeOrderError(w, "ShippingQuote", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"zone":            totals.Shipping.Zone,
+		"shipping_fee":    totals.Shipping.Fee,
+		"free_shipping":   totals.Shipping.FreeShipping,
+		"subtotal":        totals.Subtotal,
+		"discount_amount": totals.Discount,
+		"total":           totals.Total,
+	})
+}
+
+// GET /shipping-zones — Zones actives (public, pour le formulaire de checkout)
+// GET /shipping-zones?all=true — Toutes les zones, y compris désactivées [ADMIN]
+func (h *ShippingHandler) GetZones(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := "SELECT " + shippingZoneColumns + " FROM shipping_zones"
+	if r.URL.Query().Get("all") != "true" {
+		query += " WHERE is_active"
+	}
+	query += " ORDER BY city ASC, commune ASC NULLS FIRST"
+
+	rows, err := h.DB.Query(query)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetZones : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des zones"})
+		return
+	}
+	defer rows.Close()
+
+	zones := make([]models.ShippingZone, 0)
+	for rows.Next() {
+		z, err := scanShippingZone(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan shipping_zones : %v\n", err)
+			continue
+		}
+		zones = append(zones, z)
+	}
+
+	json.NewEncoder(w).Encode(zones)
+}
+
+// validateShippingZoneInput renvoie un message d'erreur en français, ou "" si la saisie est valide
+func validateShippingZoneInput(in *models.ShippingZoneInput) string {
+	in.Name = strings.TrimSpace(in.Name)
+	in.City = strings.TrimSpace(in.City)
+	if in.Commune != nil {
+		commune := strings.TrimSpace(*in.Commune)
+		if commune == "" {
+			in.Commune = nil
+		} else {
+			in.Commune = &commune
+		}
+	}
+	if in.Name == "" || in.City == "" {
+		return "Le nom et la ville de la zone sont requis"
+	}
+	if in.Fee < 0 || (in.FreeShippingThreshold != nil && *in.FreeShippingThreshold < 0) {
+		return "Les montants ne peuvent pas être négatifs"
+	}
+	return ""
+}
+
+// POST /shipping-zones — Créer une zone [ADMIN]
+func (h *ShippingHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var in models.ShippingZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateShippingZoneInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	var id int
+	err := h.DB.QueryRow(
+		`INSERT INTO shipping_zones (name, city, commune, fee, free_shipping_threshold, is_active)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		in.Name, in.City, in.Commune, in.Fee, in.FreeShippingThreshold, isActive,
+	).Scan(&id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Une zone existe déjà pour cette ville / commune"})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD CreateZone : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la création de la zone"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Zone de livraison créée"})
+}
+
+// PUT /shipping-zones/{id} — Modifier une zone [ADMIN]
+func (h *ShippingHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/shipping-zones/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var in models.ShippingZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	if msg := validateShippingZoneInput(&in); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+		return
+	}
+	isActive := in.IsActive == nil || *in.IsActive
+
+	res, err := h.DB.Exec(
+		`UPDATE shipping_zones SET name=$1, city=$2, commune=$3, fee=$4, free_shipping_threshold=$5, is_active=$6 WHERE id=$7`,
+		in.Name, in.City, in.Commune, in.Fee, in.FreeShippingThreshold, isActive, id,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Une zone existe déjà pour cette ville / commune"})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateZone id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la modification de la zone"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Zone introuvable"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Zone de livraison mise à jour avec succès"})
+}
+
+// DELETE /shipping-zones/{id} — Supprimer une zone [ADMIN]
+// Les commandes passées gardent leur shipping_fee (shipping_zone_id passe à NULL).
+func (h *ShippingHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/shipping-zones/"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM shipping_zones WHERE id = $1", id)
+	if err != nil {
+		fmt.Printf("Erreur BDD DeleteZone id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la suppression"})
+		return
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Zone introuvable"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Zone de livraison supprimée"})
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type StockHandler struct {
+	DB *sql.DB
+}
+
+// errInsufficientStock : le mouvement ferait passer le stock en négatif
+var errInsufficientStock = errors.New("stock insuffisant")
+
+// stockMovement décrit l'origine d'une variation de stock (voir migration 022)
+type stockMovement struct {
+	Type      string
+	OrderID   int // 0 si sans commande
+	ActorID   int // 0 pour un client ou le système
+	Reason    string
+	Reference string
+}
+
+// adjustStock est le seul point de modification de products.stock_quantity : la variation
+// et son mouvement sont écrits dans la même transaction. Renvoie le stock résultant et le
+// seuil d'alerte du produit ; errInsufficientStock si le stock deviendrait négatif
+// (ou si le produit n'existe pas, ou est un coffret : son stock est celui des composants).
+func adjustStock(tx *sql.Tx, productID, delta int, m stockMovement) (int, int, error) {
+	var stock, threshold int
+	err := tx.QueryRow(`
+        UPDATE products SET stock_quantity = stock_quantity + $1
+        WHERE id = $2 AND stock_quantity + $1 >= 0 AND NOT is_bundle
+        RETURNING stock_quantity, low_stock_threshold`,
+		delta, productID,
+	).Scan(&stock, &threshold)
+	if err == sql.ErrNoRows {
+		return 0, 0, errInsufficientStock
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO stock_movements (product_id, type, quantity_change, stock_after, order_id, actor_id, reason, reference)
+        VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, ''), NULLIF($8, ''))`,
+		productID, m.Type, delta, stock, m.OrderID, m.ActorID, m.Reason, m.Reference)
+	return stock, threshold, err
+}
+
+// writeStockError traduit les erreurs d'adjustStock pour les routes admin
+func writeStockError(w http.ResponseWriter, context string, productID int, err error) {
+	if err == errInsufficientStock {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Produit %d introuvable, coffret ou stock insuffisant", productID)})
+		return
+	}
+	fmt.Printf("Erreur BDD %s : %v\n", context, err)
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement mouvement de stock"})
+}
+
+// GET /stock/movements?product_id=1&type=restock&limit=100 — Journal des stocks [ADMIN]
+func (h *StockHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := `
+        SELECT m.id, m.product_id, p.name, m.type, m.quantity_change, m.stock_after, m.order_id, m.actor_id,
+               COALESCE(m.reason, ''), COALESCE(m.reference, ''), m.created_at
+        FROM stock_movements m
+        JOIN products p ON p.id = m.product_id
+        WHERE 1=1`
+	args := []interface{}{}
+	if productID, err := strconv.Atoi(q.Get("product_id")); err == nil {
+		args = append(args, productID)
+		query += fmt.Sprintf(" AND m.product_id = $%d", len(args))
+	}
+	if t := q.Get("type"); t != "" {
+		args = append(args, t)
+		query += fmt.Sprintf(" AND m.type = $%d", len(args))
+	}
+	limit := 100
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY m.created_at DESC, m.id DESC LIMIT $%d", len(args))
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetMovements : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	movements := make([]models.StockMovement, 0)
+	for rows.Next() {
+		var m models.StockMovement
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.ProductName, &m.Type, &m.QuantityChange, &m.StockAfter,
+			&m.OrderID, &m.ActorID, &m.Reason, &m.Reference, &m.CreatedAt); err != nil {
+			fmt.Printf("Erreur scan mouvement de stock : %v\n", err)
+			continue
+		}
+		movements = append(movements, m)
+	}
+	json.NewEncoder(w).Encode(movements)
+}
+
+// POST /stock/receptions — Réception fournisseur [ADMIN]
+// Toutes les lignes sont enregistrées ou aucune.
+func (h *StockHandler) CreateReception(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.StockReceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Items) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Au moins un article est requis"})
+		return
+	}
+	for _, it := range req.Items {
+		if it.Quantity <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Quantité invalide pour le produit %d", it.ProductID)})
+			return
+		}
+	}
+	reason := "Réception fournisseur"
+	if s := strings.TrimSpace(req.Supplier); s != "" {
+		reason += " " + s
+	}
+	if n := strings.TrimSpace(req.Note); n != "" {
+		reason += " : " + n
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		writeStockError(w, "CreateReception", 0, err)
+		return
+	}
+	defer tx.Rollback()
+
+	stocks := make(map[int]int, len(req.Items))
+	for _, it := range req.Items {
+		stock, err := receiveStock(tx, it.ProductID, it.Quantity, stockMovement{
+			Type: "restock", ActorID: middleware.UserID(r), Reason: reason, Reference: strings.TrimSpace(req.Reference),
+		})
+		if err != nil {
+			writeStockError(w, "CreateReception", it.ProductID, err)
+			return
+		}
+		stocks[it.ProductID] = stock
+	}
+	if err := tx.Commit(); err != nil {
+		writeStockError(w, "CreateReception", 0, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Réception enregistrée",
+		"stocks":  stocks, // Après service des précommandes en attente
+	})
+}
+
+// POST /stock/counts — Inventaire physique [ADMIN]
+// Chaque écart entre la quantité comptée et le stock enregistré devient un mouvement 'count'.
+func (h *StockHandler) CreateCount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.StockCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Items) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Au moins un article est requis"})
+		return
+	}
+	for _, it := range req.Items {
+		if it.CountedQuantity < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Quantité comptée invalide pour le produit %d", it.ProductID)})
+			return
+		}
+	}
+	reason := "Inventaire physique"
+	if n := strings.TrimSpace(req.Note); n != "" {
+		reason += " : " + n
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		writeStockError(w, "CreateCount", 0, err)
+		return
+	}
+	defer tx.Rollback()
+
+	type countResult struct {
+		ProductID  int `json:"product_id"`
+		Previous   int `json:"previous_quantity"`
+		Counted    int `json:"counted_quantity"`
+		Difference int `json:"difference"`
+	}
+	results := make([]countResult, 0, len(req.Items))
+	for _, it := range req.Items {
+		// Verrou : une commande simultanée ne peut pas fausser l'écart
+		var current int
+		err := tx.QueryRow("SELECT stock_quantity FROM products WHERE id = $1 FOR UPDATE", it.ProductID).Scan(&current)
+		if err == sql.ErrNoRows {
+			err = errInsufficientStock
+		}
+		if err != nil {
+			writeStockError(w, "CreateCount", it.ProductID, err)
+			return
+		}
+		diff := it.CountedQuantity - current
+		if diff != 0 {
+			if _, err := receiveStock(tx, it.ProductID, diff, stockMovement{
+				Type: "count", ActorID: middleware.UserID(r), Reason: reason,
+			}); err != nil {
+				writeStockError(w, "CreateCount", it.ProductID, err)
+				return
+			}
+		}
+		results = append(results, countResult{it.ProductID, current, it.CountedQuantity, diff})
+	}
+	if err := tx.Commit(); err != nil {
+		writeStockError(w, "CreateCount", 0, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Inventaire enregistré",
+		"items":   results,
+	})
+}
+
+// POST /stock/adjustments — Casse, péremption ou correction ponctuelle [ADMIN]
+func (h *StockHandler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.StockAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Type != "damage" && req.Type != "correction":
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Type invalide : damage ou correction"})
+		return
+	case req.QuantityChange == 0 || (req.Type == "damage" && req.QuantityChange > 0):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Variation invalide (négative pour une casse)"})
+		return
+	case req.Reason == "":
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Le motif est obligatoire"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		writeStockError(w, "CreateAdjustment", req.ProductID, err)
+		return
+	}
+	defer tx.Rollback()
+
+	stock, err := receiveStock(tx, req.ProductID, req.QuantityChange, stockMovement{
+		Type: req.Type, ActorID: middleware.UserID(r), Reason: req.Reason,
+	})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeStockError(w, "CreateAdjustment", req.ProductID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Ajustement enregistré",
+		"product_id":     req.ProductID,
+		"stock_quantity": stock,
+	})
+}
+
+// GET /stock/reconciliation — Produits dont le stock diffère du journal [ADMIN]
+// Un écart signale une modification hors adjustStock ; il se corrige par un inventaire.
+func (h *StockHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := h.DB.Query(`
+        SELECT p.id, p.name, p.stock_quantity, COALESCE(SUM(m.quantity_change), 0) AS ledger
+        FROM products p
+        LEFT JOIN stock_movements m ON m.product_id = p.id
+        GROUP BY p.id, p.name, p.stock_quantity
+        HAVING p.stock_quantity <> COALESCE(SUM(m.quantity_change), 0)
+        ORDER BY p.id`)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetReconciliation : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	discrepancies := make([]models.StockDiscrepancy, 0)
+	for rows.Next() {
+		var d models.StockDiscrepancy
+		if err := rows.Scan(&d.ProductID, &d.Name, &d.StockQuantity, &d.LedgerStock); err != nil {
+			fmt.Printf("Erreur scan rapprochement : %v\n", err)
+			continue
+		}
+		d.Difference = d.StockQuantity - d.LedgerStock
+		discrepancies = append(discrepancies, d)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"consistent":    len(discrepancies) == 0,
+		"discrepancies": discrepancies,
+	})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type StockSubscriptionHandler struct {
+	DB *sql.DB
+}
+
+// POST /products/{id}/stock-alerts — Être prévenu du retour en stock
+// Client connecté : contact du compte par défaut. Invité : email ou téléphone obligatoire.
+func (h *StockSubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	productID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/products/"), "/stock-alerts"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+	var input models.StockSubscriptionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+	input.Email = strings.TrimSpace(input.Email)
+	input.Phone = strings.TrimSpace(input.Phone)
+
+	var userID *int
+	if id := middleware.UserID(r); id > 0 {
+		var email, phone string
+		if err := h.DB.QueryRow("SELECT email, COALESCE(phone, '') FROM users WHERE id = $1", id).Scan(&email, &phone); err == nil {
+			userID = &id
+			if input.Email == "" && input.Phone == "" {
+				input.Email, input.Phone = email, phone
+			}
+		}
+	}
+	if input.Email != "" && !strings.Contains(input.Email, "@") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Email invalide"})
+		return
+	}
+	if input.Email == "" && input.Phone == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Email ou téléphone obligatoire"})
+		return
+	}
+
+	var stock int
+	err = h.DB.QueryRow("SELECT "+productStockColumn+" FROM products p WHERE p.id = $1 AND p.archived_at IS NULL", productID).Scan(&stock)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Produit introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD Subscribe : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if stock > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ce produit est disponible : vous pouvez le commander"})
+		return
+	}
+
+	// Déjà inscrit avec ce contact : la demande en attente est conservée
+	var id int
+	err = h.DB.QueryRow(`
+        INSERT INTO stock_subscriptions (product_id, user_id, email, phone)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+        ON CONFLICT DO NOTHING
+        RETURNING id`,
+		productID, userID, input.Email, input.Phone,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		json.NewEncoder(w).Encode(map[string]string{"message": "Vous êtes déjà inscrit(e) pour ce produit"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD Subscribe : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur enregistrement de l'alerte"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "C'est noté : vous serez prévenu(e) dès le retour en stock",
+		"id":      id,
+	})
+}
+
+// GET /stock-alerts — Demande en attente par produit, pour prioriser les commandes fournisseur [ADMIN]
+func (h *StockSubscriptionHandler) GetDemand(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := h.DB.Query(`
+        SELECT p.id, p.name, ` + productStockColumn + `, COUNT(*), MIN(s.created_at)
+        FROM stock_subscriptions s
+        JOIN products p ON p.id = s.product_id
+        WHERE s.status = 'pending'
+        GROUP BY p.id
+        ORDER BY COUNT(*) DESC, MIN(s.created_at)`)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetDemand : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	demand := make([]models.StockDemand, 0)
+	for rows.Next() {
+		var d models.StockDemand
+		var oldest time.Time
+		if err := rows.Scan(&d.ProductID, &d.Name, &d.StockQuantity, &d.Subscribers, &oldest); err != nil {
+			fmt.Printf("Erreur scan demande : %v\n", err)
+			continue
+		}
+		d.OldestRequest = oldest.Format(time.RFC3339)
+		demand = append(demand, d)
+	}
+	json.NewEncoder(w).Encode(demand)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/middleware"
+	"akwaba-bebe/backend/internal/models"
+)
+
+type UserHandler struct {
+	DB *sql.DB
+}
+
+// GET /roles — Matrice des rôles et permissions [users.manage]
+func (h *UserHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	roles := make([]models.RoleInfo, 0, len(middleware.Roles))
+	for _, role := range middleware.Roles {
+		roles = append(roles, models.RoleInfo{Role: role, Permissions: middleware.RolePermissions(role)})
+	}
+	json.NewEncoder(w).Encode(roles)
+}
+
+// GET /users?role=support&search=konan — Comptes utilisateurs [users.manage]
+// staff=true : uniquement les comptes du back-office (hors clients)
+func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	query := `SELECT id, email, full_name, COALESCE(phone, ''), role, created_at FROM users WHERE 1=1`
+	args := []interface{}{}
+	if role := q.Get("role"); role != "" {
+		args = append(args, role)
+		query += fmt.Sprintf(" AND role = $%d", len(args))
+	} else if q.Get("staff") == "true" {
+		query += " AND role <> 'customer'"
+	}
+	if search := strings.TrimSpace(q.Get("search")); search != "" {
+		args = append(args, "%"+search+"%")
+		query += fmt.Sprintf(" AND (email ILIKE $%d OR full_name ILIKE $%d)", len(args), len(args))
+	}
+	limit := 100
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY id ASC LIMIT $%d", len(args))
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetUsers : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.FullName, &u.Phone, &u.Role, &u.CreatedAt); err != nil {
+			fmt.Printf("Erreur scan utilisateur : %v\n", err)
+			continue
+		}
+		users = append(users, u)
+	}
+	json.NewEncoder(w).Encode(users)
+}
+
+// PUT /users/{id}/role — Attribution d'un rôle [users.manage]
+// Son propre rôle n'est pas modifiable, et le dernier admin ne peut pas être rétrogradé.
+// Le nouveau rôle s'applique dès la requête suivante de l'utilisateur : RequirePermission
+// relit le rôle en base au lieu de celui du JWT.
+func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/role")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !middleware.ValidRole(req.Role) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Rôle invalide (" + strings.Join(middleware.Roles, ", ") + ")"})
+		return
+	}
+
+	if id == middleware.UserID(r) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Vous ne pouvez pas modifier votre propre rôle"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateUserRole : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la mise à jour du rôle"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Verrou sur les admins : deux rétrogradations simultanées ne peuvent pas les supprimer tous
+	var admins int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM (SELECT id FROM users WHERE role = 'admin' FOR UPDATE) a").Scan(&admins); err != nil {
+		fmt.Printf("Erreur BDD UpdateUserRole id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la mise à jour du rôle"})
+		return
+	}
+
+	var current string
+	err = tx.QueryRow("SELECT role FROM users WHERE id = $1 FOR UPDATE", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Utilisateur introuvable"})
+		return
+	}
+	if err == nil && current == "admin" && req.Role != "admin" && admins <= 1 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Impossible de retirer le dernier administrateur"})
+		return
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE users SET role = $1 WHERE id = $2", req.Role, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erreur BDD UpdateUserRole id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la mise à jour du rôle"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Rôle mis à jour",
+		"id":            id,
+		"previous_role": current,
+		"role":          req.Role,
+		"permissions":   middleware.RolePermissions(req.Role),
+	})
+}
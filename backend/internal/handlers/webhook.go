@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"akwaba-bebe/backend/internal/models"
+	"akwaba-bebe/backend/internal/payments"
+)
+
+type WebhookHandler struct {
+	DB       *sql.DB
+	Payments *payments.Registry
+}
+
+// Taille maximale acceptée pour une notification
+const maxWebhookBody = 1 << 20
+
+const webhookEventColumns = `id, provider, event_id, signature_valid, payload, status, error, payment_id,
+               attempts, received_at, processed_at`
+
+func scanWebhookEvent(row interface{ Scan(...interface{}) error }) (models.PaymentWebhookEvent, error) {
+	var e models.PaymentWebhookEvent
+	err := row.Scan(&e.ID, &e.Provider, &e.EventID, &e.SignatureValid, &e.Payload, &e.Status, &e.Error,
+		&e.PaymentID, &e.Attempts, &e.ReceivedAt, &e.ProcessedAt)
+	return e, err
+}
+
+// webhookVerifier renvoie le prestataire s'il accepte les notifications
+func (h *WebhookHandler) webhookVerifier(name string) (payments.WebhookVerifier, error) {
+	provider, err := h.Payments.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	verifier, ok := provider.(payments.WebhookVerifier)
+	if !ok {
+		return nil, payments.ErrUnknownProvider
+	}
+	return verifier, nil
+}
+
+// processWebhookEvent applique un événement enregistré au paiement et à la commande liés.
+// La ligne de l'événement est verrouillée : un renvoi simultané du prestataire et un rejeu
+// admin ne peuvent pas le traiter deux fois.
+func (h *WebhookHandler) processWebhookEvent(eventRowID int) error {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var providerName, payload, status string
+	err = tx.QueryRow(
+		"SELECT provider, payload, status FROM payment_webhook_events WHERE id = $1 FOR UPDATE", eventRowID,
+	).Scan(&providerName, &payload, &status)
+	if err != nil {
+		return err
+	}
+	if status == "processed" {
+		return nil
+	}
+	if status == "rejected" {
+		return errors.New("événement rejeté (signature invalide) : il ne peut pas être traité")
+	}
+
+	processErr := func() error {
+		verifier, err := h.webhookVerifier(providerName)
+		if err != nil {
+			return err
+		}
+		evt, err := verifier.ParseWebhook([]byte(payload))
+		if err != nil {
+			return fmt.Errorf("contenu illisible : %w", err)
+		}
+
+		// merchant_reference existe dès la création du paiement : la notification peut
+		// arriver avant que la réponse d'Initiate ait été enregistrée
+		var paymentID int
+		err = tx.QueryRow(`
+            SELECT id FROM payments
+            WHERE provider = $1
+              AND ((provider_reference IS NOT NULL AND provider_reference = NULLIF($2, ''))
+                   OR merchant_reference = NULLIF($3, ''))
+            ORDER BY id DESC LIMIT 1`,
+			providerName, evt.ProviderReference, evt.MerchantReference,
+		).Scan(&paymentID)
+		if err == sql.ErrNoRows {
+			return errors.New("paiement introuvable")
+		} else if err != nil {
+			return err
+		}
+
+		// Remboursements en cours : ce sont eux que le prestataire confirme
+		// (avant toute écriture sur le paiement : verrous commande puis paiement)
+		completed := 0
+		if evt.Status == payments.StatusRefunded {
+			if completed, err = completePendingRefunds(tx, paymentID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(
+			"UPDATE payments SET provider_reference = COALESCE(provider_reference, NULLIF($1, '')) WHERE id = $2",
+			evt.ProviderReference, paymentID,
+		); err != nil {
+			return err
+		}
+		if evt.Status != payments.StatusPending && completed == 0 {
+			if _, err := setPaymentStatus(tx, paymentID, evt.Status, evt.Message); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+            UPDATE payment_webhook_events
+            SET status = 'processed', error = NULL, payment_id = $1, attempts = attempts + 1, processed_at = NOW()
+            WHERE id = $2`,
+			paymentID, eventRowID,
+		)
+		return err
+	}()
+	if processErr != nil {
+		// Les modifications partielles sont annulées ; seul l'échec est enregistré
+		tx.Rollback()
+		if _, err := h.DB.Exec(
+			"UPDATE payment_webhook_events SET status = 'failed', error = $1, attempts = attempts + 1 WHERE id = $2",
+			processErr.Error(), eventRowID,
+		); err != nil {
+			fmt.Printf("Erreur BDD webhook event=%d : %v\n", eventRowID, err)
+		}
+		return processErr
+	}
+	return tx.Commit()
+}
+
+// POST /webhooks/payments/{provider} — Notification d'un prestataire de paiement
+// Signature HMAC-SHA256 du corps brut dans le header X-Signature.
+// Réponses : 200 traité ou déjà reçu, 401 signature invalide, 500 échec (le prestataire renverra).
+func (h *WebhookHandler) ReceivePaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	providerName := strings.TrimPrefix(r.URL.Path, "/webhooks/payments/")
+	verifier, err := h.webhookVerifier(providerName)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Prestataire inconnu"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Données invalides"})
+		return
+	}
+
+	// Signature invalide : conservé pour investigation, jamais traité
+	if !verifier.VerifyWebhook(body, r.Header.Get(payments.SignatureHeader)) {
+		if _, err := h.DB.Exec(
+			`INSERT INTO payment_webhook_events (provider, signature_valid, payload, status, error)
+             VALUES ($1, FALSE, $2, 'rejected', 'Signature invalide')`,
+			providerName, string(body),
+		); err != nil {
+			fmt.Printf("Erreur BDD webhook %s : %v\n", providerName, err)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Signature invalide"})
+		return
+	}
+
+	evt, err := verifier.ParseWebhook(body)
+	if err != nil {
+		if _, dbErr := h.DB.Exec(
+			`INSERT INTO payment_webhook_events (provider, signature_valid, payload, status, error)
+             VALUES ($1, TRUE, $2, 'rejected', $3)`,
+			providerName, string(body), "Contenu illisible : "+err.Error(),
+		); dbErr != nil {
+			fmt.Printf("Erreur BDD webhook %s : %v\n", providerName, dbErr)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Notification illisible"})
+		return
+	}
+
+	// Déduplication par identifiant d'événement du prestataire
+	var eventRowID int
+	var status string
+	err = h.DB.QueryRow(`
+        INSERT INTO payment_webhook_events (provider, event_id, signature_valid, payload)
+        VALUES ($1, $2, TRUE, $3)
+        ON CONFLICT (provider, event_id) DO UPDATE SET provider = EXCLUDED.provider
+        RETURNING id, status`,
+		providerName, evt.EventID, string(body),
+	).Scan(&eventRowID, &status)
+	if err != nil {
+		fmt.Printf("Erreur BDD webhook %s : %v\n", providerName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if status == "processed" {
+		json.NewEncoder(w).Encode(map[string]string{"message": "Événement déjà traité"})
+		return
+	}
+
+	// Nouvel événement, ou renvoi d'un événement en échec : on (re)traite
+	if err := h.processWebhookEvent(eventRowID); err != nil {
+		fmt.Printf("Erreur traitement webhook %s event=%d : %v\n", providerName, eventRowID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Échec du traitement, événement enregistré"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Événement traité"})
+}
+
+// GET /payment-webhook-events?status=failed — Notifications reçues [ADMIN]
+// Les 100 plus récentes, filtrables par statut.
+func (h *WebhookHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := "SELECT " + webhookEventColumns + " FROM payment_webhook_events"
+	args := []interface{}{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY received_at DESC LIMIT 100"
+
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		fmt.Printf("Erreur BDD GetEvents : %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur lors de la récupération des événements"})
+		return
+	}
+	defer rows.Close()
+
+	events := make([]models.PaymentWebhookEvent, 0)
+	for rows.Next() {
+		e, err := scanWebhookEvent(rows)
+		if err != nil {
+			fmt.Printf("Erreur Scan payment_webhook_events : %v\n", err)
+			continue
+		}
+		events = append(events, e)
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
+
+// POST /payment-webhook-events/{id}/replay — Rejouer un événement en échec [ADMIN]
+func (h *WebhookHandler) ReplayEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/payment-webhook-events/"), "/replay"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "ID invalide"})
+		return
+	}
+
+	var status string
+	err = h.DB.QueryRow("SELECT status FROM payment_webhook_events WHERE id = $1", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Événement introuvable"})
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD ReplayEvent id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	if status != "failed" && status != "received" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Seuls les événements en échec peuvent être rejoués"})
+		return
+	}
+
+	if err := h.processWebhookEvent(id); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Échec du rejeu : " + err.Error()})
+		return
+	}
+
+	e, err := scanWebhookEvent(h.DB.QueryRow("SELECT "+webhookEventColumns+" FROM payment_webhook_events WHERE id = $1", id))
+	if err != nil {
+		fmt.Printf("Erreur BDD ReplayEvent id=%d : %v\n", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Erreur serveur"})
+		return
+	}
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/notifications"
+)
+
+// AbandonedCartJob relance les paniers actifs inactifs depuis IdleAfter dont on connaît
+// l'email ou le téléphone (panier invité via PUT /cart/contact, ou compte client).
+// Un panier n'est relancé qu'une fois par période d'inactivité, et jamais si le client
+// a commandé depuis sa dernière modification du panier.
+type AbandonedCartJob struct {
+	DB          *sql.DB
+	Notifier    *notifications.Notifier
+	IdleAfter   time.Duration // Inactivité avant relance
+	MaxAge      time.Duration // Au-delà, le panier est trop ancien pour être relancé
+	FrontendURL string        // Base du lien de restauration
+}
+
+// Variables d'environnement : ABANDONED_CART_IDLE (3h par défaut), ABANDONED_CART_MAX_AGE
+// (168h), ABANDONED_CART_INTERVAL (15m) et FRONTEND_URL.
+func AbandonedCartJobFromEnv(db *sql.DB, notifier *notifications.Notifier) (*AbandonedCartJob, time.Duration) {
+	frontend := os.Getenv("FRONTEND_URL")
+	if frontend == "" {
+		frontend = "http://localhost:3000"
+	}
+	job := &AbandonedCartJob{
+		DB:          db,
+		Notifier:    notifier,
+		IdleAfter:   envDuration("ABANDONED_CART_IDLE", 3*time.Hour),
+		MaxAge:      envDuration("ABANDONED_CART_MAX_AGE", 7*24*time.Hour),
+		FrontendURL: strings.TrimRight(frontend, "/"),
+	}
+	return job, envDuration("ABANDONED_CART_INTERVAL", 15*time.Minute)
+}
+
+type abandonedCart struct {
+	id         string
+	email      string
+	phone      string
+	reminderID int
+	token      string
+}
+
+// Run relance les paniers abandonnés (100 au maximum par passage)
+func (j *AbandonedCartJob) Run(ctx context.Context) error {
+	if len(j.Notifier.Channels()) == 0 {
+		return nil
+	}
+
+	carts, err := j.claim(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range carts {
+		if err := j.remind(ctx, c); err != nil {
+			log.Printf("Relance panier %s : %v", c.id, err)
+		}
+	}
+	return nil
+}
+
+// claim réserve les paniers à relancer : chaque panier est verrouillé (SKIP LOCKED) et sa
+// relance enregistrée ('pending') avant l'envoi, dans une même transaction. Même avec
+// plusieurs instances de l'API, un panier n'est relancé qu'une fois.
+func (j *AbandonedCartJob) claim(ctx context.Context) ([]abandonedCart, error) {
+	tx, err := j.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT c.id, ct.email, ct.phone
+        FROM carts c
+        LEFT JOIN users u ON u.id = c.user_id
+        CROSS JOIN LATERAL (
+            SELECT COALESCE(NULLIF(c.email, ''), u.email, '') AS email,
+                   COALESCE(NULLIF(c.phone, ''), NULLIF(u.phone, ''), '') AS phone) ct
+        WHERE c.status = 'active'
+          AND c.updated_at < NOW() - make_interval(secs => $1)
+          AND c.updated_at > NOW() - make_interval(secs => $2)
+          AND (ct.email <> '' OR ct.phone <> '')
+          AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = c.id)
+          AND NOT EXISTS (
+              SELECT 1 FROM abandoned_cart_reminders r
+              WHERE r.cart_id = c.id AND r.sent_at >= c.updated_at)
+          AND NOT EXISTS (
+              SELECT 1 FROM orders o
+              WHERE o.created_at >= c.updated_at
+                AND ((ct.email <> '' AND o.customer_email = ct.email)
+                  OR (ct.phone <> '' AND o.customer_phone = ct.phone)))
+        ORDER BY c.updated_at
+        LIMIT 100
+        FOR UPDATE OF c SKIP LOCKED`,
+		j.IdleAfter.Seconds(), j.MaxAge.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	carts := make([]abandonedCart, 0)
+	for rows.Next() {
+		var c abandonedCart
+		if err := rows.Scan(&c.id, &c.email, &c.phone); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		carts = append(carts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range carts {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		carts[i].token = hex.EncodeToString(b)
+		recipient := carts[i].email
+		if recipient == "" {
+			recipient = carts[i].phone
+		}
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO abandoned_cart_reminders (cart_id, token, recipient, status)
+            VALUES ($1, $2, $3, 'pending') RETURNING id`,
+			carts[i].id, carts[i].token, recipient,
+		).Scan(&carts[i].reminderID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return carts, tx.Commit()
+}
+
+// remind envoie la relance réservée par claim et enregistre son résultat, même en échec
+// (pas de nouvel essai avant la prochaine modification du panier)
+func (j *AbandonedCartJob) remind(ctx context.Context, c abandonedCart) error {
+	names, err := j.cartProductNames(ctx, c.id)
+	if err != nil {
+		return err
+	}
+
+	link := j.FrontendURL + "/cart?restore=" + c.token
+
+	notificationID, channel, sendErr := j.Notifier.Send(ctx, notifications.Message{
+		Kind:    "abandoned_cart",
+		Email:   c.email,
+		Phone:   c.phone,
+		Subject: "Votre panier vous attend chez Akwaba Bébé",
+		Body: fmt.Sprintf("Bonjour,\n\nVous avez laissé des articles dans votre panier :\n- %s\n\n"+
+			"Retrouvez-le en un clic : %s\n\nÀ très vite,\nL'équipe Akwaba Bébé",
+			strings.Join(names, "\n- "), link),
+		SMSBody: "Akwaba Bébé : votre panier vous attend ! Retrouvez-le ici : " + link,
+	})
+
+	status, recipient := "sent", c.email
+	if channel == notifications.ChannelSMS {
+		recipient = c.phone
+	}
+	if sendErr != nil {
+		status = "failed"
+	}
+	var notification *int
+	if notificationID > 0 {
+		notification = &notificationID
+	}
+
+	_, err = j.DB.ExecContext(ctx, `
+        UPDATE abandoned_cart_reminders
+        SET channel = NULLIF($1, ''), recipient = COALESCE(NULLIF($2, ''), recipient), status = $3, notification_id = $4
+        WHERE id = $5`,
+		channel, recipient, status, notification, c.reminderID)
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (j *AbandonedCartJob) cartProductNames(ctx context.Context, cartID string) ([]string, error) {
+	rows, err := j.DB.QueryContext(ctx, `
+        SELECT p.name, ci.quantity FROM cart_items ci
+        JOIN products p ON p.id = ci.product_id
+        WHERE ci.cart_id = $1 ORDER BY ci.added_at, ci.id`, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		var quantity int
+		if err := rows.Scan(&name, &quantity); err != nil {
+			return nil, err
+		}
+		names = append(names, fmt.Sprintf("%s (x%d)", name, quantity))
+	}
+	return names, rows.Err()
+}
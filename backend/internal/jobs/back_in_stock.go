@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"akwaba-bebe/backend/internal/notifications"
+)
+
+// BackInStockJob prévient les inscrits (POST /products/{id}/stock-alerts) dès que le
+// produit est de nouveau en stock, quel que soit le chemin du réapprovisionnement.
+// Chaque inscription est réservée (status 'notified') avant l'envoi : même avec plusieurs
+// instances de l'API, un inscrit n'est prévenu qu'une fois.
+type BackInStockJob struct {
+	DB          *sql.DB
+	Notifier    *notifications.Notifier
+	FrontendURL string // Base du lien vers la fiche produit
+}
+
+// Variables d'environnement : BACK_IN_STOCK_INTERVAL (5m par défaut) et FRONTEND_URL.
+func BackInStockJobFromEnv(db *sql.DB, notifier *notifications.Notifier) (*BackInStockJob, time.Duration) {
+	frontend := os.Getenv("FRONTEND_URL")
+	if frontend == "" {
+		frontend = "http://localhost:3000"
+	}
+	job := &BackInStockJob{DB: db, Notifier: notifier, FrontendURL: strings.TrimRight(frontend, "/")}
+	return job, envDuration("BACK_IN_STOCK_INTERVAL", 5*time.Minute)
+}
+
+type stockSubscriber struct {
+	id          int
+	productID   int
+	productName string
+	email       string
+	phone       string
+}
+
+// Run notifie les inscrits des produits revenus en stock (200 au maximum par passage)
+func (j *BackInStockJob) Run(ctx context.Context) error {
+	if len(j.Notifier.Channels()) == 0 {
+		return nil
+	}
+
+	rows, err := j.DB.QueryContext(ctx, `
+        UPDATE stock_subscriptions s SET status = 'notified', notified_at = NOW()
+        FROM products p
+        WHERE p.id = s.product_id
+          AND s.id IN (
+              SELECT s2.id FROM stock_subscriptions s2
+              JOIN products p2 ON p2.id = s2.product_id
+              WHERE s2.status = 'pending' AND p2.archived_at IS NULL   -- Archivé : plus en vente
+                AND CASE WHEN p2.is_bundle   -- Coffret : stock calculé (voir handlers.productStockColumn)
+                    THEN EXISTS (SELECT 1 FROM bundle_items bi WHERE bi.bundle_id = p2.id)
+                         AND NOT EXISTS (
+                             SELECT 1 FROM bundle_items bi JOIN products c ON c.id = bi.component_id
+                             WHERE bi.bundle_id = p2.id AND c.stock_quantity < bi.quantity)
+                    ELSE p2.stock_quantity > 0 END
+              ORDER BY s2.created_at
+              LIMIT 200
+              FOR UPDATE OF s2 SKIP LOCKED)
+        RETURNING s.id, s.product_id, p.name, COALESCE(s.email, ''), COALESCE(s.phone, '')`)
+	if err != nil {
+		return err
+	}
+
+	subscribers := make([]stockSubscriber, 0)
+	for rows.Next() {
+		var s stockSubscriber
+		if err := rows.Scan(&s.id, &s.productID, &s.productName, &s.email, &s.phone); err != nil {
+			rows.Close()
+			return err
+		}
+		subscribers = append(subscribers, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range subscribers {
+		if err := j.notify(ctx, s); err != nil {
+			log.Printf("Alerte retour en stock id=%d : %v", s.id, err)
+		}
+	}
+	return nil
+}
+
+// notify envoie l'alerte ; en échec, l'inscription passe en 'failed' (pas de nouvel essai)
+func (j *BackInStockJob) notify(ctx context.Context, s stockSubscriber) error {
+	link := fmt.Sprintf("%s/produits/%d", j.FrontendURL, s.productID)
+	notificationID, _, sendErr := j.Notifier.Send(ctx, notifications.Message{
+		Kind:    "back_in_stock",
+		Email:   s.email,
+		Phone:   s.phone,
+		Subject: s.productName + " est de retour chez Akwaba Bébé",
+		Body: fmt.Sprintf("Bonjour,\n\nBonne nouvelle : %s est de nouveau disponible.\n"+
+			"Les quantités sont limitées, commandez-le ici : %s\n\nL'équipe Akwaba Bébé", s.productName, link),
+		SMSBody: fmt.Sprintf("Akwaba Bébé : %s est de retour en stock ! %s", s.productName, link),
+	})
+
+	status := "notified"
+	if sendErr != nil {
+		status = "failed"
+	}
+	var notification *int
+	if notificationID > 0 {
+		notification = &notificationID
+	}
+	if _, err := j.DB.ExecContext(ctx,
+		"UPDATE stock_subscriptions SET status = $1, notification_id = $2 WHERE id = $3",
+		status, notification, s.id); err != nil {
+		return err
+	}
+	return sendErr
+}
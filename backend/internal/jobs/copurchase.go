@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CoPurchaseJob recalcule product_copurchases ("souvent achetés ensemble") à partir des
+// commandes des Window dernières heures. Une paire n'est retenue qu'à partir de MinOrders
+// commandes communes, pour ne pas recommander sur la foi d'un seul panier.
+type CoPurchaseJob struct {
+	DB        *sql.DB
+	Window    time.Duration
+	MinOrders int
+}
+
+// Variables d'environnement : COPURCHASE_WINDOW (2160h, soit 90 jours, par défaut)
+// et COPURCHASE_INTERVAL (6h).
+func CoPurchaseJobFromEnv(db *sql.DB) (*CoPurchaseJob, time.Duration) {
+	job := &CoPurchaseJob{
+		DB:        db,
+		Window:    envDuration("COPURCHASE_WINDOW", 90*24*time.Hour),
+		MinOrders: 2,
+	}
+	return job, envDuration("COPURCHASE_INTERVAL", 6*time.Hour)
+}
+
+// Run reconstruit la table en une transaction : les fiches produit lisent toujours
+// un calcul complet
+func (j *CoPurchaseJob) Run(ctx context.Context) error {
+	tx, err := j.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM product_copurchases"); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        WITH lines AS (
+            SELECT DISTINCT oi.order_id, oi.product_id
+            FROM order_items oi
+            JOIN orders o ON o.id = oi.order_id
+            WHERE oi.product_id IS NOT NULL
+              AND oi.quantity > oi.cancelled_quantity
+              AND o.created_at > NOW() - make_interval(secs => $1)
+              AND o.status NOT IN ('annulé', 'pending_payment', 'payment_failed', 'refunded')
+        ),
+        totals AS (
+            SELECT product_id, COUNT(*) AS orders FROM lines GROUP BY product_id
+        )
+        INSERT INTO product_copurchases (product_id, related_product_id, order_count, confidence)
+        SELECT a.product_id, b.product_id, COUNT(*), COUNT(*)::float8 / t.orders
+        FROM lines a
+        JOIN lines b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+        JOIN totals t ON t.product_id = a.product_id
+        GROUP BY a.product_id, b.product_id, t.orders
+        HAVING COUNT(*) >= $2`,
+		j.Window.Seconds(), j.MinOrders)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
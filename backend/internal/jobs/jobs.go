@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// Every lance job immédiatement puis à chaque intervalle, jusqu'à l'annulation de ctx.
+// Une erreur est loguée sans arrêter le job : le passage suivant réessaie.
+func Every(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := job(ctx); err != nil {
+				log.Printf("Job %s : %v", name, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// envDuration lit une durée Go ("30m", "3h") avec une valeur par défaut
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("%s invalide (%q), valeur par défaut %s", key, v, def)
+	}
+	return def
+}
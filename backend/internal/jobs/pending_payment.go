@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PendingPaymentJob expire les commandes payables en ligne restées en "pending_payment"
+// plus de TTL après leur dernière tentative de paiement : Expire (handlers.ExpireOrderPayment)
+// les passe en "payment_failed" et rend leur stock, leur créneau et leur code promo.
+// Chaque commande est verrouillée (SKIP LOCKED) dans sa propre transaction : plusieurs
+// instances de l'API ne traitent jamais la même.
+type PendingPaymentJob struct {
+	DB     *sql.DB
+	TTL    time.Duration
+	Expire func(tx *sql.Tx, orderID int) error
+}
+
+// Variables d'environnement : PENDING_PAYMENT_TTL (2h par défaut) et
+// PENDING_PAYMENT_INTERVAL (10m).
+func PendingPaymentJobFromEnv(db *sql.DB, expire func(tx *sql.Tx, orderID int) error) (*PendingPaymentJob, time.Duration) {
+	job := &PendingPaymentJob{DB: db, TTL: envDuration("PENDING_PAYMENT_TTL", 2*time.Hour), Expire: expire}
+	return job, envDuration("PENDING_PAYMENT_INTERVAL", 10*time.Minute)
+}
+
+// Condition d'expiration ($1 = TTL en secondes), sur la commande aliasée o
+const stalePendingPayment = `o.status = 'pending_payment'
+          AND GREATEST(o.created_at, COALESCE((SELECT MAX(p.created_at) FROM payments p WHERE p.order_id = o.id), o.created_at))
+              < NOW() - $1 * INTERVAL '1 second'`
+
+// Run expire les commandes en attente de paiement depuis trop longtemps (200 au maximum par passage)
+func (j *PendingPaymentJob) Run(ctx context.Context) error {
+	ttl := int(j.TTL.Seconds())
+	rows, err := j.DB.QueryContext(ctx, `
+        SELECT o.id FROM orders o
+        WHERE `+stalePendingPayment+`
+        ORDER BY o.created_at
+        LIMIT 200`, ttl)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := j.expire(ctx, id, ttl); err != nil {
+			log.Printf("Expiration paiement commande %d : %v", id, err)
+		}
+	}
+	return nil
+}
+
+// expire traite une commande si elle est toujours en attente et libre
+func (j *PendingPaymentJob) expire(ctx context.Context, orderID, ttl int) error {
+	tx, err := j.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+        SELECT o.id FROM orders o
+        WHERE o.id = $2 AND `+stalePendingPayment+`
+        FOR UPDATE SKIP LOCKED`, ttl, orderID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := j.Expire(tx, orderID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
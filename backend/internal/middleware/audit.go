@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// auditSnapshots associe chaque type d'entité journalisé à la requête de son instantané
+// avant / après ($1 = ID). Utilisateurs : jamais le hash du mot de passe
+var auditSnapshots = map[string]string{
+	"product":         "SELECT row_to_json(t) FROM products t WHERE t.id = $1",
+	"category":        "SELECT row_to_json(t) FROM categories t WHERE t.id = $1",
+	"subcategory":     "SELECT row_to_json(t) FROM subcategories t WHERE t.id = $1",
+	"article":         "SELECT row_to_json(t) FROM articles t WHERE t.id = $1",
+	"order":           "SELECT row_to_json(t) FROM orders t WHERE t.id = $1",
+	"contact_message": "SELECT row_to_json(t) FROM contact_messages t WHERE t.id = $1",
+	"user":            "SELECT json_build_object('id', id, 'email', email, 'full_name', full_name, 'role', role) FROM users WHERE id = $1",
+}
+
+// Audit journalise dans audit_log chaque écriture réussie (POST, PUT, PATCH, DELETE)
+// faite par un compte du back-office (voir IsStaff, rôle lu en base) sur la route :
+// auteur, action, entité, état avant / après, champs modifiés et IP. Les lectures, les
+// échecs et les dry-run ne sont pas journalisés.
+//
+// L'entité est le premier segment numérique du chemin (/categories/update/5,
+// /orders/5/items/9/cancel), ou l'"id" de la réponse pour une création. L'action est
+// formée des autres segments ("update", "items.cancel", "bulk"), à défaut de la méthode.
+// Sans entité unique (action groupée, import), after_data contient la réponse de l'API.
+func Audit(db *sql.DB, entity string, next http.HandlerFunc) http.HandlerFunc {
+	return AuditVia(db, entity, "", next)
+}
+
+// AuditVia est Audit pour une route dont l'ID du chemin n'est pas celui de l'entité
+// journalisée : resolve ($1 = ID du chemin) renvoie l'ID de l'entité, par exemple la
+// commande d'un paiement pour /payments/{id}/refund.
+func AuditVia(db *sql.DB, entity, resolve string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions ||
+			r.Header.Get("Authorization") == "" {
+			next(w, r)
+			return
+		}
+		// Rôle relu en base, comme RequirePermission : un compte promu depuis son dernier
+		// token écrit déjà avec ses nouvelles permissions
+		authed, ok := withToken(r)
+		if !ok {
+			next(w, r)
+			return
+		}
+		if role, err := currentRole(db, UserID(authed)); err != nil || !IsStaff(role) {
+			next(w, r)
+			return
+		}
+
+		snapshot := auditSnapshots[entity]
+		entityID, action := auditTarget(r)
+		if resolve != "" && entityID > 0 {
+			if err := db.QueryRow(resolve, entityID).Scan(&entityID); err != nil {
+				// Introuvable : le handler répondra 404, rien ne sera journalisé
+				entityID = 0
+			}
+		}
+		before := auditSnapshot(db, snapshot, entityID)
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= 300 {
+			return
+		}
+
+		var response struct {
+			ID     int  `json:"id"`
+			DryRun bool `json:"dry_run"`
+		}
+		json.Unmarshal(rec.body.Bytes(), &response)
+		if response.DryRun {
+			return
+		}
+		if entityID == 0 && r.Method == http.MethodPost && response.ID > 0 {
+			entityID = response.ID
+		}
+
+		var after []byte
+		if entityID > 0 {
+			after = auditSnapshot(db, snapshot, entityID)
+		} else if json.Valid(rec.body.Bytes()) {
+			after = rec.body.Bytes()
+		}
+
+		_, err := db.Exec(`
+            INSERT INTO audit_log (user_id, action, entity_type, entity_id, method, path, before_data, after_data, changes, ip_address)
+            VALUES (NULLIF($1, 0), $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, $10)`,
+			UserID(authed), action, entity, entityID, r.Method, r.URL.Path,
+			nullJSON(before), nullJSON(after), nullJSON(auditChanges(before, after)), clientIP(r),
+		)
+		if err != nil {
+			fmt.Printf("Erreur BDD audit_log %s %s : %v\n", r.Method, r.URL.Path, err)
+		}
+	}
+}
+
+// auditTarget extrait l'ID de l'entité et le nom de l'action du chemin
+func auditTarget(r *http.Request) (int, string) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	entityID := 0
+	words := make([]string, 0)
+	for _, s := range segments[1:] {
+		if id, err := strconv.Atoi(s); err == nil {
+			if entityID == 0 {
+				entityID = id
+			}
+			continue
+		}
+		if s != "" {
+			words = append(words, s)
+		}
+	}
+	if len(words) > 0 {
+		return entityID, strings.Join(words, ".")
+	}
+	switch r.Method {
+	case http.MethodPost:
+		return entityID, "create"
+	case http.MethodDelete:
+		return entityID, "delete"
+	default:
+		return entityID, "update"
+	}
+}
+
+// auditSnapshot renvoie l'entité en JSON (nil si absente ou sans ID)
+func auditSnapshot(db *sql.DB, query string, id int) []byte {
+	if query == "" || id == 0 {
+		return nil
+	}
+	var data []byte
+	err := db.QueryRow(query, id).Scan(&data)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Erreur BDD instantané audit id=%d : %v\n", id, err)
+	}
+	return data
+}
+
+// auditChanges compare deux instantanés : { "champ": { "from": ..., "to": ... } }.
+// nil pour une création ou une suppression (l'un des deux est absent)
+func auditChanges(before, after []byte) []byte {
+	var old, cur map[string]json.RawMessage
+	if json.Unmarshal(before, &old) != nil || json.Unmarshal(after, &cur) != nil || old == nil || cur == nil {
+		return nil
+	}
+	changes := make(map[string]map[string]json.RawMessage)
+	for field, value := range cur {
+		if !bytes.Equal(old[field], value) {
+			changes[field] = map[string]json.RawMessage{"from": orNull(old[field]), "to": value}
+		}
+	}
+	for field, value := range old {
+		if _, ok := cur[field]; !ok {
+			changes[field] = map[string]json.RawMessage{"from": value, "to": orNull(nil)}
+		}
+	}
+	data, _ := json.Marshal(changes)
+	return data
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// nullJSON : NULL en base pour un document absent
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// clientIP : première adresse de X-Forwarded-For (App Runner), sinon l'adresse de la connexion
+func clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if len(ip) > 45 {
+		ip = ip[:45]
+	}
+	return ip
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -14,44 +15,93 @@ import (
 // Ne plus jamais définir la clé en dur dans ce fichier.
 var jwtKey = config.JWTKey()
 
-// IsAdmin vérifie que la requête contient un token JWT valide avec le rôle "admin".
-// Toutes les réponses d'erreur sont en JSON (règle absolue du projet).
-func IsAdmin(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+type contextKey string
 
-		// Vérification de la présence du header Authorization
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Authentification requise"})
-			return
-		}
+const (
+	userIDKey contextKey = "user_id"
+	roleKey   contextKey = "role"
+)
+
+// UserID renvoie l'ID de l'utilisateur authentifié par RequireAuth / RequirePermission (0 sinon)
+func UserID(r *http.Request) int {
+	id, _ := r.Context().Value(userIDKey).(int)
+	return id
+}
+
+// Role renvoie le rôle de l'utilisateur authentifié par RequireAuth / RequirePermission ("" sinon) :
+// celui du token, sauf derrière RequirePermission qui le relit en base
+func Role(r *http.Request) string {
+	role, _ := r.Context().Value(roleKey).(string)
+	return role
+}
+
+// authenticate valide le JWT de la requête et renvoie la requête enrichie de l'ID et du rôle.
+// En cas d'échec, la réponse JSON 401 est déjà écrite et la requête renvoyée est nil.
+func authenticate(w http.ResponseWriter, r *http.Request) *http.Request {
+	// Vérification de la présence du header Authorization
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Authentification requise"})
+		return nil
+	}
+
+	authed, ok := withToken(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Token invalide ou expiré"})
+		return nil
+	}
+	return authed
+}
+
+// withToken valide le JWT du header Authorization et renvoie la requête enrichie
+// de l'ID et du rôle. ok vaut false si le token est invalide ou expiré.
+func withToken(r *http.Request) (*http.Request, bool) {
+	// Format attendu : "Bearer <token>" — on extrait uniquement le token
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
 
-		// Format attendu : "Bearer <token>" — on extrait uniquement le token
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	// Validation du JWT avec la clé partagée
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		return r, false
+	}
 
-		// Validation du JWT avec la clé partagée
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+	// user_id est stocké en float64 dans le JSON du token
+	role, _ := claims["role"].(string)
+	idFloat, _ := claims["user_id"].(float64)
+	ctx := context.WithValue(r.Context(), userIDKey, int(idFloat))
+	ctx = context.WithValue(ctx, roleKey, role)
+	return r.WithContext(ctx), true
+}
 
-		if err != nil || !token.Valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Token invalide ou expiré"})
+// OptionalAuth renseigne l'utilisateur quand un token valide est fourni, sans rien exiger :
+// les routes publiques (panier invité) restent accessibles, un token invalide est ignoré.
+func OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next(w, r)
 			return
 		}
+		if authed, ok := withToken(r); ok {
+			r = authed
+		}
+		next(w, r)
+	}
+}
+
+// RequireAuth laisse passer tout utilisateur authentifié, quel que soit son rôle
+func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 
-		// Vérification du rôle — seul "admin" est autorisé à continuer
-		role, ok := claims["role"].(string)
-		if !ok || role != "admin" {
-			w.WriteHeader(http.StatusForbidden)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Accès refusé : réservé aux administrateurs"})
+		r = authenticate(w, r)
+		if r == nil {
 			return
 		}
-
-		// Token valide et rôle admin confirmé — on laisse passer la requête
 		next(w, r)
 	}
 }
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// IdempotencyHeader est le header envoyé par le frontend (un UUID par clic sur "Commander")
+const IdempotencyHeader = "Idempotency-Key"
+
+// Taille maximale du corps lu pour calculer l'empreinte de la requête
+const maxIdempotentBody = 1 << 20
+
+// responseRecorder copie la réponse du handler pour pouvoir la rejouer
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotency rejoue la réponse d'origine quand une requête est renvoyée avec le même
+// header Idempotency-Key (double clic, réseau mobile instable). Sans header, la requête
+// passe normalement.
+//   - même clé, même contenu, réponse connue : réponse d'origine + header Idempotent-Replayed
+//   - même clé, contenu différent : 422
+//   - même clé, première requête encore en cours : 409
+//
+// Une réponse 5xx libère la clé pour permettre un nouvel essai.
+func Idempotency(db *sql.DB, scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			writeJSONError(w, http.StatusBadRequest, "Idempotency-Key trop longue (255 caractères max)")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Données invalides")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+
+		// Une clé expirée peut être réutilisée
+		if _, err := db.Exec(
+			"DELETE FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2 AND created_at < NOW() - INTERVAL '24 hours'",
+			scope, key,
+		); err != nil {
+			fmt.Printf("Erreur BDD idempotency : %v\n", err)
+		}
+
+		var id int
+		err = db.QueryRow(`
+            INSERT INTO idempotency_keys (scope, idempotency_key, request_hash)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (scope, idempotency_key) DO NOTHING
+            RETURNING id`,
+			scope, key, hash,
+		).Scan(&id)
+
+		if err == sql.ErrNoRows {
+			replayIdempotent(db, w, scope, key, hash)
+			return
+		} else if err != nil {
+			// La BDD d'idempotence ne doit pas bloquer la commande : on traite sans protection
+			fmt.Printf("Erreur BDD idempotency : %v\n", err)
+			next(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		if rec.status >= 500 || rec.status == 0 {
+			_, err = db.Exec("DELETE FROM idempotency_keys WHERE id = $1", id)
+		} else {
+			_, err = db.Exec(
+				"UPDATE idempotency_keys SET response_status = $1, response_body = $2, completed_at = NOW() WHERE id = $3",
+				rec.status, rec.body.String(), id,
+			)
+		}
+		if err != nil {
+			fmt.Printf("Erreur BDD idempotency id=%d : %v\n", id, err)
+		}
+	}
+}
+
+// replayIdempotent répond à une requête dont la clé est déjà connue
+func replayIdempotent(db *sql.DB, w http.ResponseWriter, scope, key, hash string) {
+	var storedHash string
+	var status sql.NullInt64
+	var body sql.NullString
+	err := db.QueryRow(
+		"SELECT request_hash, response_status, response_body FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2",
+		scope, key,
+	).Scan(&storedHash, &status, &body)
+	if err == sql.ErrNoRows {
+		// Clé libérée entre-temps (erreur 5xx) : le client peut renvoyer
+		writeJSONError(w, http.StatusConflict, "Requête en cours de traitement, merci de réessayer")
+		return
+	} else if err != nil {
+		fmt.Printf("Erreur BDD idempotency : %v\n", err)
+		writeJSONError(w, http.StatusInternalServerError, "Erreur serveur")
+		return
+	}
+
+	if storedHash != hash {
+		writeJSONError(w, http.StatusUnprocessableEntity, "Cette Idempotency-Key a déjà été utilisée pour une requête différente")
+		return
+	}
+	if !status.Valid {
+		writeJSONError(w, http.StatusConflict, "Requête en cours de traitement, merci de réessayer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(status.Int64))
+	io.WriteString(w, body.String)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Permissions vérifiées par RequirePermission, une par domaine du back-office
+const (
+	PermCatalogManage   = "catalog.manage"   // Produits, catégories, coffrets, import / export, upload
+	PermPricingManage   = "pricing.manage"   // Promotions et codes promo
+	PermContentManage   = "content.manage"   // Articles du blog
+	PermReviewsModerate = "reviews.moderate" // Avis clients et questions produit
+	PermInventoryManage = "inventory.manage" // Inventaire, journal des stocks, précommandes
+	PermOrdersRead      = "orders.read"      // Consultation des commandes et paiements
+	PermOrdersManage    = "orders.manage"    // Statuts, annulations, remboursements, livreurs
+	PermShippingManage  = "shipping.manage"  // Zones de livraison et créneaux
+	PermCODCollect      = "cod.collect"      // Encaissements à la livraison
+	PermCODDeliveries   = "cod.deliveries"   // Tournée du livreur connecté
+	PermContactManage   = "contact.manage"   // Messages du formulaire de contact
+	PermPaymentsManage  = "payments.manage"  // Webhooks des prestataires de paiement
+	PermUsersManage     = "users.manage"     // Attribution des rôles
+	PermAuditRead       = "audit.read"       // Journal d'audit
+)
+
+// Permissions dans l'ordre d'affichage (GET /roles)
+var Permissions = []string{
+	PermCatalogManage, PermPricingManage, PermContentManage, PermReviewsModerate, PermInventoryManage,
+	PermOrdersRead, PermOrdersManage, PermShippingManage, PermCODCollect, PermCODDeliveries,
+	PermContactManage, PermPaymentsManage, PermUsersManage, PermAuditRead,
+}
+
+// Rôles connus, dans l'ordre d'affichage : doivent correspondre à la contrainte
+// users_role_check (migration 028)
+var Roles = []string{"customer", "admin", "order_manager", "content_editor", "inventory_manager", "support", "rider"}
+
+// rolePermissions est la matrice rôle → permissions. "admin" a toutes les permissions,
+// "customer" aucune.
+var rolePermissions = map[string][]string{
+	"admin":             Permissions,
+	"order_manager":     {PermOrdersRead, PermOrdersManage, PermShippingManage, PermCODCollect},
+	"content_editor":    {PermCatalogManage, PermContentManage, PermReviewsModerate},
+	"inventory_manager": {PermInventoryManage, PermCatalogManage},
+	"support":           {PermOrdersRead, PermContactManage, PermReviewsModerate},
+	"rider":             {PermCODCollect, PermCODDeliveries},
+}
+
+// RolePermissions renvoie les permissions d'un rôle (vide pour un client ou un rôle inconnu)
+func RolePermissions(role string) []string {
+	perms := rolePermissions[role]
+	if perms == nil {
+		return []string{}
+	}
+	return perms
+}
+
+// HasPermission indique si le rôle donne la permission
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff indique un rôle du back-office (toute permission hors tournée livreur)
+func IsStaff(role string) bool {
+	for _, p := range rolePermissions[role] {
+		if p != PermCODCollect && p != PermCODDeliveries {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole indique si le rôle fait partie des rôles connus
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission laisse passer les utilisateurs authentifiés dont le rôle donne la
+// permission (ex : RequirePermission(db, PermOrdersManage) pour changer un statut de commande).
+// Le rôle est relu dans users à chaque requête, et non pris dans le token : un changement
+// de rôle (PUT /users/{id}/role) s'applique immédiatement. Role(r) renvoie ce rôle à jour.
+func RequirePermission(db *sql.DB, permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			r = authenticate(w, r)
+			if r == nil {
+				return
+			}
+
+			role, err := currentRole(db, UserID(r))
+			if err == sql.ErrNoRows {
+				writeJSONError(w, http.StatusUnauthorized, "Compte introuvable")
+				return
+			} else if err != nil {
+				fmt.Printf("Erreur BDD rôle utilisateur id=%d : %v\n", UserID(r), err)
+				writeJSONError(w, http.StatusInternalServerError, "Erreur serveur")
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), roleKey, role))
+
+			if !HasPermission(role, permission) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"message": "Accès refusé : permission " + permission + " requise"})
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// currentRole lit le rôle actuel de l'utilisateur (RequirePermission, Audit) : celui du
+// JWT peut dater d'avant un PUT /users/{id}/role
+func currentRole(db *sql.DB, userID int) (string, error) {
+	var role string
+	err := db.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	return role, err
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import "testing"
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		role, permission string
+		want             bool
+	}{
+		{"admin", PermUsersManage, true},
+		{"admin", PermCODDeliveries, true},
+		{"order_manager", PermOrdersManage, true},
+		{"order_manager", PermCatalogManage, false},
+		{"content_editor", PermCatalogManage, true},
+		{"content_editor", PermOrdersRead, false},
+		{"inventory_manager", PermInventoryManage, true},
+		{"inventory_manager", PermPricingManage, false},
+		{"support", PermContactManage, true},
+		{"support", PermOrdersManage, false},
+		{"rider", PermCODDeliveries, true},
+		{"rider", PermOrdersRead, false},
+		{"customer", PermOrdersRead, false},
+		{"", PermOrdersRead, false},
+		{"inconnu", PermCatalogManage, false},
+		{"admin", "permission.inconnue", false},
+	}
+
+	for _, tt := range tests {
+		if got := HasPermission(tt.role, tt.permission); got != tt.want {
+			t.Errorf("HasPermission(%q, %q) = %v, %v attendu", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+// La matrice doit rester cohérente avec Roles et Permissions (GET /roles, users_role_check)
+func TestRolePermissionsMatrix(t *testing.T) {
+	known := make(map[string]bool)
+	for _, p := range Permissions {
+		known[p] = true
+	}
+	for role, perms := range rolePermissions {
+		if !ValidRole(role) {
+			t.Errorf("rôle %q absent de Roles", role)
+		}
+		for _, p := range perms {
+			if !known[p] {
+				t.Errorf("rôle %q : permission %q absente de Permissions", role, p)
+			}
+		}
+	}
+
+	if got := RolePermissions("admin"); len(got) != len(Permissions) {
+		t.Errorf("admin : %d permissions, %d attendues", len(got), len(Permissions))
+	}
+	if got := RolePermissions("customer"); got == nil || len(got) != 0 {
+		t.Errorf("customer : %v, liste vide attendue", got)
+	}
+	if got := RolePermissions("inconnu"); got == nil || len(got) != 0 {
+		t.Errorf("rôle inconnu : %v, liste vide attendue", got)
+	}
+}
+
+func TestIsStaff(t *testing.T) {
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{"admin", true},
+		{"order_manager", true},
+		{"content_editor", true},
+		{"inventory_manager", true},
+		{"support", true},
+		{"rider", false},
+		{"customer", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsStaff(tt.role); got != tt.want {
+			t.Errorf("IsStaff(%q) = %v, %v attendu", tt.role, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry est une écriture admin journalisée (GET /audit-log)
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	UserID     *int            `json:"user_id"`
+	UserEmail  *string         `json:"user_email"` // null si le compte a été supprimé
+	Action     string          `json:"action"`     // "create", "update", "delete", "bulk", "items.cancel"...
+	EntityType string          `json:"entity_type"`
+	EntityID   *int            `json:"entity_id"` // null pour une action groupée ou un import
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Changes    json.RawMessage `json:"changes"` // { "champ": { "from": ..., "to": ... } }
+	IPAddress  string          `json:"ip_address"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
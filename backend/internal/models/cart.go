@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Cart est le panier renvoyé au frontend : prix et stock recalculés à chaque lecture
+type Cart struct {
+	ID        string         `json:"id"`
+	UserID    *int           `json:"user_id"`
+	Status    string         `json:"status"` // "active" | "converted" | "merged"
+	Items     []CartLineView `json:"items"`
+	ItemCount int            `json:"item_count"`
+	Subtotal  float64        `json:"subtotal"`
+	// Vrai si au moins un article non précommandable dépasse le stock (la commande sera refusée)
+	HasStockIssues bool      `json:"has_stock_issues"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CartLineView est une ligne du panier avec le prix du moment
+type CartLineView struct {
+	ProductID        int               `json:"product_id"`
+	Name             string            `json:"name"`
+	ImageURL         string            `json:"image_url"`
+	Price            float64           `json:"price"`       // Prix catalogue
+	FinalPrice       float64           `json:"final_price"` // Après promotion
+	AppliedPromotion *AppliedPromotion `json:"applied_promotion"`
+	Quantity         int               `json:"quantity"`
+	LineTotal        float64           `json:"line_total"`
+	StockQuantity    int               `json:"stock_quantity"`
+	InStock          bool              `json:"in_stock"` // quantity <= stock_quantity
+	Preorder         bool              `json:"preorder"` // Hors stock mais précommandable
+}
+
+// Ce que le frontend envoie pour ajouter un article ou changer sa quantité
+type CartItemInput struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Contact d'un panier invité, envoyé par le checkout dès que l'email ou le téléphone est saisi
+type CartContactInput struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// AbandonedCartStats est le rapport des relances de paniers abandonnés sur une période
+type AbandonedCartStats struct {
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	RemindersSent    int     `json:"reminders_sent"`
+	RemindersFailed  int     `json:"reminders_failed"`
+	Restored         int     `json:"restored"`  // Liens de restauration ouverts
+	Recovered        int     `json:"recovered"` // Paniers relancés puis commandés
+	RecoveryRate     float64 `json:"recovery_rate"`
+	RecoveredRevenue float64 `json:"recovered_revenue"`
+}
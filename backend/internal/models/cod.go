@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Ce que l'admin envoie pour assigner un livreur à une commande
+type AssignRiderRequest struct {
+	RiderID int `json:"rider_id"`
+}
+
+// Ce que le livreur (ou l'admin) déclare après encaissement
+type CODCollectionInput struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+}
+
+// CODOrder est une commande en paiement à la livraison vue par le livreur et le rapprochement
+type CODOrder struct {
+	OrderID         int        `json:"order_id"`
+	CustomerName    string     `json:"customer_name"`
+	CustomerPhone   string     `json:"customer_phone"`
+	ShippingCity    string     `json:"shipping_city"`
+	ShippingCommune string     `json:"shipping_commune"`
+	ShippingAddress string     `json:"shipping_address"`
+	Status          string     `json:"status"`
+	RiderID         *int       `json:"rider_id"`
+	AssignedAt      *time.Time `json:"assigned_at"`
+	ExpectedAmount  float64    `json:"expected_amount"`
+	CollectedAmount *float64   `json:"collected_amount"` // null tant que rien n'est déclaré
+	CollectedAt     *time.Time `json:"collected_at"`
+	Discrepancy     *float64   `json:"discrepancy"` // encaissé - attendu
+	Flagged         bool       `json:"flagged"`     // true si l'écart est non nul
+}
+
+// RiderReconciliation est le rapprochement d'une journée pour un livreur
+type RiderReconciliation struct {
+	RiderID          int        `json:"rider_id"`
+	RiderName        string     `json:"rider_name"`
+	Date             string     `json:"date"`
+	OrdersCount      int        `json:"orders_count"`
+	CollectedCount   int        `json:"collected_count"`
+	ExpectedTotal    float64    `json:"expected_total"`
+	CollectedTotal   float64    `json:"collected_total"`
+	OutstandingTotal float64    `json:"outstanding_total"` // attendu sur les commandes non encore déclarées
+	Difference       float64    `json:"difference"`        // somme des écarts des commandes déclarées
+	FlaggedCount     int        `json:"flagged_count"`
+	Orders           []CODOrder `json:"orders"`
+}
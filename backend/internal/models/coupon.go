@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Coupon représente un code promo saisi au checkout (ex: BIENVENUE10)
+type Coupon struct {
+	ID                 int        `json:"id"`
+	Code               string     `json:"code"`
+	Description        string     `json:"description"`
+	DiscountType       string     `json:"discount_type"` // "percent" | "fixed" | "free_shipping"
+	DiscountValue      float64    `json:"discount_value"`
+	MinBasket          float64    `json:"min_basket"`
+	MaxUses            *int       `json:"max_uses"`              // nil = illimité
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer"` // nil = illimité
+	UsedCount          int        `json:"used_count"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IsActive           bool       `json:"is_active"`
+	CategoryIDs        []int      `json:"category_ids"` // vide = tout le catalogue
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Ce que l'admin envoie pour créer / modifier un code promo
+type CouponInput struct {
+	Code               string     `json:"code"`
+	Description        string     `json:"description"`
+	DiscountType       string     `json:"discount_type"`
+	DiscountValue      float64    `json:"discount_value"`
+	MinBasket          float64    `json:"min_basket"`
+	MaxUses            *int       `json:"max_uses"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IsActive           *bool      `json:"is_active"` // true par défaut
+	CategoryIDs        []int      `json:"category_ids"`
+}
+
+// Ce que le frontend envoie pour vérifier un code avant de commander
+type ValidateCouponRequest struct {
+	Code  string     `json:"code"`
+	Email string     `json:"email"`
+	Items []CartItem `json:"items"`
+}
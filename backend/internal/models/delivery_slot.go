@@ -0,0 +1,26 @@
+package models
+
+// DeliverySlot représente un créneau de livraison à domicile ou de retrait magasin
+type DeliverySlot struct {
+	ID             int    `json:"id"`
+	SlotType       string `json:"slot_type"` // "delivery" | "pickup"
+	ShippingZoneID *int   `json:"shipping_zone_id"`
+	SlotDate       string `json:"slot_date"`  // "2026-10-20"
+	StartTime      string `json:"start_time"` // "09:00"
+	EndTime        string `json:"end_time"`   // "12:00"
+	Capacity       int    `json:"capacity"`
+	BookedCount    int    `json:"booked_count"`
+	Remaining      int    `json:"remaining"`
+	IsActive       bool   `json:"is_active"`
+}
+
+// Ce que l'admin envoie pour créer / modifier un créneau
+type DeliverySlotInput struct {
+	SlotType       string `json:"slot_type"`
+	ShippingZoneID *int   `json:"shipping_zone_id"`
+	SlotDate       string `json:"slot_date"`
+	StartTime      string `json:"start_time"`
+	EndTime        string `json:"end_time"`
+	Capacity       int    `json:"capacity"`
+	IsActive       *bool  `json:"is_active"` // true par défaut
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// InventoryItem est une ligne du tableau de bord des stocks (GET /inventory)
+type InventoryItem struct {
+	ProductID         int     `json:"product_id"`
+	Name              string  `json:"name"`
+	SKU               string  `json:"sku"`
+	StockQuantity     int     `json:"stock_quantity"`
+	LowStockThreshold int     `json:"low_stock_threshold"`
+	IsLow             bool    `json:"is_low"` // stock_quantity <= low_stock_threshold
+	UnitsSold         int     `json:"units_sold"`
+	DailySales        float64 `json:"daily_sales"`
+	// Jours avant rupture au rythme des ventes récentes ; null sans vente sur la période
+	DaysOfCover *float64   `json:"days_of_cover"`
+	LastAlertAt *time.Time `json:"last_alert_at"`
+}
+
+// Seuil d'alerte envoyé par l'admin
+type LowStockThresholdRequest struct {
+	LowStockThreshold *int `json:"low_stock_threshold"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Ce que le Frontend envoie lors du checkout
 type OrderRequest struct {
@@ -15,21 +18,32 @@ type OrderRequest struct {
 	ShippingCity    string `json:"shipping_city"`
 	ShippingCommune string `json:"shipping_commune"`
 	ShippingAddress string `json:"shipping_address"`
+	DeliverySlotID  *int   `json:"delivery_slot_id"` // Créneau choisi (optionnel)
 
 	// Options
 	CreateAccount bool   `json:"create_account"`
 	Password      string `json:"password"` // Non stocké dans la table orders
 	OrderNote     string `json:"order_note"`
 
-	// Panier
-	Items []CartItem `json:"items"`
-	Total float64    `json:"total"`
+	// Paiement : "cod" (à la livraison, par défaut) ou un prestataire en ligne
+	// ("orange_money", "mtn_momo", "wave", "card")
+	PaymentMethod string `json:"payment_method"`
+
+	// Code promo (optionnel)
+	CouponCode string `json:"coupon_code"`
+
+	// Panier : soit le panier serveur (cart_id, voir /cart), soit la liste des articles
+	CartID string     `json:"cart_id"`
+	Items  []CartItem `json:"items"`
+	Total  float64    `json:"total"` // Indicatif : le total est recalculé côté serveur
 }
 
 type CartItem struct {
 	ID       int     `json:"id"` // ID du produit
 	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
+	Price    float64 `json:"price"` // Indicatif : le prix est recalculé côté serveur
+	// Article offert depuis une liste de naissance (optionnel)
+	RegistryItemID *int `json:"registry_item_id,omitempty"`
 }
 
 // MODÈLES BASE DE DONNÉES
@@ -47,18 +61,72 @@ type Order struct {
 	ShippingCommune string `json:"shipping_commune"`
 	ShippingAddress string `json:"shipping_address"`
 
-	OrderNote   string  `json:"order_note"`
-	TotalAmount float64 `json:"total_amount"`
-	Status      string  `json:"status"`
+	OrderNote      string  `json:"order_note"`
+	SubtotalAmount float64 `json:"subtotal_amount"`
+	DiscountAmount float64 `json:"discount_amount"`
+	CouponCode     *string `json:"coupon_code"`
+	ShippingFee    float64 `json:"shipping_fee"`
+	ShippingZoneID *int    `json:"shipping_zone_id"`
+	DeliverySlotID *int    `json:"delivery_slot_id"`
+	TotalAmount    float64 `json:"total_amount"`
+	PaymentMethod  string  `json:"payment_method"`
+	Status         string  `json:"status"`
 
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // Représente une ligne de la table 'order_items'
+// Les champs Product* sont un snapshot figé à l'achat : ils ne suivent pas
+// les modifications ultérieures du catalogue (renommage, suppression...).
 type OrderItem struct {
-	ID        int     `json:"id"`
-	OrderID   int     `json:"order_id"`
-	ProductID int     `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ID               int      `json:"id"`
+	OrderID          int      `json:"order_id"`
+	ProductID        *int     `json:"product_id"` // NULL si le produit a été supprimé depuis
+	Quantity         int      `json:"quantity"`
+	Price            float64  `json:"price"`
+	ProductName      string   `json:"product_name"`
+	ProductSKU       string   `json:"product_sku"`
+	ProductImageURL  string   `json:"product_image_url"`
+	OriginalPrice    float64  `json:"original_price"`
+	PromotionPercent *float64 `json:"promotion_percent"`
+	PromotionID      *int     `json:"promotion_id"`
+	PromotionName    *string  `json:"promotion_name"`
+}
+
+// Refund représente un remboursement (table 'refunds')
+type Refund struct {
+	ID            int       `json:"id"`
+	OrderID       int       `json:"order_id"`
+	PaymentID     *int      `json:"payment_id"`
+	Amount        float64   `json:"amount"`
+	Method        string    `json:"method"` // "provider" | "cash"
+	Reason        *string   `json:"reason"`
+	Status        string    `json:"status"`         // "pending" | "completed" | "failed"
+	FailureReason *string   `json:"failure_reason"` // Réponse du prestataire si refusé
+	CreatedBy     *int      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// OrderHistoryEntry représente une ligne du journal d'une commande (table 'order_history')
+type OrderHistoryEntry struct {
+	ID        int             `json:"id"`
+	OrderID   int             `json:"order_id"`
+	Action    string          `json:"action"` // "status_changed" | "item_cancelled" | "refund"
+	Message   string          `json:"message"`
+	Details   json.RawMessage `json:"details"`
+	ActorID   *int            `json:"actor_id"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Ce que l'admin envoie pour annuler tout ou partie d'une ligne de commande
+type CancelItemRequest struct {
+	Quantity *int   `json:"quantity"` // Optionnel : toutes les unités restantes par défaut
+	Reason   string `json:"reason"`
+	Restock  *bool  `json:"restock"` // true par défaut : remise en stock
+}
+
+// Ce que l'admin envoie pour rembourser une commande
+type OrderRefundRequest struct {
+	Amount *float64 `json:"amount"` // Optionnel : tout le montant remboursable par défaut
+	Reason string   `json:"reason"`
 }
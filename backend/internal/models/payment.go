@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Payment représente une tentative de paiement en ligne d'une commande
+type Payment struct {
+	ID                int       `json:"id"`
+	OrderID           int       `json:"order_id"`
+	Provider          string    `json:"provider"`
+	MerchantReference string    `json:"merchant_reference"`
+	ProviderReference *string   `json:"provider_reference"`
+	Amount            float64   `json:"amount"`
+	Currency          string    `json:"currency"`
+	Phone             *string   `json:"phone"`
+	Status            string    `json:"status"` // "pending" | "succeeded" | "failed" | "refunded"
+	RefundedAmount    float64   `json:"refunded_amount"`
+	CheckoutURL       *string   `json:"checkout_url"`
+	FailureReason     *string   `json:"failure_reason"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Ce que le checkout envoie pour lancer (ou relancer) le paiement d'une commande.
+// L'email doit correspondre à celui de la commande (le client n'est pas forcément connecté).
+type InitiatePaymentRequest struct {
+	OrderID  int    `json:"order_id"`
+	Email    string `json:"email"`
+	Provider string `json:"provider"` // Optionnel : payment_method de la commande par défaut
+	Phone    string `json:"phone"`
+}
+
+// Ce que l'admin envoie pour rembourser un paiement
+type RefundPaymentRequest struct {
+	Amount *float64 `json:"amount"` // Optionnel : reste à rembourser par défaut
+	Reason string   `json:"reason"`
+}
+
+// PaymentWebhookEvent est une notification brute reçue d'un prestataire
+type PaymentWebhookEvent struct {
+	ID             int        `json:"id"`
+	Provider       string     `json:"provider"`
+	EventID        *string    `json:"event_id"`
+	SignatureValid bool       `json:"signature_valid"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"` // "received" | "processed" | "failed" | "rejected"
+	Error          *string    `json:"error"`
+	PaymentID      *int       `json:"payment_id"`
+	Attempts       int        `json:"attempts"`
+	ReceivedAt     time.Time  `json:"received_at"`
+	ProcessedAt    *time.Time `json:"processed_at"`
+}
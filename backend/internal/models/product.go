@@ -1,8 +1,11 @@
 package models
 
+import "time"
+
 type Product struct {
 	ID               int      `json:"id"`
 	Name             string   `json:"name"`
+	SKU              string   `json:"sku"`
 	Description      string   `json:"description"`
 	Price            float64  `json:"price"`
 	StockQuantity    int      `json:"stock_quantity"`
@@ -10,4 +13,77 @@ type Product struct {
 	CategoryID       int      `json:"category_id"`
 	SubcategoryID    *int     `json:"subcategory_id"`
 	PromotionPercent *float64 `json:"promotion_percent"`
+
+	// Précommande : commandable sans stock jusqu'au plafond, servie à la réception
+	PreorderEnabled     bool    `json:"preorder_enabled"`
+	PreorderAvailableAt *string `json:"preorder_available_at"` // "2026-11-15"
+	PreorderCap         *int    `json:"preorder_cap"`
+
+	// Coffret : stock_quantity est calculé à partir des composants
+	IsBundle    bool              `json:"is_bundle"`
+	BundleItems []BundleComponent `json:"bundle_items,omitempty"` // GetProduct uniquement
+
+	// Archivé : hors catalogue public, ni panier ni commande ; null = actif
+	ArchivedAt *time.Time `json:"archived_at"`
+
+	// Calculés à la lecture : meilleur prix parmi les promotions actives
+	FinalPrice       float64           `json:"final_price"`
+	AppliedPromotion *AppliedPromotion `json:"applied_promotion"`
+
+	// Calculés à la lecture : avis approuvés uniquement
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
+
+	// Questions répondues et publiques (GetProduct uniquement)
+	Questions []ProductQuestion `json:"questions,omitempty"`
+}
+
+// Ce que l'admin envoie pour modifier un produit (PUT /products/{id}).
+// stock_quantity n'est pris en compte qu'avec expected_stock_quantity, le stock lu par
+// le formulaire : un formulaire resté ouvert pendant des ventes ne les annule pas.
+type ProductUpdateRequest struct {
+	Product
+	ExpectedStockQuantity *int `json:"expected_stock_quantity"`
+}
+
+// Réglages de précommande envoyés par l'admin (PUT /products/{id}/preorder)
+type PreorderSettingsRequest struct {
+	Enabled     bool    `json:"enabled"`
+	AvailableAt *string `json:"available_at"` // "2026-11-15", optionnel
+	Cap         *int    `json:"cap"`          // Unités en attente maximum, null = illimité
+}
+
+// PreorderLine est une ligne de commande en précommande (GET /preorders)
+type PreorderLine struct {
+	ItemID        int     `json:"item_id"`
+	OrderID       int     `json:"order_id"`
+	OrderStatus   string  `json:"order_status"`
+	CustomerName  string  `json:"customer_name"`
+	CustomerEmail string  `json:"customer_email"`
+	ProductID     *int    `json:"product_id"`
+	ProductName   string  `json:"product_name"`
+	Quantity      int     `json:"quantity"` // Hors unités annulées
+	OrderedAt     string  `json:"ordered_at"`
+	AvailableAt   *string `json:"available_at"`
+	AllocatedAt   *string `json:"allocated_at"` // null : en attente de stock
+}
+
+// BundleComponent est un produit composant un coffret
+type BundleComponent struct {
+	ProductID     int     `json:"product_id"`
+	Name          string  `json:"name"`
+	ImageURL      string  `json:"image_url"`
+	Price         float64 `json:"price"`
+	Quantity      int     `json:"quantity"`
+	StockQuantity int     `json:"stock_quantity"`
+}
+
+// Composition d'un coffret envoyée par l'admin (liste vide : le produit n'est plus un coffret)
+type BundleRequest struct {
+	Items []BundleItemInput `json:"items"`
+}
+
+type BundleItemInput struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
 }
@@ -0,0 +1,47 @@
+package models
+
+// Action groupée sur des produits (POST /products/bulk)
+type ProductBulkRequest struct {
+	Action string `json:"action"` // "set_category" | "archive" | "unarchive" | "adjust_price" | "set_stock"
+
+	// Sélection : liste d'IDs OU filtre (exclusifs)
+	ProductIDs []int              `json:"product_ids"`
+	Filter     *ProductBulkFilter `json:"filter"`
+
+	// Paramètres selon l'action
+	CategoryID    *int     `json:"category_id"`    // set_category
+	SubcategoryID *int     `json:"subcategory_id"` // set_category, null = aucune
+	Percent       *float64 `json:"percent"`        // adjust_price : +10 = hausse de 10 %, -15 = baisse
+	Stock         *int     `json:"stock"`          // set_stock
+	Reason        string   `json:"reason"`         // set_stock : motif du mouvement 'correction'
+
+	DryRun bool `json:"dry_run"`
+}
+
+// Filtre de sélection : au moins un critère
+type ProductBulkFilter struct {
+	CategoryID    *int   `json:"category_id"`
+	SubcategoryID *int   `json:"subcategory_id"`
+	Search        string `json:"search"`   // Nom ou SKU (contient)
+	Archived      *bool  `json:"archived"` // null = actifs et archivés
+}
+
+type ProductBulkResult struct {
+	Action    string            `json:"action"`
+	DryRun    bool              `json:"dry_run"`
+	Matched   int               `json:"matched"`
+	Succeeded int               `json:"succeeded"` // Modifiés (ou qui le seraient en dry-run)
+	Unchanged int               `json:"unchanged"` // Déjà dans l'état demandé
+	Failed    int               `json:"failed"`
+	Items     []ProductBulkItem `json:"items"`
+}
+
+// Résultat par produit : une seule erreur annule toute l'action
+type ProductBulkItem struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"` // "ok" | "unchanged" | "error"
+	OldValue  string `json:"old_value,omitempty"`
+	NewValue  string `json:"new_value,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
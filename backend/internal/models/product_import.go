@@ -0,0 +1,17 @@
+package models
+
+// Résultat d'un import CSV du catalogue (POST /products/import)
+type ProductImportResult struct {
+	DryRun  bool                 `json:"dry_run"`
+	Rows    int                  `json:"rows"`    // Lignes de données lues (hors en-tête)
+	Created int                  `json:"created"` // Produits créés (ou qui le seraient en dry-run)
+	Updated int                  `json:"updated"` // Produits mis à jour (SKU existant)
+	Errors  []ProductImportError `json:"errors"`
+}
+
+// Erreur rattachée à une ligne du fichier : rien n'est écrit tant qu'il en reste une
+type ProductImportError struct {
+	Line    int    `json:"line"` // Numéro de ligne dans le fichier (en-tête = 1)
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
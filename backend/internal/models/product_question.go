@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ProductQuestion est une question posée sur un produit et sa réponse
+type ProductQuestion struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"product_id"`
+	ProductName string     `json:"product_name,omitempty"` // Liste admin uniquement
+	AskerName   string     `json:"asker_name"`
+	AskerEmail  string     `json:"asker_email,omitempty"` // Jamais renvoyé publiquement
+	Question    string     `json:"question"`
+	Answer      *string    `json:"answer"`
+	AnsweredAt  *time.Time `json:"answered_at"`
+	IsPublic    bool       `json:"is_public"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Ce que le client envoie pour poser une question (nom et email obligatoires sans compte)
+type ProductQuestionInput struct {
+	Question string `json:"question"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+}
+
+// Ce que l'admin envoie pour répondre
+type AnswerQuestionRequest struct {
+	Answer   string `json:"answer"`
+	IsPublic *bool  `json:"is_public"` // true par défaut
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Promotion représente une campagne programmée (ex: "Fête des mères")
+type Promotion struct {
+	ID            int               `json:"id"`
+	Name          string            `json:"name"`
+	DiscountType  string            `json:"discount_type"` // "percent" | "fixed"
+	DiscountValue float64           `json:"discount_value"`
+	StartsAt      time.Time         `json:"starts_at"`
+	EndsAt        time.Time         `json:"ends_at"`
+	IsActive      bool              `json:"is_active"`
+	Status        string            `json:"status"` // calculé : "scheduled" | "active" | "expired" | "disabled"
+	Targets       []PromotionTarget `json:"targets"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// PromotionTarget désigne un produit, une catégorie ou une sous-catégorie ciblée
+type PromotionTarget struct {
+	TargetType string `json:"target_type"` // "product" | "category" | "subcategory"
+	TargetID   int    `json:"target_id"`
+}
+
+// AppliedPromotion est la promotion retenue lors du calcul du prix d'un produit.
+// ID vaut nil pour la promotion immédiate historique (products.promotion_percent).
+type AppliedPromotion struct {
+	ID            *int    `json:"id"`
+	Name          string  `json:"name"`
+	DiscountType  string  `json:"discount_type"`
+	DiscountValue float64 `json:"discount_value"`
+}
+
+// Ce que l'admin envoie pour créer / modifier une campagne
+type PromotionInput struct {
+	Name          string            `json:"name"`
+	DiscountType  string            `json:"discount_type"`
+	DiscountValue float64           `json:"discount_value"`
+	StartsAt      time.Time         `json:"starts_at"` // RFC 3339, ex: "2026-05-31T00:00:00Z"
+	EndsAt        time.Time         `json:"ends_at"`
+	IsActive      *bool             `json:"is_active"` // true par défaut
+	Targets       []PromotionTarget `json:"targets"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Registry est une liste de naissance partagée par un lien public
+type Registry struct {
+	ID          int            `json:"id"`
+	UserID      int            `json:"user_id"`
+	Title       string         `json:"title"`
+	DueDate     *string        `json:"due_date"` // "2026-12-15"
+	Message     string         `json:"message"`
+	PublicToken string         `json:"public_token"`
+	IsActive    bool           `json:"is_active"`
+	Items       []RegistryItem `json:"items"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// RegistryItem est un produit souhaité, avec le prix du moment
+type RegistryItem struct {
+	ID                int               `json:"id"`
+	ProductID         int               `json:"product_id"`
+	ProductName       string            `json:"product_name"`
+	ImageURL          string            `json:"image_url"`
+	Price             float64           `json:"price"`
+	FinalPrice        float64           `json:"final_price"`
+	AppliedPromotion  *AppliedPromotion `json:"applied_promotion"`
+	StockQuantity     int               `json:"stock_quantity"`
+	DesiredQuantity   int               `json:"desired_quantity"`
+	PurchasedQuantity int               `json:"purchased_quantity"`
+	Remaining         int               `json:"remaining"`
+	Note              string            `json:"note"`
+	// Achats de l'article (visible uniquement par le propriétaire de la liste)
+	Purchases []RegistryPurchase `json:"purchases,omitempty"`
+}
+
+// RegistryPurchase est un achat effectué depuis la liste
+type RegistryPurchase struct {
+	OrderID   int       `json:"order_id"`
+	BuyerName string    `json:"buyer_name"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Ce que le client envoie pour créer / modifier une liste
+type RegistryInput struct {
+	Title    string  `json:"title"`
+	DueDate  *string `json:"due_date"`
+	Message  string  `json:"message"`
+	IsActive *bool   `json:"is_active"` // true par défaut
+}
+
+// Ce que le client envoie pour ajouter / modifier un produit de la liste
+type RegistryItemInput struct {
+	ProductID       int    `json:"product_id"`
+	DesiredQuantity int    `json:"desired_quantity"`
+	Note            string `json:"note"`
+}
+
+// PublicRegistry est la vue partagée de la liste (sans données personnelles ni acheteurs)
+type PublicRegistry struct {
+	Title     string         `json:"title"`
+	OwnerName string         `json:"owner_name"` // Prénom du propriétaire
+	DueDate   *string        `json:"due_date"`
+	Message   string         `json:"message"`
+	Items     []RegistryItem `json:"items"`
+}
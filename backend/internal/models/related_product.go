@@ -0,0 +1,21 @@
+package models
+
+// RelatedProduct est un produit proposé sur la fiche d'un autre produit
+type RelatedProduct struct {
+	Product
+	// "manual" (choisi par l'admin) ou "subcategory" (même sous-catégorie)
+	Source string `json:"source,omitempty"`
+	// Achats conjoints uniquement : nombre de commandes contenant les deux produits
+	OrderCount int `json:"order_count,omitempty"`
+}
+
+// RelatedProducts est la réponse de GET /products/{id}/related
+type RelatedProducts struct {
+	Related                  []RelatedProduct `json:"related"`
+	FrequentlyBoughtTogether []RelatedProduct `json:"frequently_bought_together"`
+}
+
+// Liens manuels envoyés par l'admin, dans l'ordre d'affichage
+type ProductLinksRequest struct {
+	ProductIDs []int `json:"product_ids"`
+}
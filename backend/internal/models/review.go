@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Review est un avis client sur un produit
+type Review struct {
+	ID               int        `json:"id"`
+	ProductID        int        `json:"product_id"`
+	ProductName      string     `json:"product_name,omitempty"` // Liste admin uniquement
+	UserID           int        `json:"user_id"`
+	AuthorName       string     `json:"author_name"` // Prénom + initiale du nom
+	Rating           int        `json:"rating"`
+	Title            string     `json:"title"`
+	Comment          string     `json:"comment"`
+	PhotoURLs        []string   `json:"photo_urls"`
+	VerifiedPurchase bool       `json:"verified_purchase"`
+	Status           string     `json:"status"` // "pending" | "approved" | "rejected"
+	ModerationNote   string     `json:"moderation_note,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Ce que le client envoie pour publier un avis
+type ReviewInput struct {
+	Rating    int      `json:"rating"`
+	Title     string   `json:"title"`
+	Comment   string   `json:"comment"`
+	PhotoURLs []string `json:"photo_urls"`
+}
+
+// Ce que l'admin envoie pour modérer un avis
+type ModerateReviewRequest struct {
+	Status string `json:"status"` // "approved" | "rejected"
+	Note   string `json:"note"`
+}
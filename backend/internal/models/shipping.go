@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ShippingZone représente une zone de livraison : une commune d'Abidjan
+// (city + commune) ou une ville de l'intérieur (commune nil)
+type ShippingZone struct {
+	ID                    int       `json:"id"`
+	Name                  string    `json:"name"`
+	City                  string    `json:"city"`
+	Commune               *string   `json:"commune"`
+	Fee                   float64   `json:"fee"`
+	FreeShippingThreshold *float64  `json:"free_shipping_threshold"`
+	IsActive              bool      `json:"is_active"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// Ce que l'admin envoie pour créer / modifier une zone
+type ShippingZoneInput struct {
+	Name                  string   `json:"name"`
+	City                  string   `json:"city"`
+	Commune               *string  `json:"commune"`
+	Fee                   float64  `json:"fee"`
+	FreeShippingThreshold *float64 `json:"free_shipping_threshold"`
+	IsActive              *bool    `json:"is_active"` // true par défaut
+}
+
+// Ce que le checkout envoie pour obtenir le détail du total avant de commander
+type ShippingQuoteRequest struct {
+	DeliveryMethod  string     `json:"delivery_method"`
+	ShippingCity    string     `json:"shipping_city"`
+	ShippingCommune string     `json:"shipping_commune"`
+	CouponCode      string     `json:"coupon_code"`
+	Email           string     `json:"email"`
+	Items           []CartItem `json:"items"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// StockMovement est une ligne du journal des stocks
+type StockMovement struct {
+	ID             int       `json:"id"`
+	ProductID      int       `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	Type           string    `json:"type"` // "initial" | "sale" | "cancellation" | "restock" | "damage" | "correction" | "count"
+	QuantityChange int       `json:"quantity_change"`
+	StockAfter     int       `json:"stock_after"`
+	OrderID        *int      `json:"order_id"`
+	ActorID        *int      `json:"actor_id"`
+	Reason         string    `json:"reason"`
+	Reference      string    `json:"reference"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Réception fournisseur : une entrée 'restock' par ligne
+type StockReceptionRequest struct {
+	Supplier  string              `json:"supplier"`
+	Reference string              `json:"reference"` // Bon de livraison, facture...
+	Note      string              `json:"note"`
+	Items     []StockQuantityLine `json:"items"`
+}
+
+type StockQuantityLine struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Inventaire physique : quantités comptées, l'écart devient un mouvement 'count'
+type StockCountRequest struct {
+	Note  string           `json:"note"`
+	Items []StockCountLine `json:"items"`
+}
+
+type StockCountLine struct {
+	ProductID       int `json:"product_id"`
+	CountedQuantity int `json:"counted_quantity"`
+}
+
+// Ajustement ponctuel : casse, péremption ou correction
+type StockAdjustmentRequest struct {
+	ProductID      int    `json:"product_id"`
+	QuantityChange int    `json:"quantity_change"`
+	Type           string `json:"type"` // "damage" | "correction"
+	Reason         string `json:"reason"`
+}
+
+// StockDiscrepancy est un produit dont le stock ne correspond pas au journal
+type StockDiscrepancy struct {
+	ProductID     int    `json:"product_id"`
+	Name          string `json:"name"`
+	StockQuantity int    `json:"stock_quantity"`
+	LedgerStock   int    `json:"ledger_stock"` // SUM(quantity_change)
+	Difference    int    `json:"difference"`   // stock_quantity - ledger_stock
+}
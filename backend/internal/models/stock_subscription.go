@@ -0,0 +1,16 @@
+package models
+
+// Inscription "prévenez-moi du retour en stock" (email ou téléphone)
+type StockSubscriptionInput struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// StockDemand est la demande en attente sur un produit en rupture (GET /stock-alerts)
+type StockDemand struct {
+	ProductID     int    `json:"product_id"`
+	Name          string `json:"name"`
+	StockQuantity int    `json:"stock_quantity"`
+	Subscribers   int    `json:"subscribers"`
+	OldestRequest string `json:"oldest_request"`
+}
@@ -8,7 +8,7 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	FullName     string    `json:"full_name"`
 	Phone        string    `json:"phone"`
-	Role         string    `json:"role"` // ex: "user", "admin"
+	Role         string    `json:"role"` // "customer", "admin", "order_manager", "content_editor", "inventory_manager", "support", "rider"
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -24,4 +24,16 @@ type SignupInput struct {
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	CartID   string `json:"cart_id"` // Panier invité à fusionner dans celui du compte (optionnel)
+}
+
+// Rôle et permissions associées (GET /roles)
+type RoleInfo struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// Attribution d'un rôle par un admin (PUT /users/{id}/role)
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
 }
@@ -0,0 +1,191 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Canaux d'envoi, identiques à la colonne notifications.channel
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook" // Alertes admin uniquement
+)
+
+// ErrNoChannel est renvoyée quand aucun canal configuré ne permet de joindre le destinataire
+var ErrNoChannel = errors.New("aucun canal de notification disponible pour ce destinataire")
+
+// Message est une notification à envoyer. L'email est préféré au SMS quand les deux
+// sont connus et configurés ; SMSBody remplace Body pour le SMS (texte court).
+type Message struct {
+	Kind    string // Type métier : "abandoned_cart", ... (colonne notifications.kind)
+	Email   string
+	Phone   string
+	Subject string
+	Body    string
+	SMSBody string
+}
+
+// Sender est implémenté par chaque canal (SMTP, passerelle SMS, log)
+type Sender interface {
+	Channel() string
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Notifier choisit le canal, envoie et journalise chaque envoi dans la table notifications
+type Notifier struct {
+	db      *sql.DB
+	senders map[string]Sender
+
+	// Destinataires des alertes admin (Alert)
+	adminEmails []string
+	webhookURL  string
+}
+
+func NewNotifier(db *sql.DB, senders ...Sender) *Notifier {
+	n := &Notifier{db: db, senders: make(map[string]Sender)}
+	for _, s := range senders {
+		n.senders[s.Channel()] = s
+	}
+	return n
+}
+
+// NewNotifierFromEnv enregistre les canaux dont les variables d'environnement sont définies.
+// NOTIFICATIONS_LOG_ONLY=true remplace tous les canaux par un simple log (développement et recette).
+// Alertes admin : ADMIN_ALERT_EMAILS (liste séparée par des virgules) et ALERT_WEBHOOK_URL.
+func NewNotifierFromEnv(db *sql.DB) *Notifier {
+	var n *Notifier
+	if os.Getenv("NOTIFICATIONS_LOG_ONLY") == "true" {
+		n = NewNotifier(db, logSender{ChannelEmail}, logSender{ChannelSMS}, logSender{ChannelWebhook})
+	} else {
+		n = NewNotifier(db)
+		if s := smtpSenderFromEnv(); s != nil {
+			n.senders[s.Channel()] = s
+		}
+		if s := smsSenderFromEnv(); s != nil {
+			n.senders[s.Channel()] = s
+		}
+		if webhookURLFromEnv() != "" {
+			n.senders[ChannelWebhook] = newWebhookSender()
+		}
+	}
+	for _, email := range strings.Split(os.Getenv("ADMIN_ALERT_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			n.adminEmails = append(n.adminEmails, email)
+		}
+	}
+	n.webhookURL = webhookURLFromEnv()
+	return n
+}
+
+// Channels liste les canaux disponibles, triés
+func (n *Notifier) Channels() []string {
+	channels := make([]string, 0)
+	if n == nil {
+		return channels
+	}
+	for c := range n.senders {
+		channels = append(channels, c)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Send envoie le message et renvoie l'ID de la ligne notifications et le canal utilisé.
+// Un envoi en échec est journalisé (status 'failed') et son erreur renvoyée.
+func (n *Notifier) Send(ctx context.Context, msg Message) (int, string, error) {
+	if n == nil {
+		return 0, "", ErrNoChannel
+	}
+
+	channel, to := "", ""
+	if msg.Email != "" && n.senders[ChannelEmail] != nil {
+		channel, to = ChannelEmail, msg.Email
+	} else if msg.Phone != "" && n.senders[ChannelSMS] != nil {
+		channel, to = ChannelSMS, msg.Phone
+	}
+	if channel == "" {
+		return 0, "", ErrNoChannel
+	}
+
+	body := msg.Body
+	if channel == ChannelSMS && msg.SMSBody != "" {
+		body = msg.SMSBody
+	}
+
+	sendErr := n.senders[channel].Send(ctx, to, msg.Subject, body)
+	status, errText := "sent", ""
+	if sendErr != nil {
+		status, errText = "failed", sendErr.Error()
+	}
+
+	return n.record(ctx, msg.Kind, channel, to, msg.Subject, body, status, errText), channel, sendErr
+}
+
+// Alert envoie une alerte à l'équipe (ADMIN_ALERT_EMAILS par email, ALERT_WEBHOOK_URL) ;
+// chaque envoi est journalisé. Sans destinataire configuré, l'alerte est seulement loguée.
+// Renvoie la première erreur d'envoi, après avoir tenté tous les destinataires.
+func (n *Notifier) Alert(ctx context.Context, kind, subject, body string) error {
+	type target struct{ channel, to string }
+	targets := make([]target, 0)
+	if n != nil {
+		if n.senders[ChannelEmail] != nil {
+			for _, email := range n.adminEmails {
+				targets = append(targets, target{ChannelEmail, email})
+			}
+		}
+		if n.senders[ChannelWebhook] != nil && n.webhookURL != "" {
+			targets = append(targets, target{ChannelWebhook, n.webhookURL})
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Printf("[alerte %s] %s\n%s\n", kind, subject, strings.TrimSpace(body))
+		return nil
+	}
+
+	var firstErr error
+	for _, t := range targets {
+		sendErr := n.senders[t.channel].Send(ctx, t.to, subject, body)
+		status, errText := "sent", ""
+		if sendErr != nil {
+			status, errText = "failed", sendErr.Error()
+			if firstErr == nil {
+				firstErr = sendErr
+			}
+		}
+		n.record(ctx, kind, t.channel, t.to, subject, body, status, errText)
+	}
+	return firstErr
+}
+
+// record journalise un envoi dans la table notifications et renvoie l'ID de la ligne (0 en cas d'erreur)
+func (n *Notifier) record(ctx context.Context, kind, channel, to, subject, body, status, errText string) int {
+	var id int
+	err := n.db.QueryRowContext(ctx, `
+        INSERT INTO notifications (kind, channel, recipient, subject, body, status, error, sent_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), CASE WHEN $6 = 'sent' THEN NOW() END)
+        RETURNING id`,
+		kind, channel, to, subject, body, status, errText,
+	).Scan(&id)
+	if err != nil {
+		fmt.Printf("Erreur BDD notification %s : %v\n", kind, err)
+	}
+	return id
+}
+
+// logSender écrit la notification dans les logs au lieu de l'envoyer
+type logSender struct {
+	channel string
+}
+
+func (s logSender) Channel() string { return s.channel }
+
+func (s logSender) Send(ctx context.Context, to, subject, body string) error {
+	fmt.Printf("[notification %s] à %s : %s\n%s\n", s.channel, to, subject, strings.TrimSpace(body))
+	return nil
+}
@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// smsSender envoie les SMS via la passerelle HTTP configurée.
+// Variables d'environnement : SMS_API_URL, SMS_API_KEY et SMS_SENDER (nom affiché).
+//
+// Contrat attendu de la passerelle :
+//
+//	POST {url}  {"to": "+225...", "from": "AKWABA", "message": "..."}  → 2xx si accepté
+type smsSender struct {
+	url    string
+	apiKey string
+	from   string
+	client *http.Client
+}
+
+func smsSenderFromEnv() Sender {
+	url := os.Getenv("SMS_API_URL")
+	apiKey := os.Getenv("SMS_API_KEY")
+	if url == "" || apiKey == "" {
+		return nil
+	}
+	from := os.Getenv("SMS_SENDER")
+	if from == "" {
+		from = "AKWABA"
+	}
+	return &smsSender{url: url, apiKey: apiKey, from: from, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (s *smsSender) Channel() string { return ChannelSMS }
+
+func (s *smsSender) Send(ctx context.Context, to, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{"to": to, "from": s.from, "message": body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("passerelle SMS : %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("passerelle SMS : HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// smtpSender envoie les emails via un serveur SMTP.
+// Variables d'environnement : SMTP_HOST, SMTP_PORT (587 par défaut), SMTP_USERNAME,
+// SMTP_PASSWORD et SMTP_FROM.
+type smtpSender struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func smtpSenderFromEnv() Sender {
+	host := os.Getenv("SMTP_HOST")
+	from := os.Getenv("SMTP_FROM")
+	if host == "" || from == "" {
+		return nil
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return &smtpSender{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     from,
+	}
+}
+
+func (s *smtpSender) Channel() string { return ChannelEmail }
+
+func (s *smtpSender) Send(ctx context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	msg := strings.Join([]string{
+		"From: Akwaba Bébé <" + s.from + ">",
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(s.addr, auth, s.from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("smtp : %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// webhookSender poste les alertes admin sur un webhook (Slack, Teams, outil interne).
+// Variable d'environnement : ALERT_WEBHOOK_URL.
+//
+// Corps envoyé (compatible webhook entrant Slack) :
+//
+//	POST {url}  {"text": "sujet\n\ncorps", "subject": "...", "body": "..."}  → 2xx si accepté
+type webhookSender struct {
+	client *http.Client
+}
+
+func webhookURLFromEnv() string {
+	return os.Getenv("ALERT_WEBHOOK_URL")
+}
+
+func (s *webhookSender) Channel() string { return ChannelWebhook }
+
+// Send poste sur l'URL to (le destinataire d'un webhook est son URL)
+func (s *webhookSender) Send(ctx context.Context, to, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{"text": subject + "\n\n" + body, "subject": subject, "body": body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook d'alerte : %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook d'alerte : HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newWebhookSender() Sender {
+	return &webhookSender{client: &http.Client{Timeout: 15 * time.Second}}
+}
@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+func fakeEnabled() bool {
+	return os.Getenv("PAYMENT_FAKE_ENABLED") == "true"
+}
+
+// fakeWebhookSecret signe les webhooks simulés (PAYMENT_FAKE_WEBHOOK_SECRET)
+func fakeWebhookSecret() string {
+	if secret := os.Getenv("PAYMENT_FAKE_WEBHOOK_SECRET"); secret != "" {
+		return secret
+	}
+	return "fake_webhook_secret_akwaba"
+}
+
+// FakeProvider simule un prestataire en mémoire pour tester le parcours complet hors ligne.
+// Un numéro se terminant par "0000" produit un paiement refusé, tous les autres réussissent
+// à la vérification.
+type FakeProvider struct {
+	mu       sync.Mutex
+	seq      int64
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   float64
+	refunded float64
+	failed   bool
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{payments: make(map[string]*fakePayment)}
+}
+
+func (f *FakeProvider) Name() string { return "fake" }
+
+func (f *FakeProvider) Initiate(ctx context.Context, req InitiateRequest) (*Result, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("fake : montant invalide")
+	}
+	ref := fmt.Sprintf("FAKE-%d", atomic.AddInt64(&f.seq, 1))
+
+	f.mu.Lock()
+	f.payments[ref] = &fakePayment{amount: req.Amount, failed: strings.HasSuffix(req.Phone, "0000")}
+	f.mu.Unlock()
+
+	return &Result{
+		ProviderReference: ref,
+		Status:            StatusPending,
+		CheckoutURL:       "https://fake-payments.local/checkout/" + ref,
+		Message:           "Paiement fictif créé",
+	}, nil
+}
+
+func (f *FakeProvider) Verify(ctx context.Context, providerReference string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[providerReference]
+	if !ok {
+		return nil, fmt.Errorf("fake : transaction %s inconnue", providerReference)
+	}
+	res := &Result{ProviderReference: providerReference, Status: StatusSucceeded}
+	switch {
+	case p.failed:
+		res.Status = StatusFailed
+		res.Message = "Solde insuffisant (simulation)"
+	case p.refunded >= p.amount:
+		res.Status = StatusRefunded
+	}
+	return res, nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, providerReference string, amount float64) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[providerReference]
+	if !ok {
+		return nil, fmt.Errorf("fake : transaction %s inconnue", providerReference)
+	}
+	if p.failed || p.refunded+amount > p.amount {
+		return nil, fmt.Errorf("fake : remboursement impossible")
+	}
+	p.refunded += amount
+	return &Result{ProviderReference: providerReference, Status: StatusSucceeded, Message: "Remboursement fictif effectué"}, nil
+}
+
+func (f *FakeProvider) VerifyWebhook(body []byte, signature string) bool {
+	return verifyHMAC(fakeWebhookSecret(), body, signature)
+}
+
+// ParseWebhook attend directement nos statuts ("succeeded", "failed"...)
+func (f *FakeProvider) ParseWebhook(body []byte) (*WebhookEvent, error) {
+	return parseWebhook(body, func(status string) string {
+		switch status {
+		case StatusSucceeded, StatusFailed, StatusRefunded:
+			return status
+		}
+		return StatusPending
+	})
+}
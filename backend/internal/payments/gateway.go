@@ -0,0 +1,180 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// gateway est un client HTTP JSON commun aux prestataires réels.
+// Chaque prestataire est exposé via la passerelle configurée (URL + clé API) et
+// seule la traduction des statuts diffère : Orange Money "SUCCESS", MTN "SUCCESSFUL",
+// Wave "succeeded", carte "captured"...
+//
+// Contrat attendu de la passerelle :
+//
+//	POST {base}/payments               → ouverture du paiement
+//	GET  {base}/payments/{ref}         → statut
+//	POST {base}/payments/{ref}/refund  → remboursement
+type gateway struct {
+	name     string
+	baseURL  string
+	apiKey   string
+	secret   string            // Secret de signature des webhooks
+	statuses map[string]string // statut prestataire (en majuscules) → Status*
+	client   *http.Client
+}
+
+// Variables d'environnement : <PREFIX>_API_URL, <PREFIX>_API_KEY et <PREFIX>_WEBHOOK_SECRET
+var gatewayConfigs = []struct {
+	name     string
+	prefix   string
+	statuses map[string]string
+}{
+	{"orange_money", "ORANGE_MONEY", map[string]string{
+		"INITIATED": StatusPending, "PENDING": StatusPending,
+		"SUCCESS": StatusSucceeded, "FAILED": StatusFailed, "EXPIRED": StatusFailed,
+		"REFUNDED": StatusRefunded,
+	}},
+	{"mtn_momo", "MTN_MOMO", map[string]string{
+		"PENDING": StatusPending, "SUCCESSFUL": StatusSucceeded,
+		"FAILED": StatusFailed, "REJECTED": StatusFailed, "TIMEOUT": StatusFailed,
+		"REFUNDED": StatusRefunded,
+	}},
+	{"wave", "WAVE", map[string]string{
+		"PROCESSING": StatusPending, "OPEN": StatusPending,
+		"SUCCEEDED": StatusSucceeded, "COMPLETE": StatusSucceeded,
+		"FAILED": StatusFailed, "CANCELLED": StatusFailed, "EXPIRED": StatusFailed,
+		"REFUNDED": StatusRefunded,
+	}},
+	{"card", "CARD_GATEWAY", map[string]string{
+		"PENDING": StatusPending, "AUTHORIZED": StatusPending,
+		"CAPTURED": StatusSucceeded, "DECLINED": StatusFailed, "REFUNDED": StatusRefunded,
+	}},
+}
+
+func gatewaysFromEnv() []Provider {
+	providers := make([]Provider, 0)
+	for _, c := range gatewayConfigs {
+		baseURL := os.Getenv(c.prefix + "_API_URL")
+		apiKey := os.Getenv(c.prefix + "_API_KEY")
+		if baseURL == "" || apiKey == "" {
+			continue
+		}
+		providers = append(providers, &gateway{
+			name:     c.name,
+			baseURL:  strings.TrimRight(baseURL, "/"),
+			apiKey:   apiKey,
+			secret:   os.Getenv(c.prefix + "_WEBHOOK_SECRET"),
+			statuses: c.statuses,
+			client:   &http.Client{Timeout: 20 * time.Second},
+		})
+	}
+	return providers
+}
+
+func (g *gateway) Name() string { return g.name }
+
+// gatewayResponse est la réponse JSON commune de la passerelle
+type gatewayResponse struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	CheckoutURL string `json:"checkout_url"`
+	Message     string `json:"message"`
+}
+
+func (g *gateway) Initiate(ctx context.Context, req InitiateRequest) (*Result, error) {
+	return g.call(ctx, http.MethodPost, "/payments", map[string]interface{}{
+		"reference":      req.Reference,
+		"amount":         req.Amount,
+		"currency":       req.Currency,
+		"phone":          req.Phone,
+		"customer_email": req.CustomerEmail,
+		"description":    req.Description,
+	})
+}
+
+func (g *gateway) Verify(ctx context.Context, providerReference string) (*Result, error) {
+	return g.call(ctx, http.MethodGet, "/payments/"+url.PathEscape(providerReference), nil)
+}
+
+func (g *gateway) Refund(ctx context.Context, providerReference string, amount float64) (*Result, error) {
+	res, err := g.call(ctx, http.MethodPost, "/payments/"+url.PathEscape(providerReference)+"/refund", map[string]interface{}{
+		"amount": amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Remboursement : "refunded" vaut confirmation. Un statut en attente (ou inconnu) reste
+	// pending : le remboursement est finalisé par le webhook ou la vérification du paiement
+	if res.Status == StatusRefunded {
+		res.Status = StatusSucceeded
+	}
+	return res, nil
+}
+
+func (g *gateway) call(ctx context.Context, method, path string, body interface{}) (*Result, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", g.name, err)
+	}
+	defer resp.Body.Close()
+
+	var out gatewayResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("%s : réponse illisible (HTTP %d) : %w", g.name, resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s : HTTP %d : %s", g.name, resp.StatusCode, out.Message)
+	}
+
+	return &Result{
+		ProviderReference: out.ID,
+		Status:            g.normalizeStatus(out.Status),
+		CheckoutURL:       out.CheckoutURL,
+		Message:           out.Message,
+	}, nil
+}
+
+// normalizeStatus traduit le statut du prestataire ; un statut inconnu reste "pending"
+// pour ne jamais valider une commande à tort
+func (g *gateway) normalizeStatus(status string) string {
+	if s, ok := g.statuses[strings.ToUpper(status)]; ok {
+		return s
+	}
+	return StatusPending
+}
+
+func (g *gateway) VerifyWebhook(body []byte, signature string) bool {
+	return verifyHMAC(g.secret, body, signature)
+}
+
+func (g *gateway) ParseWebhook(body []byte) (*WebhookEvent, error) {
+	return parseWebhook(body, g.normalizeStatus)
+}
@@ -0,0 +1,98 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Statuts d'un paiement, identiques à la colonne payments.status
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRefunded  = "refunded"
+)
+
+// ErrUnknownProvider est renvoyée quand le moyen de paiement demandé n'est pas configuré
+var ErrUnknownProvider = errors.New("moyen de paiement non disponible")
+
+// InitiateRequest décrit le paiement à ouvrir chez le prestataire
+type InitiateRequest struct {
+	Reference     string  // Référence marchand unique (payments.merchant_reference)
+	Amount        float64 // En FCFA
+	Currency      string  // "XOF"
+	Phone         string  // Numéro mobile money du client (vide pour la carte)
+	CustomerEmail string
+	Description   string
+}
+
+// Result est la réponse normalisée d'un prestataire.
+// Pour Refund, Status vaut StatusSucceeded si le remboursement est effectué, StatusPending
+// s'il est encore en cours chez le prestataire et StatusFailed s'il est refusé.
+type Result struct {
+	ProviderReference string // Identifiant de la transaction chez le prestataire
+	Status            string // Un des Status* ci-dessus
+	CheckoutURL       string // Page de paiement à ouvrir côté client (carte, Wave)
+	Message           string // Détail renvoyé par le prestataire (motif d'échec...)
+}
+
+// Provider est implémenté par chaque moyen de paiement (Orange Money, MTN MoMo, Wave, carte, fake)
+type Provider interface {
+	Name() string
+	Initiate(ctx context.Context, req InitiateRequest) (*Result, error)
+	Verify(ctx context.Context, providerReference string) (*Result, error)
+	Refund(ctx context.Context, providerReference string, amount float64) (*Result, error)
+}
+
+// Registry regroupe les prestataires configurés, indexés par nom
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// NewRegistryFromEnv enregistre les prestataires dont les variables d'environnement sont définies.
+// Le prestataire "fake" n'est activé que si PAYMENT_FAKE_ENABLED=true (développement et recette).
+func NewRegistryFromEnv() *Registry {
+	r := NewRegistry()
+	for _, p := range gatewaysFromEnv() {
+		r.providers[p.Name()] = p
+	}
+	if fakeEnabled() {
+		fake := NewFakeProvider()
+		r.providers[fake.Name()] = fake
+	}
+	return r
+}
+
+// Get renvoie le prestataire demandé, ou ErrUnknownProvider
+func (r *Registry) Get(name string) (Provider, error) {
+	if r == nil {
+		return nil, ErrUnknownProvider
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names liste les prestataires disponibles, triés (affichés au checkout)
+func (r *Registry) Names() []string {
+	names := make([]string, 0)
+	if r == nil {
+		return names
+	}
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
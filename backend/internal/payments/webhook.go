@@ -0,0 +1,71 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// SignatureHeader porte la signature HMAC-SHA256 (hex) du corps brut de la notification
+const SignatureHeader = "X-Signature"
+
+// WebhookEvent est une notification de prestataire, normalisée
+type WebhookEvent struct {
+	EventID           string // Identifiant unique de l'événement chez le prestataire (déduplication)
+	ProviderReference string
+	MerchantReference string
+	Status            string // Un des Status*
+	Message           string
+}
+
+// WebhookVerifier est implémenté par les prestataires qui notifient par webhook
+type WebhookVerifier interface {
+	VerifyWebhook(body []byte, signature string) bool
+	ParseWebhook(body []byte) (*WebhookEvent, error)
+}
+
+// verifyHMAC compare en temps constant la signature reçue au HMAC-SHA256 du corps.
+// Le préfixe "sha256=" est accepté. Sans secret configuré, aucune signature n'est valide.
+func verifyHMAC(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	received, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// parseWebhook lit le format JSON commun :
+// { "event_id": "...", "id": "<référence prestataire>", "reference": "<merchant_reference>", "status": "...", "message": "..." }
+func parseWebhook(body []byte, normalize func(string) string) (*WebhookEvent, error) {
+	var payload struct {
+		EventID   string `json:"event_id"`
+		ID        string `json:"id"`
+		Reference string `json:"reference"`
+		Status    string `json:"status"`
+		Message   string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.EventID == "" {
+		return nil, errors.New("event_id manquant")
+	}
+	if payload.ID == "" && payload.Reference == "" {
+		return nil, errors.New("référence de paiement manquante")
+	}
+	return &WebhookEvent{
+		EventID:           payload.EventID,
+		ProviderReference: payload.ID,
+		MerchantReference: payload.Reference,
+		Status:            normalize(payload.Status),
+		Message:           payload.Message,
+	}, nil
+}
@@ -15,6 +15,11 @@ import (
 
 // UploadToS3 prend un fichier et le renvoie sur S3, retourne l'URL publique
 func UploadToS3(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
+	return UploadToS3Folder("products", file, fileHeader)
+}
+
+// UploadToS3Folder envoie le fichier dans le dossier donné du bucket ("products", "reviews")
+func UploadToS3Folder(folder string, file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
 	// Charger la config AWS
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
 		config.WithRegion(os.Getenv("AWS_REGION")),
@@ -29,7 +34,7 @@ func UploadToS3(file multipart.File, fileHeader *multipart.FileHeader) (string,
 	// Créer un nom de fichier unique
 	ext := filepath.Ext(fileHeader.Filename)
 	// On utilise UnixNano pour garantir l'unicité
-	filename := fmt.Sprintf("products/%d%s", time.Now().UnixNano(), ext)
+	filename := fmt.Sprintf("%s/%d%s", folder, time.Now().UnixNano(), ext)
 	bucketName := os.Getenv("AWS_BUCKET_NAME")
 
 	// Upload direct avec PutObject (Remplace l'ancien Uploader)
@@ -46,6 +51,10 @@ func UploadToS3(file multipart.File, fileHeader *multipart.FileHeader) (string,
 	}
 
 	// Retourner l'URL publique
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, os.Getenv("AWS_REGION"), filename)
-	return url, nil
+	return PublicS3URL(filename), nil
+}
+
+// PublicS3URL renvoie l'URL publique d'une clé du bucket
+func PublicS3URL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", os.Getenv("AWS_BUCKET_NAME"), os.Getenv("AWS_REGION"), key)
 }
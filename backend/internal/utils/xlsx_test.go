@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.want {
+			t.Errorf("columnName(%d) = %q, %q attendu", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	rows := [][]string{
+		{"sku", "name", "price"},
+		{"00123", "Body <coton> & lin", "12500"},
+		{"00124", "Bavoir", ""},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, "Catalogue", rows, map[int]bool{2: true}); err != nil {
+		t.Fatalf("WriteXLSX : %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("archive illisible : %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s : %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("fichier %s absent de l'archive", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Catalogue"`) {
+		t.Errorf("nom de feuille absent : %s", files["xl/workbook.xml"])
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		// En-tête toujours en texte, même pour une colonne numérique
+		`<c r="C1" t="inlineStr"><is><t xml:space="preserve">price</t></is></c>`,
+		// SKU en texte : les zéros de tête sont conservés
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">00123</t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Body &lt;coton&gt; &amp; lin</t></is></c>`,
+		`<c r="C2"><v>12500</v></c>`,
+		// Cellule numérique vide écrite en texte
+		`<c r="C3" t="inlineStr"><is><t xml:space="preserve"></t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("cellule absente : %s", want)
+		}
+	}
+}
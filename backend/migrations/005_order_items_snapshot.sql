@@ -0,0 +1,69 @@
+-- Migration 005 : Snapshot des données produit sur order_items — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Problème corrigé :
+--   Le détail d'une commande joignait order_items à products pour afficher le nom.
+--   Renommer ou supprimer un produit réécrivait donc l'historique des commandes
+--   et des factures.
+--
+-- Modifications :
+--   1. products.sku (nullable, unique) — référence article
+--   2. order_items : product_name, product_sku, product_image_url,
+--      original_price, promotion_percent figés au moment de l'achat
+--   3. Backfill des lignes existantes depuis products
+--   4. order_items.product_id devient nullable (ON DELETE SET NULL) :
+--      supprimer un produit ne bloque plus et n'efface plus l'historique
+--
+-- ⚠️  Le backfill utilise le nom / prix ACTUELS des produits : c'est la meilleure
+--     approximation disponible pour les commandes passées avant cette migration.
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- 1. products.sku
+-- -----------------------------------------------------------------------------
+ALTER TABLE products
+    ADD COLUMN sku VARCHAR(100) UNIQUE;
+
+-- -----------------------------------------------------------------------------
+-- 2. Colonnes snapshot sur order_items
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items
+    ADD COLUMN product_name      VARCHAR(255),
+    ADD COLUMN product_sku       VARCHAR(100),
+    ADD COLUMN product_image_url TEXT,
+    ADD COLUMN original_price    NUMERIC(10, 2),
+    ADD COLUMN promotion_percent NUMERIC(5, 2);
+
+-- -----------------------------------------------------------------------------
+-- 3. Backfill depuis le catalogue actuel
+-- -----------------------------------------------------------------------------
+UPDATE order_items oi
+SET product_name      = p.name,
+    product_sku       = p.sku,
+    product_image_url = p.image_url,
+    original_price    = p.price
+FROM products p
+WHERE p.id = oi.product_id;
+
+ALTER TABLE order_items
+    ALTER COLUMN product_name   SET NOT NULL,
+    ALTER COLUMN original_price SET NOT NULL;
+
+-- -----------------------------------------------------------------------------
+-- 4. product_id nullable + ON DELETE SET NULL
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items
+    ALTER COLUMN product_id DROP NOT NULL;
+
+ALTER TABLE order_items
+    DROP CONSTRAINT order_items_product_id_fkey,
+    ADD CONSTRAINT order_items_product_id_fkey
+        FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_order_items_order   ON order_items(order_id);
+CREATE INDEX idx_order_items_product ON order_items(product_id);
+
+COMMIT;
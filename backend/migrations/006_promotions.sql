@@ -0,0 +1,67 @@
+-- Migration 006 : Promotions programmées — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table promotions (nom de campagne, pourcentage ou montant fixe, dates de début/fin)
+--   2. Table promotion_targets (produits, catégories ou sous-catégories ciblés)
+--   3. products.promotion_percent garanti (colonne ajoutée à la main sur RDS, jamais migrée)
+--   4. order_items : promotion_id + promotion_name (snapshot de la campagne appliquée)
+--
+-- Notes :
+--   - Les dates sont en TIMESTAMP sans fuseau, comparées à NOW() côté serveur.
+--     Abidjan étant en UTC+0, une campagne "Fête des mères" du 31/05 00:00
+--     s'active bien à minuit heure locale.
+--   - products.promotion_percent reste supporté (promotion immédiate "legacy") :
+--     le meilleur prix entre cette valeur et les campagnes actives est retenu.
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : promotions
+-- -----------------------------------------------------------------------------
+CREATE TABLE promotions (
+    id             SERIAL PRIMARY KEY,
+    name           VARCHAR(150)   NOT NULL,
+    discount_type  VARCHAR(20)    NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value NUMERIC(10, 2) NOT NULL CHECK (discount_value > 0),
+    starts_at      TIMESTAMP      NOT NULL,
+    ends_at        TIMESTAMP      NOT NULL,
+    is_active      BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at     TIMESTAMP      NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+-- -----------------------------------------------------------------------------
+-- TABLE : promotion_targets
+-- -----------------------------------------------------------------------------
+-- target_id n'a pas de FK (cible polymorphe) : une cible supprimée est simplement ignorée
+CREATE TABLE promotion_targets (
+    id           SERIAL PRIMARY KEY,
+    promotion_id INTEGER     NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    target_type  VARCHAR(20) NOT NULL CHECK (target_type IN ('product', 'category', 'subcategory')),
+    target_id    INTEGER     NOT NULL,
+    UNIQUE (promotion_id, target_type, target_id)
+);
+
+-- -----------------------------------------------------------------------------
+-- products.promotion_percent (promotion immédiate existante)
+-- -----------------------------------------------------------------------------
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS promotion_percent NUMERIC(5, 2);
+
+-- -----------------------------------------------------------------------------
+-- order_items : campagne appliquée au moment de l'achat
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items
+    ADD COLUMN promotion_id   INTEGER REFERENCES promotions(id) ON DELETE SET NULL,
+    ADD COLUMN promotion_name VARCHAR(150);
+
+-- -----------------------------------------------------------------------------
+-- INDEX de performance
+-- -----------------------------------------------------------------------------
+CREATE INDEX idx_promotions_period     ON promotions(starts_at, ends_at) WHERE is_active;
+CREATE INDEX idx_promotion_targets_ref ON promotion_targets(target_type, target_id);
+
+COMMIT;
@@ -0,0 +1,78 @@
+-- Migration 007 : Codes promo (coupons) — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table coupons (BIENVENUE10...) : pourcentage, montant fixe ou livraison offerte,
+--      panier minimum, limites d'utilisation globale / par client, période de validité
+--   2. Table coupon_categories : restriction optionnelle à certaines catégories
+--   3. Table coupon_redemptions : une ligne par commande ayant utilisé un code
+--   4. orders : subtotal_amount, discount_amount, coupon_code
+--
+-- Notes :
+--   - L'unicité du code est insensible à la casse (index sur UPPER(code))
+--   - used_count est incrémenté dans la transaction de CreateOrder, sous verrou
+--     SELECT ... FOR UPDATE : deux commandes simultanées ne peuvent pas dépasser max_uses
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : coupons
+-- -----------------------------------------------------------------------------
+CREATE TABLE coupons (
+    id                    SERIAL PRIMARY KEY,
+    code                  VARCHAR(50)    NOT NULL,
+    description           TEXT,
+    discount_type         VARCHAR(20)    NOT NULL CHECK (discount_type IN ('percent', 'fixed', 'free_shipping')),
+    discount_value        NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    min_basket            NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    max_uses              INTEGER,                       -- NULL = illimité
+    max_uses_per_customer INTEGER,                       -- NULL = illimité
+    used_count            INTEGER        NOT NULL DEFAULT 0,
+    starts_at             TIMESTAMP,                     -- NULL = valable immédiatement
+    ends_at               TIMESTAMP,                     -- NULL = sans date de fin
+    is_active             BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at            TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_coupons_code ON coupons(UPPER(code));
+
+-- -----------------------------------------------------------------------------
+-- TABLE : coupon_categories
+-- -----------------------------------------------------------------------------
+CREATE TABLE coupon_categories (
+    coupon_id   INTEGER NOT NULL REFERENCES coupons(id)    ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (coupon_id, category_id)
+);
+
+-- -----------------------------------------------------------------------------
+-- TABLE : coupon_redemptions
+-- -----------------------------------------------------------------------------
+CREATE TABLE coupon_redemptions (
+    id              SERIAL PRIMARY KEY,
+    coupon_id       INTEGER        NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    order_id        INTEGER        NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    customer_email  VARCHAR(255)   NOT NULL,
+    discount_amount NUMERIC(10, 2) NOT NULL,
+    created_at      TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions(coupon_id, LOWER(customer_email));
+
+-- -----------------------------------------------------------------------------
+-- orders : détail du calcul du total
+-- -----------------------------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN subtotal_amount NUMERIC(10, 2),
+    ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_code     VARCHAR(50);
+
+-- Commandes existantes : aucune remise, sous-total = total
+UPDATE orders SET subtotal_amount = total_amount;
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal_amount SET NOT NULL;
+
+COMMIT;
@@ -0,0 +1,44 @@
+-- Migration 008 : Zones de livraison et frais de port — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table shipping_zones (la migration 001 supprimait une ancienne table
+--      shipping_zones jamais recréée ni utilisée) :
+--        - zone "commune" : city + commune (ex: Abidjan / Cocody)
+--        - zone "ville"   : city seule, commune NULL (ex: Bouaké) — sert aussi de
+--          repli pour les communes d'une ville sans zone dédiée
+--   2. orders : shipping_fee (colonne dédiée) + shipping_zone_id
+--
+-- Notes :
+--   - Le retrait magasin (delivery_method = 'pickup') est toujours gratuit
+--   - free_shipping_threshold NULL = jamais de livraison offerte sur cette zone
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : shipping_zones
+-- -----------------------------------------------------------------------------
+CREATE TABLE shipping_zones (
+    id                      SERIAL PRIMARY KEY,
+    name                    VARCHAR(100)   NOT NULL,
+    city                    VARCHAR(100)   NOT NULL,
+    commune                 VARCHAR(100),                 -- NULL = toute la ville
+    fee                     NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    free_shipping_threshold NUMERIC(10, 2),               -- NULL = pas de seuil de gratuité
+    is_active               BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at              TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+-- Une seule zone par couple ville / commune (insensible à la casse)
+CREATE UNIQUE INDEX idx_shipping_zones_place ON shipping_zones(LOWER(city), LOWER(COALESCE(commune, '')));
+
+-- -----------------------------------------------------------------------------
+-- orders : frais de livraison
+-- -----------------------------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN shipping_fee     NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN shipping_zone_id INTEGER REFERENCES shipping_zones(id) ON DELETE SET NULL;
+
+COMMIT;
@@ -0,0 +1,46 @@
+-- Migration 009 : Créneaux de livraison et de retrait — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table delivery_slots : créneaux configurés par l'admin avec une capacité
+--        - 'delivery' : livraison à domicile, rattachée à une zone de livraison
+--        - 'pickup'   : retrait magasin, zone optionnelle (NULL = boutique)
+--   2. orders.delivery_slot_id : créneau réservé par le client
+--
+-- Notes :
+--   - booked_count est incrémenté dans la transaction de CreateOrder par un
+--     UPDATE conditionnel (booked_count < capacity) : pas de surréservation possible
+--   - Un créneau déjà réservé ne peut pas être supprimé (à désactiver à la place)
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : delivery_slots
+-- -----------------------------------------------------------------------------
+CREATE TABLE delivery_slots (
+    id               SERIAL PRIMARY KEY,
+    slot_type        VARCHAR(20) NOT NULL CHECK (slot_type IN ('delivery', 'pickup')),
+    shipping_zone_id INTEGER     REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    slot_date        DATE        NOT NULL,
+    start_time       TIME        NOT NULL,
+    end_time         TIME        NOT NULL,
+    capacity         INTEGER     NOT NULL CHECK (capacity > 0),
+    booked_count     INTEGER     NOT NULL DEFAULT 0 CHECK (booked_count >= 0),
+    is_active        BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time),
+    -- Une livraison à domicile doit toujours être rattachée à une zone
+    CHECK (slot_type = 'pickup' OR shipping_zone_id IS NOT NULL)
+);
+
+CREATE INDEX idx_delivery_slots_lookup ON delivery_slots(slot_type, shipping_zone_id, slot_date);
+
+-- -----------------------------------------------------------------------------
+-- orders : créneau réservé
+-- -----------------------------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN delivery_slot_id INTEGER REFERENCES delivery_slots(id) ON DELETE SET NULL;
+
+COMMIT;
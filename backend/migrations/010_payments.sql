@@ -0,0 +1,54 @@
+-- Migration 010 : Paiements mobile money et carte — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. orders.payment_method : 'cod' (paiement à la livraison, comportement historique)
+--      ou nom du prestataire ('orange_money', 'mtn_momo', 'wave', 'card', 'fake')
+--   2. Nouveaux statuts de commande :
+--        - 'pending_payment' : commande payée en ligne, en attente du paiement
+--        - 'paid'            : paiement confirmé par le prestataire
+--        - 'payment_failed'  : paiement refusé (le client peut réessayer)
+--        - 'refunded'        : paiement intégralement remboursé
+--   3. Table payments : une ligne par tentative de paiement d'une commande
+--
+-- Notes :
+--   - merchant_reference est notre référence envoyée au prestataire (unique)
+--   - provider_reference est l'identifiant de transaction renvoyé par le prestataire
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- orders : moyen de paiement
+-- -----------------------------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN payment_method VARCHAR(30) NOT NULL DEFAULT 'cod';
+
+-- -----------------------------------------------------------------------------
+-- TABLE : payments
+-- -----------------------------------------------------------------------------
+CREATE TABLE payments (
+    id                 SERIAL PRIMARY KEY,
+    order_id           INTEGER        NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider           VARCHAR(30)    NOT NULL,
+    merchant_reference VARCHAR(100)   NOT NULL UNIQUE,
+    provider_reference VARCHAR(255),
+    amount             NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    currency           VARCHAR(3)     NOT NULL DEFAULT 'XOF',
+    phone              VARCHAR(50),
+    status             VARCHAR(20)    NOT NULL DEFAULT 'pending'
+                       CHECK (status IN ('pending', 'succeeded', 'failed', 'refunded')),
+    refunded_amount    NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
+    checkout_url       TEXT,
+    failure_reason     TEXT,
+    created_at         TIMESTAMP      NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMP      NOT NULL DEFAULT NOW(),
+    CHECK (refunded_amount <= amount)
+);
+
+CREATE INDEX idx_payments_order ON payments(order_id);
+CREATE UNIQUE INDEX idx_payments_provider_ref ON payments(provider, provider_reference)
+    WHERE provider_reference IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- Migration 011 : Notifications (webhooks) des prestataires de paiement — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table payment_webhook_events : chaque notification reçue est conservée brute,
+--      y compris celles dont la signature est invalide (status 'rejected')
+--
+-- Notes :
+--   - UNIQUE (provider, event_id) : un prestataire qui renvoie le même événement
+--     n'est traité qu'une fois. event_id reste NULL pour les événements rejetés
+--     (signature invalide ou contenu illisible), ils ne bloquent donc pas un vrai événement
+--   - status : 'received' → 'processed' | 'failed' ; les 'failed' peuvent être rejoués par l'admin
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : payment_webhook_events
+-- -----------------------------------------------------------------------------
+CREATE TABLE payment_webhook_events (
+    id              SERIAL PRIMARY KEY,
+    provider        VARCHAR(30)  NOT NULL,
+    event_id        VARCHAR(255),
+    signature_valid BOOLEAN      NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'received'
+                    CHECK (status IN ('received', 'processed', 'failed', 'rejected')),
+    error           TEXT,
+    payment_id      INTEGER      REFERENCES payments(id) ON DELETE SET NULL,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    received_at     TIMESTAMP    NOT NULL DEFAULT NOW(),
+    processed_at    TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
+
+CREATE INDEX idx_payment_webhook_events_status ON payment_webhook_events(status);
+
+COMMIT;
@@ -0,0 +1,47 @@
+-- Migration 012 : Paiement à la livraison et rapprochement des livreurs — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Nouveau rôle utilisateur 'rider' (livreur), attribué manuellement comme 'admin'
+--   2. orders : livreur assigné et encaissement du paiement à la livraison
+--        - rider_id / rider_assigned_at : livreur chargé de la commande
+--        - cod_collected_amount / cod_collected_at : montant encaissé déclaré
+--        - cod_discrepancy : encaissé - attendu (total_amount), NULL tant que rien n'est déclaré
+--   3. Table cod_collections : historique des déclarations (livreur ou correction admin)
+--
+-- Notes :
+--   - Le rapport de rapprochement regroupe les commandes par livreur et par jour d'assignation
+--   - Une commande est signalée dès que cod_discrepancy <> 0
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- orders : livreur et encaissement
+-- -----------------------------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN rider_id             INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN rider_assigned_at    TIMESTAMP,
+    ADD COLUMN cod_collected_amount NUMERIC(10, 2) CHECK (cod_collected_amount >= 0),
+    ADD COLUMN cod_collected_at     TIMESTAMP,
+    ADD COLUMN cod_discrepancy      NUMERIC(10, 2);
+
+CREATE INDEX idx_orders_rider ON orders(rider_id, rider_assigned_at);
+
+-- -----------------------------------------------------------------------------
+-- TABLE : cod_collections
+-- -----------------------------------------------------------------------------
+CREATE TABLE cod_collections (
+    id           SERIAL PRIMARY KEY,
+    order_id     INTEGER        NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    rider_id     INTEGER        REFERENCES users(id) ON DELETE SET NULL,
+    recorded_by  INTEGER        REFERENCES users(id) ON DELETE SET NULL,
+    amount       NUMERIC(10, 2) NOT NULL CHECK (amount >= 0),
+    note         TEXT,
+    created_at   TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cod_collections_order ON cod_collections(order_id);
+
+COMMIT;
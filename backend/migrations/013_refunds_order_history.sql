@@ -0,0 +1,62 @@
+-- Migration 013 : Remboursements, annulations partielles et historique des commandes — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. order_items.cancelled_quantity : unités annulées par l'admin sur une ligne
+--   2. orders.refunded_amount : total remboursé au client
+--   3. Table refunds : chaque remboursement (prestataire de paiement ou espèces)
+--   4. Table order_history : journal des actions sur une commande
+--        'status_changed' | 'item_cancelled' | 'refund'
+--
+-- Notes :
+--   - Les totaux de la commande (subtotal, discount, total) sont recalculés après
+--     chaque annulation de ligne ; la remise est réduite au prorata
+--   - CreateOrder décrémente désormais products.stock_quantity ; une ligne annulée
+--     est remise en stock
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- order_items / orders
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items
+    ADD COLUMN cancelled_quantity INTEGER NOT NULL DEFAULT 0 CHECK (cancelled_quantity >= 0),
+    ADD CONSTRAINT order_items_cancelled_le_quantity CHECK (cancelled_quantity <= quantity);
+
+ALTER TABLE orders
+    ADD COLUMN refunded_amount NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0);
+
+-- -----------------------------------------------------------------------------
+-- TABLE : refunds
+-- -----------------------------------------------------------------------------
+CREATE TABLE refunds (
+    id          SERIAL PRIMARY KEY,
+    order_id    INTEGER        NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    payment_id  INTEGER        REFERENCES payments(id) ON DELETE SET NULL,
+    amount      NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    method      VARCHAR(20)    NOT NULL CHECK (method IN ('provider', 'cash')),
+    reason      TEXT,
+    created_by  INTEGER        REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_order ON refunds(order_id);
+
+-- -----------------------------------------------------------------------------
+-- TABLE : order_history
+-- -----------------------------------------------------------------------------
+CREATE TABLE order_history (
+    id          SERIAL PRIMARY KEY,
+    order_id    INTEGER      NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    action      VARCHAR(50)  NOT NULL,
+    message     TEXT         NOT NULL,
+    details     JSONB,
+    actor_id    INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_history_order ON order_history(order_id, created_at);
+
+COMMIT;
@@ -0,0 +1,34 @@
+-- Migration 014 : Clés d'idempotence — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table idempotency_keys : header Idempotency-Key de POST /orders et POST /contact
+--      avec l'empreinte de la requête et la réponse renvoyée
+--
+-- Notes :
+--   - Un renvoi avec la même clé et le même contenu renvoie la réponse d'origine
+--   - Même clé, contenu différent : 422
+--   - Les clés expirent après 24 h ; une réponse 5xx libère la clé pour un nouvel essai
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : idempotency_keys
+-- -----------------------------------------------------------------------------
+CREATE TABLE idempotency_keys (
+    id              SERIAL PRIMARY KEY,
+    scope           VARCHAR(50)  NOT NULL,   -- 'orders' | 'contact'
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash    CHAR(64)     NOT NULL,   -- SHA-256 hex de méthode + chemin + corps
+    response_status INTEGER,                 -- NULL tant que la requête est en cours
+    response_body   TEXT,
+    created_at      TIMESTAMP    NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMP,
+    UNIQUE (scope, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created_at);
+
+COMMIT;
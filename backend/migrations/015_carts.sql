@@ -0,0 +1,47 @@
+-- Migration 015 : Panier persistant côté serveur — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table carts : panier invité (identifié par son UUID) ou rattaché à un compte
+--   2. Table cart_items : remplace le schéma planifié (user_id) par un lien vers carts
+--
+-- Notes :
+--   - L'UUID est généré côté Go (crypto/rand) et sert de secret pour un panier invité
+--   - Un seul panier 'active' par utilisateur (index unique partiel)
+--   - Les prix ne sont pas stockés : ils sont recalculés à chaque lecture
+--   - status : 'active' | 'converted' (commande passée) | 'merged' (fusionné à la connexion)
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : carts
+-- -----------------------------------------------------------------------------
+CREATE TABLE carts (
+    id          UUID        PRIMARY KEY,
+    user_id     INTEGER     REFERENCES users(id) ON DELETE CASCADE,
+    status      VARCHAR(20) NOT NULL DEFAULT 'active'
+                CHECK (status IN ('active', 'converted', 'merged')),
+    order_id    INTEGER     REFERENCES orders(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP   NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_carts_active_user ON carts(user_id) WHERE status = 'active' AND user_id IS NOT NULL;
+
+-- -----------------------------------------------------------------------------
+-- TABLE : cart_items
+-- -----------------------------------------------------------------------------
+CREATE TABLE cart_items (
+    id          SERIAL PRIMARY KEY,
+    cart_id     UUID      NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id  INTEGER   NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity    INTEGER   NOT NULL CHECK (quantity > 0),
+    added_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (cart_id, product_id)
+);
+
+CREATE INDEX idx_cart_items_cart ON cart_items(cart_id);
+
+COMMIT;
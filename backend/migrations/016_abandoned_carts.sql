@@ -0,0 +1,64 @@
+-- Migration 016 : Paniers abandonnés et notifications — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. carts.email / carts.phone : contact d'un panier invité (PUT /cart/contact)
+--   2. Table notifications : journal de tous les envois (email, SMS)
+--   3. Table abandoned_cart_reminders : relances envoyées par le job, lien de restauration
+--      et commande récupérée
+--
+-- Notes :
+--   - Une relance par période d'inactivité : pas de nouvelle relance tant que le panier
+--     n'a pas été modifié depuis la précédente
+--   - recovered_order_id est renseigné quand le panier relancé est commandé
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 1 : Contact du panier
+-- -----------------------------------------------------------------------------
+ALTER TABLE carts ADD COLUMN email VARCHAR(255);
+ALTER TABLE carts ADD COLUMN phone VARCHAR(50);
+
+CREATE INDEX idx_carts_active_updated ON carts(updated_at) WHERE status = 'active';
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 2 : TABLE notifications
+-- -----------------------------------------------------------------------------
+CREATE TABLE notifications (
+    id          SERIAL PRIMARY KEY,
+    kind        VARCHAR(50)  NOT NULL,   -- 'abandoned_cart', ...
+    channel     VARCHAR(20)  NOT NULL CHECK (channel IN ('email', 'sms')),
+    recipient   VARCHAR(255) NOT NULL,
+    subject     VARCHAR(255),
+    body        TEXT         NOT NULL,
+    status      VARCHAR(20)  NOT NULL CHECK (status IN ('sent', 'failed')),
+    error       TEXT,
+    created_at  TIMESTAMP    NOT NULL DEFAULT NOW(),
+    sent_at     TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_kind ON notifications(kind, created_at);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 3 : TABLE abandoned_cart_reminders
+-- -----------------------------------------------------------------------------
+CREATE TABLE abandoned_cart_reminders (
+    id                 SERIAL PRIMARY KEY,
+    cart_id            UUID         NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    token              VARCHAR(64)  NOT NULL UNIQUE,   -- lien de restauration en un clic
+    channel            VARCHAR(20),
+    recipient          VARCHAR(255) NOT NULL,
+    status             VARCHAR(20)  NOT NULL CHECK (status IN ('sent', 'failed')),
+    notification_id    INTEGER      REFERENCES notifications(id) ON DELETE SET NULL,
+    sent_at            TIMESTAMP    NOT NULL DEFAULT NOW(),
+    restored_at        TIMESTAMP,
+    recovered_order_id INTEGER      REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_abandoned_cart_reminders_cart ON abandoned_cart_reminders(cart_id);
+CREATE INDEX idx_abandoned_cart_reminders_sent ON abandoned_cart_reminders(sent_at);
+
+COMMIT;
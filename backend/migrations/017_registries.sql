@@ -0,0 +1,56 @@
+-- Migration 017 : Listes de naissance — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table registries : liste nommée d'un client, date prévue, lien public (token)
+--   2. Table registry_items : produits souhaités, quantité voulue et déjà offerte
+--   3. order_items.registry_item_id : article acheté depuis une liste
+--
+-- Notes :
+--   - Les invités achètent via POST /orders avec registry_item_id sur l'article :
+--     purchased_quantity est incrémenté dans la transaction de commande
+--   - L'annulation d'une ligne (PUT /orders/{id}/items/{item_id}/cancel) la décrémente
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 1 : TABLE registries
+-- -----------------------------------------------------------------------------
+CREATE TABLE registries (
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title         VARCHAR(255) NOT NULL,
+    due_date      DATE,
+    message       TEXT,
+    public_token  VARCHAR(64)  NOT NULL UNIQUE,
+    is_active     BOOLEAN      NOT NULL DEFAULT TRUE,   -- false : lien public désactivé
+    created_at    TIMESTAMP    NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_registries_user ON registries(user_id);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 2 : TABLE registry_items
+-- -----------------------------------------------------------------------------
+CREATE TABLE registry_items (
+    id                 SERIAL PRIMARY KEY,
+    registry_id        INTEGER   NOT NULL REFERENCES registries(id) ON DELETE CASCADE,
+    product_id         INTEGER   NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    desired_quantity   INTEGER   NOT NULL CHECK (desired_quantity > 0),
+    purchased_quantity INTEGER   NOT NULL DEFAULT 0 CHECK (purchased_quantity >= 0),
+    note               TEXT,
+    created_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (registry_id, product_id)
+);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 3 : Lien commande → liste
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items ADD COLUMN registry_item_id INTEGER REFERENCES registry_items(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_order_items_registry_item ON order_items(registry_item_id) WHERE registry_item_id IS NOT NULL;
+
+COMMIT;
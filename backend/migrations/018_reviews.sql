@@ -0,0 +1,40 @@
+-- Migration 018 : Avis clients et notes — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table reviews (planifiée depuis 001) : note, titre, commentaire, photos,
+--      badge "achat vérifié" et modération
+--
+-- Notes :
+--   - Un avis par client et par produit
+--   - verified_purchase est calculé à la création d'après les commandes du client
+--   - Seuls les avis 'approved' sont publics et comptent dans la note moyenne
+--   - Photos : URLs S3 du dossier reviews/ (POST /reviews/photos)
+-- =============================================================================
+
+BEGIN;
+
+CREATE TABLE reviews (
+    id                SERIAL PRIMARY KEY,
+    product_id        INTEGER      NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id           INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating            INTEGER      NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title             VARCHAR(255),
+    comment           TEXT,
+    photo_urls        TEXT[]       NOT NULL DEFAULT '{}',
+    verified_purchase BOOLEAN      NOT NULL DEFAULT FALSE,
+    status            VARCHAR(20)  NOT NULL DEFAULT 'pending'
+                      CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderation_note   TEXT,
+    moderated_by      INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    moderated_at      TIMESTAMP,
+    created_at        TIMESTAMP    NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMP    NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX idx_reviews_product_approved ON reviews(product_id) WHERE status = 'approved';
+CREATE INDEX idx_reviews_status ON reviews(status, created_at);
+
+COMMIT;
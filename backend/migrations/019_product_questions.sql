@@ -0,0 +1,33 @@
+-- Migration 019 : Questions / réponses produit — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table product_questions : question d'un client (connecté ou non), réponse de l'admin
+--
+-- Notes :
+--   - Seules les questions répondues et publiques sont renvoyées par GetProduct
+--   - Le client est notifié (email) à la publication de la réponse : notified_at
+-- =============================================================================
+
+BEGIN;
+
+CREATE TABLE product_questions (
+    id            SERIAL PRIMARY KEY,
+    product_id    INTEGER      NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id       INTEGER      REFERENCES users(id) ON DELETE SET NULL,   -- NULL pour un invité
+    asker_name    VARCHAR(255) NOT NULL,
+    asker_email   VARCHAR(255),
+    question      TEXT         NOT NULL,
+    answer        TEXT,
+    answered_by   INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    answered_at   TIMESTAMP,
+    is_public     BOOLEAN      NOT NULL DEFAULT TRUE,   -- false : réponse privée (question personnelle)
+    notified_at   TIMESTAMP,
+    created_at    TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_product_questions_product ON product_questions(product_id) WHERE answer IS NOT NULL AND is_public;
+CREATE INDEX idx_product_questions_unanswered ON product_questions(created_at) WHERE answer IS NULL;
+
+COMMIT;
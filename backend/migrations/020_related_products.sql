@@ -0,0 +1,46 @@
+-- Migration 020 : Produits associés et "souvent achetés ensemble" — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table product_links : produits associés choisis par l'admin (ordonnés)
+--   2. Table product_copurchases : paires de produits commandés ensemble, recalculées
+--      périodiquement par le job jobs.CoPurchaseJob à partir de order_items
+--
+-- Notes :
+--   - Un lien est orienté (A → B) : l'admin peut associer B à A sans l'inverse
+--   - product_copurchases est entièrement reconstruite à chaque passage du job :
+--     ne pas la modifier à la main
+--   - Les commandes annulées, impayées ou remboursées et les lignes entièrement
+--     annulées ne comptent pas
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- 1. Liens manuels
+-- -----------------------------------------------------------------------------
+CREATE TABLE product_links (
+    product_id          INTEGER   NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_product_id  INTEGER   NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position            INTEGER   NOT NULL DEFAULT 0,
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, related_product_id),
+    CHECK (product_id <> related_product_id)
+);
+
+-- -----------------------------------------------------------------------------
+-- 2. Achats conjoints (calculés)
+-- -----------------------------------------------------------------------------
+CREATE TABLE product_copurchases (
+    product_id          INTEGER          NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_product_id  INTEGER          NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    order_count         INTEGER          NOT NULL,   -- Commandes contenant les deux produits
+    confidence          DOUBLE PRECISION NOT NULL,   -- order_count / commandes contenant product_id
+    computed_at         TIMESTAMP        NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, related_product_id)
+);
+
+CREATE INDEX idx_product_copurchases_rank ON product_copurchases(product_id, order_count DESC);
+
+COMMIT;
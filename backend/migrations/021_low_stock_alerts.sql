@@ -0,0 +1,46 @@
+-- Migration 021 : Alertes de stock bas — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. products.low_stock_threshold : seuil d'alerte par produit (5 par défaut)
+--   2. notifications.channel accepte 'webhook' (alertes admin)
+--   3. Table low_stock_alerts : passages sous le seuil provoqués par une commande
+--
+-- Notes :
+--   - L'alerte part quand une commande fait passer le stock de "au-dessus du seuil"
+--     à "au seuil ou en dessous" : une seule alerte tant que le produit n'est pas
+--     réapprovisionné au-dessus du seuil
+--   - low_stock_threshold = 0 : alerte uniquement à la rupture
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 1 : Seuil par produit
+-- -----------------------------------------------------------------------------
+ALTER TABLE products
+    ADD COLUMN low_stock_threshold INTEGER NOT NULL DEFAULT 5 CHECK (low_stock_threshold >= 0);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 2 : Canal webhook
+-- -----------------------------------------------------------------------------
+ALTER TABLE notifications DROP CONSTRAINT notifications_channel_check;
+ALTER TABLE notifications
+    ADD CONSTRAINT notifications_channel_check CHECK (channel IN ('email', 'sms', 'webhook'));
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 3 : TABLE low_stock_alerts
+-- -----------------------------------------------------------------------------
+CREATE TABLE low_stock_alerts (
+    id              SERIAL PRIMARY KEY,
+    product_id      INTEGER   NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    order_id        INTEGER   REFERENCES orders(id) ON DELETE SET NULL,   -- Commande déclenchante
+    stock_quantity  INTEGER   NOT NULL,   -- Stock après la commande
+    threshold       INTEGER   NOT NULL,   -- Seuil au moment de l'alerte
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_low_stock_alerts_product ON low_stock_alerts(product_id, created_at DESC);
+
+COMMIT;
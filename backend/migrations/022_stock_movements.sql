@@ -0,0 +1,49 @@
+-- Migration 022 : Journal des mouvements de stock — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table stock_movements : chaque variation de products.stock_quantity, avec son type,
+--      l'auteur, le motif et le stock résultant
+--   2. Reprise : un mouvement 'initial' par produit pour le stock actuel
+--
+-- Notes :
+--   - products.stock_quantity reste la valeur lue par le catalogue et le checkout ; elle
+--     n'est modifiée que via handlers.adjustStock, qui écrit le mouvement dans la même
+--     transaction. SUM(quantity_change) par produit doit donc toujours lui être égale
+--     (contrôle : GET /stock/reconciliation)
+--   - Types : 'initial' | 'sale' | 'cancellation' | 'restock' (réception fournisseur)
+--     | 'damage' (casse, péremption) | 'correction' | 'count' (inventaire physique)
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 1 : TABLE stock_movements
+-- -----------------------------------------------------------------------------
+CREATE TABLE stock_movements (
+    id               SERIAL PRIMARY KEY,
+    product_id       INTEGER      NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    type             VARCHAR(20)  NOT NULL
+                     CHECK (type IN ('initial', 'sale', 'cancellation', 'restock', 'damage', 'correction', 'count')),
+    quantity_change  INTEGER      NOT NULL CHECK (quantity_change <> 0),
+    stock_after      INTEGER      NOT NULL CHECK (stock_after >= 0),
+    order_id         INTEGER      REFERENCES orders(id) ON DELETE SET NULL,
+    actor_id         INTEGER      REFERENCES users(id) ON DELETE SET NULL,   -- NULL : client ou système
+    reason           TEXT,
+    reference        VARCHAR(100),   -- Bon de livraison, facture fournisseur...
+    created_at       TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stock_movements_product ON stock_movements(product_id, created_at DESC);
+CREATE INDEX idx_stock_movements_type ON stock_movements(type, created_at DESC);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 2 : Reprise du stock existant
+-- -----------------------------------------------------------------------------
+INSERT INTO stock_movements (product_id, type, quantity_change, stock_after, reason)
+SELECT id, 'initial', stock_quantity, stock_quantity, 'Reprise du stock existant (migration 022)'
+FROM products
+WHERE stock_quantity > 0;
+
+COMMIT;
@@ -0,0 +1,38 @@
+-- Migration 023 : Alertes de retour en stock — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table stock_subscriptions : demandes "prévenez-moi du retour en stock" sur un
+--      produit en rupture (email ou téléphone)
+--
+-- Notes :
+--   - Le job jobs.BackInStockJob notifie les inscrits des produits revenus en stock,
+--     quel que soit le chemin du réapprovisionnement (réception, inventaire, correction,
+--     annulation de commande), puis clôt l'inscription : un seul envoi par inscription
+--   - Une seule inscription en attente par produit et par contact (index uniques partiels)
+-- =============================================================================
+
+BEGIN;
+
+CREATE TABLE stock_subscriptions (
+    id               SERIAL PRIMARY KEY,
+    product_id       INTEGER      NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id          INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    email            VARCHAR(255),
+    phone            VARCHAR(50),
+    status           VARCHAR(20)  NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'notified', 'failed')),
+    notification_id  INTEGER      REFERENCES notifications(id) ON DELETE SET NULL,
+    created_at       TIMESTAMP    NOT NULL DEFAULT NOW(),
+    notified_at      TIMESTAMP,
+    CHECK (email IS NOT NULL OR phone IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX idx_stock_subscriptions_email ON stock_subscriptions(product_id, LOWER(email))
+    WHERE status = 'pending' AND email IS NOT NULL;
+CREATE UNIQUE INDEX idx_stock_subscriptions_phone ON stock_subscriptions(product_id, phone)
+    WHERE status = 'pending' AND email IS NULL;
+CREATE INDEX idx_stock_subscriptions_pending ON stock_subscriptions(product_id) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- Migration 024 : Précommandes — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. products.preorder_enabled / preorder_available_at / preorder_cap : produit
+--      précommandable, date de disponibilité annoncée et plafond d'unités en attente
+--   2. order_items.is_preorder / preorder_allocated_at : ligne commandée sans stock,
+--      servie à la réception du stock
+--
+-- Notes :
+--   - Une ligne devient une précommande quand le stock ne couvre pas sa quantité et que
+--     le produit est précommandable : aucune unité n'est alors prise sur le stock
+--   - À chaque entrée de stock (réception, inventaire, correction, remise en stock), les
+--     précommandes en attente sont servies dans l'ordre des commandes (mouvement 'sale')
+--   - preorder_cap NULL : pas de plafond
+-- =============================================================================
+
+BEGIN;
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 1 : Produits précommandables
+-- -----------------------------------------------------------------------------
+ALTER TABLE products
+    ADD COLUMN preorder_enabled      BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN preorder_available_at DATE,
+    ADD COLUMN preorder_cap          INTEGER CHECK (preorder_cap > 0);
+
+-- -----------------------------------------------------------------------------
+-- ÉTAPE 2 : Lignes en précommande
+-- -----------------------------------------------------------------------------
+ALTER TABLE order_items
+    ADD COLUMN is_preorder           BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN preorder_allocated_at TIMESTAMP;
+
+CREATE INDEX idx_order_items_preorder_pending ON order_items(product_id, id)
+    WHERE is_preorder AND preorder_allocated_at IS NULL;
+
+COMMIT;
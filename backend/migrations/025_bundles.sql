@@ -0,0 +1,31 @@
+-- Migration 025 : Coffrets (produits composés) — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. products.is_bundle : le produit est un coffret composé d'autres produits
+--   2. Table bundle_items : composants d'un coffret et quantités
+--
+-- Notes :
+--   - Le coffret a son propre prix (products.price) ; son stock n'est pas stocké :
+--     il est calculé à la lecture, MIN(stock du composant / quantité)
+--   - La vente d'un coffret décrémente le stock de chaque composant (mouvements 'sale'
+--     sur les composants dans stock_movements) ; products.stock_quantity du coffret reste 0
+--   - Pas de coffret dans un coffret (vérifié par PUT /products/{id}/bundle)
+-- =============================================================================
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE bundle_items (
+    bundle_id     INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_id  INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,   -- composant d'un coffret : non supprimable
+    quantity      INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id),
+    CHECK (bundle_id <> component_id)
+);
+
+CREATE INDEX idx_bundle_items_component ON bundle_items(component_id);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration 026 : Archivage des produits — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. products.archived_at : date d'archivage (NULL = produit actif)
+--
+-- Notes :
+--   - Un produit archivé disparaît du catalogue public (GET /products), des produits
+--     associés et ne peut plus être ajouté au panier ni commandé ; sa fiche reste lisible
+--     (liens des commandes passées, avis)
+--   - Contrairement à DELETE /products/{id}, l'archivage conserve le produit, son journal
+--     de stock et ses références : il est réversible (action "unarchive")
+--   - Archivage, changement de catégorie, prix et stock en masse : POST /products/bulk
+-- =============================================================================
+
+BEGIN;
+
+ALTER TABLE products ADD COLUMN archived_at TIMESTAMP;
+
+-- Lectures du catalogue public : uniquement les produits actifs
+CREATE INDEX idx_products_active ON products(category_id) WHERE archived_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,41 @@
+-- Migration 027 : Journal d'audit des actions admin — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table audit_log : chaque écriture réussie d'un admin (produits, catégories,
+--      sous-catégories, articles, commandes, messages de contact), avec l'état de
+--      l'entité avant / après et les champs modifiés
+--
+-- Notes :
+--   - Écrit par middleware.Audit autour des routes concernées ; lecture par GET /audit-log
+--   - before_data / after_data : ligne complète de la table (row_to_json). Pour une action
+--     sans entité unique (action groupée, import CSV, promotion par catégorie),
+--     entity_id est NULL et after_data contient la réponse de l'API
+--   - changes : { "champ": { "from": ..., "to": ... } } pour les seuls champs modifiés
+--   - Les requêtes en échec (4xx, 5xx) et les dry-run ne sont pas journalisés
+--   - Table en ajout seul : aucune route ne modifie ni ne supprime une entrée
+-- =============================================================================
+
+BEGIN;
+
+CREATE TABLE audit_log (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    action       VARCHAR(100) NOT NULL,   -- "create", "update", "delete", "bulk", "items.cancel"...
+    entity_type  VARCHAR(50)  NOT NULL,   -- "product", "category", "subcategory", "article", "order", "contact_message"
+    entity_id    INTEGER,
+    method       VARCHAR(10)  NOT NULL,
+    path         TEXT         NOT NULL,
+    before_data  JSONB,
+    after_data   JSONB,
+    changes      JSONB,
+    ip_address   VARCHAR(45),
+    created_at   TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at DESC);
+CREATE INDEX idx_audit_log_user ON audit_log(user_id, created_at DESC);
+CREATE INDEX idx_audit_log_created ON audit_log(created_at DESC);
+
+COMMIT;
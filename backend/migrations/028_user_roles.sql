@@ -0,0 +1,30 @@
+-- Migration 028 : Rôles du back-office — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. users.role : nouveaux rôles order_manager, content_editor, inventory_manager
+--      et support, en plus de customer, admin et rider
+--   2. Contrainte users_role_check sur la liste des rôles connus
+--
+-- Notes :
+--   - La matrice rôle → permissions est définie dans middleware/permissions.go
+--     (RequirePermission) ; la liste de la contrainte doit rester alignée sur
+--     middleware.Roles
+--   - Attribution par PUT /users/{id}/role (permission users.manage) au lieu d'un
+--     UPDATE manuel en base ; effective immédiatement (RequirePermission relit
+--     users.role à chaque requête)
+--   - Reprise : un rôle inconnu (ex : ancien 'user') redevient 'customer'
+-- =============================================================================
+
+BEGIN;
+
+UPDATE users SET role = 'customer'
+WHERE role NOT IN ('customer', 'admin', 'rider', 'order_manager', 'content_editor', 'inventory_manager', 'support');
+
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('customer', 'admin', 'rider', 'order_manager', 'content_editor', 'inventory_manager', 'support'));
+
+CREATE INDEX idx_users_role ON users(role) WHERE role <> 'customer';
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration 029 : Libération des réservations d'une commande impayée — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. orders.resources_released_at : date à laquelle le stock, le créneau de livraison,
+--      le code promo et les articles de liste de naissance de la commande ont été rendus
+--      (NULL = réservations en place)
+--
+-- Notes :
+--   - Posée quand un paiement en ligne échoue (commande "payment_failed") ou quand le job
+--     PENDING_PAYMENT expire une commande restée trop longtemps en "pending_payment"
+--   - Une nouvelle tentative (POST /payments/initiate) ou une confirmation tardive du
+--     prestataire reprend les réservations et remet la colonne à NULL
+-- =============================================================================
+
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN resources_released_at TIMESTAMP;
+
+-- Job d'expiration : commandes en attente de paiement
+CREATE INDEX idx_orders_pending_payment ON orders(created_at) WHERE status = 'pending_payment';
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration 030 : Remboursements en deux temps — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. refunds.status : 'pending' (demandé au prestataire), 'completed' ou 'failed'
+--   2. refunds.failure_reason : réponse du prestataire en cas de refus
+--
+-- Notes :
+--   - Un remboursement en ligne est d'abord enregistré 'pending' et validé, puis le
+--     prestataire est appelé hors transaction, puis la ligne est finalisée dans une
+--     seconde transaction : un prestataire lent ne bloque plus la commande et ses paiements
+--   - Un montant 'pending' est déduit du remboursable : deux demandes simultanées ne
+--     peuvent pas dépasser le montant payé
+--   - Une ligne restée 'pending' (arrêt de l'API pendant l'appel) est à rapprocher
+--     manuellement avec le relevé du prestataire
+--   - Les remboursements existants et les remboursements en espèces sont 'completed'
+-- =============================================================================
+
+BEGIN;
+
+ALTER TABLE refunds
+    ADD COLUMN status         VARCHAR(20) NOT NULL DEFAULT 'completed'
+        CHECK (status IN ('pending', 'completed', 'failed')),
+    ADD COLUMN failure_reason TEXT;
+
+CREATE INDEX idx_refunds_pending ON refunds(payment_id) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration 031 : Réservation des relances de paniers abandonnés — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. abandoned_cart_reminders.status : nouvelle valeur 'pending' (relance réservée,
+--      envoi en cours)
+--
+-- Notes :
+--   - Le job verrouille les paniers à relancer (FOR UPDATE SKIP LOCKED) et enregistre
+--     leur relance 'pending' avant l'envoi : avec plusieurs instances de l'API, un
+--     panier n'est relancé qu'une fois
+--   - La relance passe ensuite en 'sent' ou 'failed' ; une relance restée 'pending'
+--     (arrêt de l'API pendant l'envoi) n'est pas renvoyée avant la prochaine
+--     modification du panier
+-- =============================================================================
+
+BEGIN;
+
+ALTER TABLE abandoned_cart_reminders DROP CONSTRAINT abandoned_cart_reminders_status_check;
+ALTER TABLE abandoned_cart_reminders ADD CONSTRAINT abandoned_cart_reminders_status_check
+    CHECK (status IN ('pending', 'sent', 'failed'));
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- Migration 032 : Composition des coffrets vendus — Akwaba Bébé
+-- Date    : 2026-10-19
+-- Auteur  : Siahoué Siaka
+--
+-- Modifications :
+--   1. Table order_item_components : composants et quantités d'un coffret au moment
+--      de sa vente, une ligne par composant et par ligne de commande
+--
+-- Notes :
+--   - Écrite par CreateOrder avec les lignes de la commande ; une annulation ou une
+--     remise en stock rend les composants vendus, même si la composition du coffret
+--     (PUT /products/{id}/bundle) a changé depuis
+--   - component_name est conservé si le composant est supprimé ensuite
+--   - Reprise : les coffrets déjà vendus reçoivent leur composition actuelle, faute
+--     de mieux
+-- =============================================================================
+
+BEGIN;
+
+CREATE TABLE order_item_components (
+    id             SERIAL PRIMARY KEY,
+    order_item_id  INTEGER      NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    component_id   INTEGER      REFERENCES products(id) ON DELETE SET NULL,
+    component_name VARCHAR(255) NOT NULL,
+    quantity       INTEGER      NOT NULL CHECK (quantity > 0)   -- par coffret
+);
+
+CREATE INDEX idx_order_item_components_item ON order_item_components(order_item_id);
+
+INSERT INTO order_item_components (order_item_id, component_id, component_name, quantity)
+SELECT oi.id, bi.component_id, c.name, bi.quantity
+FROM order_items oi
+JOIN products b ON b.id = oi.product_id AND b.is_bundle
+JOIN bundle_items bi ON bi.bundle_id = b.id
+JOIN products c ON c.id = bi.component_id;
+
+COMMIT;
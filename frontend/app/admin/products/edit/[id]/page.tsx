@@ -42,6 +42,9 @@ export default function EditProductPage() {
   const [showSubcategoryDropdown, setShowSubcategoryDropdown] = useState(false);
   const subcategoryDropdownRef = useRef<HTMLDivElement>(null);
 
+  // Stock lu au chargement : le serveur refuse la correction s'il a changé depuis (ventes)
+  const [initialStock, setInitialStock] = useState<number | null>(null);
+
   const [formData, setFormData] = useState({
     name: '',
     description: '',
@@ -90,6 +93,7 @@ export default function EditProductPage() {
             category_id: prod.category_id.toString(),
             subcategory_id: prod.subcategory_id ? prod.subcategory_id.toString() : ''
           });
+          setInitialStock(prod.stock_quantity);
 
           const currentCat = cats.find((c: Category) => c.id === prod.category_id);
           if (currentCat) setCategorySearch(currentCat.name);
@@ -219,6 +223,7 @@ export default function EditProductPage() {
       description: formData.description,
       price: parseFloat(formData.price),
       stock_quantity: parseInt(formData.stock_quantity),
+      expected_stock_quantity: initialStock,
       image_url: formData.image_url,
       category_id: parseInt(formData.category_id),
       subcategory_id: formData.subcategory_id ? parseInt(formData.subcategory_id) : null
@@ -1,17 +1,64 @@
 'use client';
 
+import { useEffect, useState } from 'react';
 import { useCart } from '@/context/CartContext';
+import { API_URL } from '@/config';
 import { Trash2, ShoppingBag, ArrowRight } from 'lucide-react';
 import Link from 'next/link';
 
 export default function CartPage() {
   // On récupère tout ce qu'il y a dans le panier
-  const { items, removeFromCart, clearCart, cartTotal } = useCart();
+  const { items, removeFromCart, clearCart, replaceCart, cartTotal } = useCart();
+  const [restoreMessage, setRestoreMessage] = useState('');
+
+  // Lien de relance d'un panier abandonné : /cart?restore=<token>
+  useEffect(() => {
+    const token = new URLSearchParams(window.location.search).get('restore');
+    if (!token) return;
+
+    const restore = async () => {
+      try {
+        const res = await fetch(`${API_URL}/cart/restore/${encodeURIComponent(token)}`);
+        const data = await res.json();
+        if (!res.ok) {
+          setRestoreMessage(data.message || 'Impossible de restaurer votre panier');
+          return;
+        }
+        if (data.user_id) {
+          // Panier d'un compte client : il est retrouvé après connexion
+          setRestoreMessage('Connectez-vous pour retrouver votre panier');
+          return;
+        }
+        replaceCart(data.items.map((line: any) => ({
+          id: line.product_id,
+          name: line.name,
+          price: line.final_price,
+          image_url: line.image_url,
+          quantity: line.quantity,
+        })));
+        setRestoreMessage('Votre panier a été restauré');
+      } catch {
+        setRestoreMessage('Impossible de restaurer votre panier');
+      } finally {
+        // On retire le token de l'URL : un rechargement ne rejoue pas la restauration
+        window.history.replaceState(null, '', '/cart');
+      }
+    };
+    restore();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  const restoreBanner = restoreMessage && (
+    <div className="max-w-md w-full mx-auto mb-6 bg-primary-50 text-primary-700 text-sm font-medium px-4 py-3 rounded-xl text-center">
+      {restoreMessage}
+    </div>
+  );
 
   // CAS 1 : Le panier est vide
   if (items.length === 0) {
     return (
       <div className="min-h-screen bg-gray-50 flex flex-col items-center justify-center p-4">
+        {restoreBanner}
         <div className="bg-white p-8 rounded-2xl shadow-sm text-center max-w-md w-full">
           <div className="bg-primary-50 w-20 h-20 rounded-full flex items-center justify-center mx-auto mb-6">
             <ShoppingBag className="h-10 w-10 text-primary-400" />
@@ -30,6 +77,7 @@ export default function CartPage() {
   return (
     <div className="min-h-screen bg-gray-50 py-12 px-4">
       <div className="max-w-6xl mx-auto">
+        {restoreBanner}
         <h1 className="text-3xl font-bold text-primary-900 mb-8 flex items-center gap-3">
           <ShoppingBag className="h-8 w-8" />
           Mon Panier
@@ -17,6 +17,7 @@ interface CartContextType {
   addToCart: (product: any) => void;
   removeFromCart: (id: number) => void;
   clearCart: () => void;
+  replaceCart: (items: CartItem[]) => void;
   cartCount: number;
   cartTotal: number;
 }
@@ -77,12 +78,17 @@ export function CartProvider({ children }: { children: ReactNode }) {
     setItems([]);
   };
 
+  // Remplace tout le panier (ex : panier restauré depuis un lien de relance)
+  const replaceCart = (newItems: CartItem[]) => {
+    setItems(newItems);
+  };
+
   // Calculs automatiques
   const cartCount = items.reduce((acc, item) => acc + item.quantity, 0);
   const cartTotal = items.reduce((acc, item) => acc + (item.price * item.quantity), 0);
 
   return (
-    <CartContext.Provider value={{ items, addToCart, removeFromCart, clearCart, cartCount, cartTotal }}>
+    <CartContext.Provider value={{ items, addToCart, removeFromCart, clearCart, replaceCart, cartCount, cartTotal }}>
       {children}
     </CartContext.Provider>
   );